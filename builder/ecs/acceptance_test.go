@@ -0,0 +1,292 @@
+package ecs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/huaweicloud/packer-builder-huaweicloud/internal/fakecloud"
+)
+
+// The acceptance tests build the examples under example/ and replay the cassettes in
+// testdata/cassettes by default, so they run offline without an account.
+//
+// To record a cassette against a real account, run the test with PACKER_ACC=1,
+// HW_HTTP_CASSETTE_MODE=record and the credentials in HW_ACCESS_KEY, HW_SECRET_KEY and
+// HW_REGION_NAME, the existing resources used by the example are specified by the environment
+// variables listed in accExample.env. Without PACKER_ACC, the cassette is recorded against the
+// fake cloud in internal/fakecloud.
+//
+// The committed cassettes are recorded against the fake cloud, so replaying them checks the
+// request sequence of the builder, not the behaviour of the real API. The tests are named after
+// the fake cloud until the cassettes are recorded against an account.
+
+// accExample is an example under example/.
+type accExample struct {
+	name string
+	// config returns the builder configuration of the example with the variables
+	config func(vars map[string]string) map[string]interface{}
+	// env maps the variables to the environment variables used when recording against an account
+	env map[string]string
+	// seed creates the existing resources in the fake cloud and returns the variables
+	seed func(cloud *fakecloud.Server) map[string]string
+}
+
+// accEnvVars are the environment variables of AccessConfig, they're cleared when the build is
+// replayed or recorded against the fake cloud.
+var accEnvVars = []string{
+	"HW_ACCESS_KEY", "HW_SECRET_KEY", "HW_REGION_NAME", "HW_SECURITY_TOKEN", "HW_PROJECT_ID",
+	"HW_PROJECT_NAME", "HW_DOMAIN_ID", "HW_DOMAIN_NAME", "HW_SHARED_CONFIG_FILE", "HW_PROFILE",
+	"HW_CREDENTIAL_PROCESS", "HW_CLOUD", "HW_AUTH_URL",
+}
+
+func accBaseConfig(vars map[string]string) map[string]interface{} {
+	return map[string]interface{}{
+		"region":     vars["region"],
+		"flavor":     "c6.large.2",
+		"image_name": "Ubuntu-2204-image-powered-by-Packer",
+		"image_tags": map[string]string{
+			"builder": "packer",
+			"os":      "Ubuntu-22.04-server",
+		},
+		"ssh_username": "root",
+		// the name of the key pair is in the request path, so it must be the same when replaying
+		"temporary_key_pair_name": "packer_acc_key_pair",
+	}
+}
+
+func seedSourceImage(cloud *fakecloud.Server) map[string]string {
+	cloud.AddImage(fakecloud.Image{Name: "Ubuntu 22.04 server 64bit", MinDisk: 40})
+	return map[string]string{}
+}
+
+func TestAccBuilder_fakeCloudBasic(t *testing.T) {
+	testAccExample(t, accExample{
+		name: "basic",
+		config: func(vars map[string]string) map[string]interface{} {
+			config := accBaseConfig(vars)
+			config["source_image_name"] = "Ubuntu 22.04 server 64bit"
+			config["eip_type"] = "5_bgp"
+			config["eip_bandwidth_size"] = 5
+			return config
+		},
+		seed: seedSourceImage,
+	})
+}
+
+func TestAccBuilder_fakeCloudDataDisks(t *testing.T) {
+	testAccExample(t, accExample{
+		name: "data_disks",
+		config: func(vars map[string]string) map[string]interface{} {
+			config := accBaseConfig(vars)
+			config["image_type"] = SystemDataImageType
+			// the existing snapshot and volume must be in the availability zone of the server,
+			// it's also in the query of the volume checks, so it must be the same when replaying
+			config["availability_zone"] = vars["availability_zone"]
+			config["source_image_name"] = "Ubuntu 22.04 server 64bit"
+			config["data_disks"] = []map[string]interface{}{
+				{"volume_size": 100, "volume_type": "GPSSD"},
+				{"snapshot_id": vars["snapshot_id"]},
+				{"data_image_id": vars["data_image_id"]},
+				{"volume_id": vars["volume_id"]},
+			}
+			config["eip_type"] = "5_bgp"
+			config["eip_bandwidth_size"] = 5
+			return config
+		},
+		env: map[string]string{
+			"availability_zone": "HW_AVAILABILITY_ZONE",
+			"snapshot_id":       "HW_SNAPSHOT_ID",
+			"data_image_id":     "HW_DATA_IMAGE_ID",
+			"volume_id":         "HW_VOLUME_ID",
+		},
+		seed: func(cloud *fakecloud.Server) map[string]string {
+			seedSourceImage(cloud)
+			return map[string]string{
+				"availability_zone": cloud.Zones[0],
+				"snapshot_id":       cloud.AddSnapshot(fakecloud.Snapshot{Size: 50}),
+				"data_image_id": cloud.AddImage(fakecloud.Image{Name: "data-image", ImageType: "private",
+					MinDisk: 40, VolumeID: "volume-data-image"}),
+				"volume_id": cloud.AddVolume(fakecloud.Volume{Name: "existing-volume", VolumeType: "SSD", Size: 60}),
+			}
+		},
+	})
+}
+
+func TestAccBuilder_fakeCloudEncryption(t *testing.T) {
+	testAccExample(t, accExample{
+		name: "encryption",
+		config: func(vars map[string]string) map[string]interface{} {
+			config := accBaseConfig(vars)
+			config["kms_key_id"] = vars["kms_key_id"]
+			config["source_image_filter"] = map[string]interface{}{
+				"filters": map[string]interface{}{
+					"name":       "Ubuntu 22.04 server 64bit",
+					"visibility": "public",
+				},
+				"most_recent": true,
+			}
+			config["eip_type"] = "5_bgp"
+			config["eip_bandwidth_size"] = 5
+			return config
+		},
+		env: map[string]string{
+			"kms_key_id": "HW_KMS_KEY_ID",
+		},
+		seed: func(cloud *fakecloud.Server) map[string]string {
+			seedSourceImage(cloud)
+			return map[string]string{
+				"kms_key_id": cloud.AddKey(fakecloud.Key{ID: "0a4e4c26-2d5e-4d5c-8e2c-7c1b9a7f6d11", Alias: "packer"}),
+			}
+		},
+	})
+}
+
+func TestAccBuilder_fakeCloudPrivateIP(t *testing.T) {
+	testAccExample(t, accExample{
+		name: "private_ip",
+		config: func(vars map[string]string) map[string]interface{} {
+			config := accBaseConfig(vars)
+			config["source_image_name"] = "Ubuntu 22.04 server 64bit"
+			config["associate_public_ip_address"] = "false"
+			config["vpc_id"] = vars["vpc_id"]
+			config["subnets"] = []string{vars["subnet_id"]}
+			config["security_groups"] = []string{vars["security_group_id"]}
+			return config
+		},
+		env: map[string]string{
+			"vpc_id":            "HW_VPC_ID",
+			"subnet_id":         "HW_NETWORK_ID",
+			"security_group_id": "HW_SECGROUP_ID",
+		},
+		seed: func(cloud *fakecloud.Server) map[string]string {
+			seedSourceImage(cloud)
+			vpcID, subnetID := cloud.AddNetwork()
+			return map[string]string{
+				"vpc_id":            vpcID,
+				"subnet_id":         subnetID,
+				"security_group_id": cloud.AddSecurityGroup(fakecloud.SecurityGroup{}),
+			}
+		},
+	})
+}
+
+func testAccExample(t *testing.T, example accExample) {
+	cassettePath := filepath.Join("testdata", "cassettes", example.name+".json")
+	mode := os.Getenv("HW_HTTP_CASSETTE_MODE")
+	if mode == "" {
+		mode = cassetteModeReplay
+	}
+
+	var vars map[string]string
+	var cloud *fakecloud.Server
+	extra := map[string]interface{}{}
+	realAccount := mode == cassetteModeRecord && os.Getenv("PACKER_ACC") != ""
+
+	switch {
+	case realAccount:
+		vars = map[string]string{"region": os.Getenv("HW_REGION_NAME")}
+		if projectID := os.Getenv("HW_PROJECT_ID"); projectID != "" {
+			vars["project_id"] = projectID
+		}
+		for name, env := range example.env {
+			if vars[name] = os.Getenv(env); vars[name] == "" {
+				t.Fatalf("%s must be set to record the %s example", env, example.name)
+			}
+		}
+	case mode == cassetteModeRecord:
+		clearAccEnv(t)
+		cloud = fakecloud.New(t)
+		cloud.Region = "ap-southeast-1"
+		cloud.Zones = []string{"ap-southeast-1a", "ap-southeast-1b"}
+		vars = example.seed(cloud)
+		vars["region"] = cloud.Region
+		extra["endpoints"] = cloud.Endpoints()
+	default:
+		file, err := loadCassette(cassettePath)
+		if errors.Is(err, fs.ErrNotExist) {
+			t.Skipf("the cassette %s has not been recorded", cassettePath)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		clearAccEnv(t)
+		vars = file.Variables
+	}
+
+	if !realAccount {
+		extra["access_key"] = "ACCEXAMPLEACCESSKEY"
+		extra["secret_key"] = "AccExampleSecretKey"
+	}
+	if mode == cassetteModeRecord {
+		if err := os.Remove(cassettePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("HW_HTTP_CASSETTE", cassettePath)
+	t.Setenv("HW_HTTP_CASSETTE_MODE", mode)
+
+	raw := example.config(vars)
+	if projectID := vars["project_id"]; projectID != "" {
+		raw["project_id"] = projectID
+	}
+	for k, v := range extra {
+		raw[k] = v
+	}
+
+	b := &Builder{}
+	if _, _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("prepare failed: %s", err)
+	}
+	cassette := b.config.clientRegistry().cassette
+	if err := cassette.SetVariables(vars); err != nil {
+		t.Fatalf("failed to save the variables: %s", err)
+	}
+
+	// the SSH connection is not recorded, so the mock communicator is used unless the
+	// build is recorded against an account, and the waits are skipped as nothing changes
+	// in between
+	if !realAccount {
+		b.config.waitSleep = skipSleep
+		b.connectStep = &fakeConnect{comm: new(packer.MockCommunicator)}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	artifact, err := b.Run(ctx, packer.TestUi(t), &packer.MockHook{})
+	if err != nil {
+		t.Fatalf("build failed: %s", err)
+	}
+	if artifact == nil || artifact.Id() == "" {
+		t.Fatal("no image is created")
+	}
+	if err := artifact.Destroy(); err != nil {
+		t.Fatalf("failed to delete the image %s: %s", artifact.Id(), err)
+	}
+
+	if cloud != nil {
+		if leftovers := cloud.Leftovers(); len(leftovers) > 0 {
+			t.Fatalf("the temporary resources are not deleted: %v", leftovers)
+		}
+	}
+	if mode == cassetteModeReplay {
+		for _, item := range cassette.file.Interactions {
+			if !item.used {
+				t.Errorf("the recorded request %s %s is not replayed", item.Request.Method, item.Request.URL)
+			}
+		}
+	}
+}
+
+func clearAccEnv(t *testing.T) {
+	for _, env := range accEnvVars {
+		t.Setenv(env, "")
+	}
+}
@@ -3,16 +3,17 @@
 package ecs
 
 import (
+	"crypto/tls"
 	"fmt"
 	"log"
-	"net/url"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core"
-	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/basic"
-	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/global"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/config"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/httphandler"
 
@@ -33,12 +34,17 @@ const (
 type AccessConfig struct {
 	// The access key of the HuaweiCloud to use.
 	// If omitted, the HW_ACCESS_KEY environment variable is used.
+	// If neither the access key nor the secret key is specified in the template, the environment
+	// variables or the shared config file, and the build is running on an ECS instance with an agency,
+	// the temporary credentials of the instance are obtained from the metadata service and refreshed
+	// automatically.
 	AccessKey string `mapstructure:"access_key" required:"true"`
 	// The secret key of the HuaweiCloud to use.
 	// If omitted, the HW_SECRET_KEY environment variable is used.
 	SecretKey string `mapstructure:"secret_key" required:"true"`
 	// The HuaweiCloud region in which to launch the server to create the image.
 	// If omitted, the HW_REGION_NAME environment variable is used.
+	// When the temporary credentials of the ECS instance are used, defaults to the region of the instance.
 	Region string `mapstructure:"region" required:"true"`
 
 	// The name of the project to login with.
@@ -58,8 +64,93 @@ type AccessConfig struct {
 	// Trust self-signed SSL certificates.
 	// By default this is false.
 	Insecure bool `mapstructure:"insecure" required:"false"`
-
-	Cloud string
+	// The maximum number of retries for the API requests which are throttled (HTTP 429) or failed
+	// with transient errors, such as HTTP 5xx and connection resets. The retries wait with
+	// exponential backoff and jitter. To avoid creating duplicate resources, the creation requests
+	// are retried only if they are not processed by the server.
+	// If omitted, the HW_MAX_RETRIES environment variable is used. Defaults to 5,
+	// set to a negative value, e.g. -1, to disable the retries.
+	MaxRetries int `mapstructure:"max_retries" required:"false"`
+	// The path to a custom CA bundle in PEM format, which is trusted in addition to the system
+	// CA certificates, e.g. the CA of a TLS-intercepting proxy.
+	// If omitted, the HW_CACERT_FILE environment variable is used.
+	CACertFile string `mapstructure:"cacert_file" required:"false"`
+	// The path to the client certificate in PEM format for mutual TLS authentication.
+	// If omitted, the HW_CERT environment variable is used.
+	ClientCertFile string `mapstructure:"cert" required:"false"`
+	// The path to the private key of the client certificate in PEM format.
+	// If omitted, the HW_KEY environment variable is used.
+	ClientKeyFile string `mapstructure:"key" required:"false"`
+	// The ID of the account (domain) to login with, it's used to scope the project lookup.
+	// If omitted, the HW_DOMAIN_ID environment variable is used.
+	DomainID string `mapstructure:"domain_id" required:"false"`
+	// The name of the account (domain) to login with, it's used to scope the project lookup
+	// if `domain_id` is not specified.
+	// If omitted, the HW_DOMAIN_NAME environment variable is used.
+	DomainName string `mapstructure:"domain_name" required:"false"`
+	// The path to the shared config file of [KooCLI](https://support.huaweicloud.com/intl/en-us/productdesc-hcli/hcli_01.html),
+	// such as `~/.hcloud/config.json`. A credentials file in INI format like `~/.huaweicloud/credentials`,
+	// whose sections are the profile names with keys `ak`, `sk`, `security_token`, `region`, `project_id`
+	// and `domain_id`, is also supported.
+	// If omitted, the HW_SHARED_CONFIG_FILE environment variable is used.
+	// If neither is set but `profile` is specified, `~/.hcloud/config.json` is used if it exists,
+	// otherwise `~/.huaweicloud/credentials` is used.
+	//
+	// The access key, secret key, security token, region, project ID and domain ID are loaded from the
+	// profile only if they are not specified in the template or the environment variables.
+	SharedConfigFile string `mapstructure:"shared_config_file" required:"false"`
+	// The profile name in the shared config file.
+	// If omitted, the HW_PROFILE environment variable is used.
+	// If neither is set, the current profile of KooCLI or the `default` profile is used.
+	Profile string `mapstructure:"profile" required:"false"`
+	// The command to retrieve the credentials from an external source, such as Vault or a
+	// corporate credential broker. The command is run by the shell and must print a JSON object
+	// to the standard output:
+	//
+	// ```json
+	// {
+	//   "access_key": "xxx",
+	//   "secret_key": "xxx",
+	//   "security_token": "xxx",
+	//   "expires_at": "2023-06-01T08:00:00Z"
+	// }
+	// ```
+	//
+	// The `security_token` and `expires_at` are optional. The credentials are cached until they
+	// expire, and the command is run again to refresh them during a long-running build.
+	// The command is used only if neither the access key nor the secret key is specified in the
+	// template, the environment variables or the shared config file.
+	// If omitted, the HW_CREDENTIAL_PROCESS environment variable is used.
+	CredentialProcess string `mapstructure:"credential_process" required:"false"`
+	// The configuration to assume an IAM agency of another account with the base credentials.
+	AssumeRole AssumeRoleConfig `mapstructure:"assume_role" required:"false"`
+	// The configuration to exchange an OpenID Connect ID token for temporary credentials.
+	OIDC OIDCConfig `mapstructure:"oidc" required:"false"`
+	// The cloud domain of the service endpoints, such as `myhuaweicloud.com`. The endpoint of a
+	// service is `https://{service}.{region}.{cloud}/`.
+	// If omitted, the HW_CLOUD environment variable is used. If neither is set, it is derived from
+	// `auth_url`, e.g. `myhuaweicloud.com` from `https://iam.cn-north-4.myhuaweicloud.com`.
+	Cloud string `mapstructure:"cloud" required:"false"`
+	// The custom endpoints of the services which override the endpoints derived from `region` and `cloud`,
+	// it's useful for the dedicated clouds and the regions with non-standard hostnames.
+	// The supported services are `ecs`, `ims`, `vpc`, `eip`, `evs`, `kms`, `cbr`, `obs` and `iam`.
+	// The `iam` endpoint is used only if `auth_url` is not specified. The custom endpoints except `iam`
+	// can not be used with the post-processors working in other regions, such as `huaweicloud-promote`.
+	//
+	// Usage example:
+	//
+	// ```hcl
+	//   endpoints = {
+	//     ecs = "https://ecs.region-1.example.com"
+	//     ims = "https://ims.region-1.example.com"
+	//     iam = "https://iam.example.com"
+	//   }
+	// ```
+	Endpoints map[string]string `mapstructure:"endpoints" required:"false"`
+
+	credentialProvider CredentialProvider
+	tlsConfig          *tls.Config
+	clients            *clientRegistry
 }
 
 func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
@@ -73,18 +164,91 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 	if c.Region == "" {
 		c.Region = os.Getenv("HW_REGION_NAME")
 	}
-	// access parameters validation
-	if c.AccessKey == "" || c.SecretKey == "" || c.Region == "" {
-		paraErr := fmt.Errorf("access_key, secret_key and region must be set")
-		return []error{paraErr}
-	}
-
 	if c.SecurityToken == "" {
 		c.SecurityToken = os.Getenv("HW_SECURITY_TOKEN")
 	}
 	if c.ProjectID == "" {
 		c.ProjectID = os.Getenv("HW_PROJECT_ID")
 	}
+	if c.DomainID == "" {
+		c.DomainID = os.Getenv("HW_DOMAIN_ID")
+	}
+	if c.DomainName == "" {
+		c.DomainName = os.Getenv("HW_DOMAIN_NAME")
+	}
+	if c.SharedConfigFile == "" {
+		c.SharedConfigFile = os.Getenv("HW_SHARED_CONFIG_FILE")
+	}
+	if c.Profile == "" {
+		c.Profile = os.Getenv("HW_PROFILE")
+	}
+	if c.CredentialProcess == "" {
+		c.CredentialProcess = os.Getenv("HW_CREDENTIAL_PROCESS")
+	}
+	if c.Cloud == "" {
+		c.Cloud = os.Getenv("HW_CLOUD")
+	}
+	if c.CACertFile == "" {
+		c.CACertFile = os.Getenv("HW_CACERT_FILE")
+	}
+	if c.ClientCertFile == "" {
+		c.ClientCertFile = os.Getenv("HW_CERT")
+	}
+	if c.ClientKeyFile == "" {
+		c.ClientKeyFile = os.Getenv("HW_KEY")
+	}
+	if c.MaxRetries == 0 {
+		if value := os.Getenv("HW_MAX_RETRIES"); value != "" {
+			maxRetries, err := strconv.Atoi(value)
+			if err != nil {
+				return []error{fmt.Errorf("the HW_MAX_RETRIES environment variable must be an integer: %s", err)}
+			}
+			c.MaxRetries = maxRetries
+		}
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = defaultMaxRetries
+	}
+	// the TLS settings are required by all the requests, including the credential providers
+	if err := c.prepareTLSConfig(); err != nil {
+		return []error{err}
+	}
+
+	if c.SharedConfigFile != "" || c.Profile != "" {
+		if err := c.loadSharedConfig(); err != nil {
+			return []error{err}
+		}
+	}
+
+	errs := append(c.AssumeRole.Prepare(), c.OIDC.Prepare()...)
+	if errs = append(errs, c.validateEndpoints()...); len(errs) > 0 {
+		return errs
+	}
+	useOIDC := c.OIDC.IdpID != ""
+
+	if !useOIDC && c.AccessKey == "" && c.SecretKey == "" && c.CredentialProcess != "" {
+		c.credentialProvider = newCachedCredentialProvider(&processProvider{command: c.CredentialProcess})
+		// run the credential process in advance to report the error early
+		if _, err := c.GetCredential(); err != nil {
+			return []error{err}
+		}
+	}
+
+	// fall back to the temporary credentials of the ECS instance if no credentials are specified
+	if !useOIDC && c.credentialProvider == nil && c.AccessKey == "" && c.SecretKey == "" {
+		if err := c.loadMetadata(); err != nil {
+			log.Printf("[DEBUG] the ECS metadata is unavailable: %s", err)
+		}
+	}
+
+	// access parameters validation
+	if c.Region == "" || (!useOIDC && c.credentialProvider == nil && (c.AccessKey == "" || c.SecretKey == "")) {
+		paraErr := fmt.Errorf("access_key, secret_key and region must be set, they are loaded from " +
+			"the template, the HW_ACCESS_KEY, HW_SECRET_KEY and HW_REGION_NAME environment variables, " +
+			"the profile in shared_config_file (or HW_SHARED_CONFIG_FILE), credential_process, " +
+			"and then the ECS metadata in order")
+		return []error{paraErr}
+	}
 	if c.ProjectName == "" {
 		c.ProjectName = os.Getenv("HW_PROJECT_NAME")
 	}
@@ -96,14 +260,38 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 	if c.IdentityEndpoint == "" {
 		c.IdentityEndpoint = os.Getenv("HW_AUTH_URL")
 	}
+	if c.IdentityEndpoint == "" {
+		c.IdentityEndpoint = c.Endpoints["iam"]
+	}
 	// if neither "auth_url" nor HW_AUTH_URL was specified, defaults to "iam.xxx.myhuaweicloud.com"
 	// In Europe site(e.g. eu-west-101), the default endpoint is "iam.eu-west-10x.myhuaweicloud.eu"
 	if c.IdentityEndpoint == "" {
-		c.IdentityEndpoint = buildDefaultIamEndpoint(c.Region)
+		c.IdentityEndpoint = buildDefaultIamEndpoint(c.Cloud, c.Region)
+	}
+
+	if useOIDC {
+		c.credentialProvider = newCachedCredentialProvider(newOIDCProvider(c))
+		// retrieve the temporary credential in advance to report the error early
+		if _, err := c.GetCredential(); err != nil {
+			return []error{err}
+		}
+	}
+	// the base credentials may be the temporary credentials of the ECS instance or OIDC
+	if c.AssumeRole.AgencyName != "" {
+		c.credentialProvider = newCachedCredentialProvider(newAssumeRoleProvider(c))
+		// retrieve the temporary credential in advance to report the error early
+		if _, err := c.GetCredential(); err != nil {
+			return []error{err}
+		}
+	}
+
+	// the region is validated even if the project ID is specified
+	if err := c.validateRegion(); err != nil {
+		return []error{err}
 	}
 
 	if c.ProjectID == "" {
-		projectID, err := c.getProjectID(c.ProjectName)
+		projectID, err := c.getProjectID()
 		if err != nil {
 			return []error{err}
 		}
@@ -111,31 +299,29 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 		c.ProjectID = projectID
 	}
 
-	cloudDomain, err := GetCloudFromAuth(c.IdentityEndpoint)
-	if err != nil {
-		return []error{err}
+	if c.Cloud == "" {
+		cloudDomain, err := GetCloudFromAuth(c.IdentityEndpoint)
+		if err != nil {
+			return []error{err}
+		}
+		c.Cloud = cloudDomain
 	}
-	c.Cloud = cloudDomain
 
 	return nil
 }
 
-// NewHcClient is the common client using huaweicloud-sdk-go-v3 package
+// NewHcClient is the common client using huaweicloud-sdk-go-v3 package, it's not cached,
+// use the HcXxxClient methods to get the cached service clients.
 func NewHcClient(c *AccessConfig, region, product string) (*core.HcHttpClient, error) {
-	endpoint := GetServiceEndpoint(c.Cloud, product, region)
+	endpoint := c.ServiceEndpoint(product, region)
 	if endpoint == "" {
 		return nil, fmt.Errorf("failed to get the endpoint of %q service in region %s", product, region)
 	}
 
-	builder := core.NewHcHttpClientBuilder().WithEndpoints([]string{endpoint}).WithHttpConfig(buildHTTPConfig(c))
+	builder := core.NewHcHttpClientBuilder().WithEndpoints([]string{endpoint}).WithHttpConfig(buildHTTPConfig(c, product))
 
-	credentials := basic.Credentials{
-		AK:            c.AccessKey,
-		SK:            c.SecretKey,
-		SecurityToken: c.SecurityToken,
-		ProjectId:     c.ProjectID,
-	}
-	builder.WithCredential(&credentials)
+	builder.WithCredentialsType("basic.Credentials," + refreshableCredentialsType).
+		WithCredential(c.buildCredentials(false, c.ProjectID))
 
 	headers := map[string]string{
 		"User-Agent": UserAgent,
@@ -143,138 +329,208 @@ func NewHcClient(c *AccessConfig, region, product string) (*core.HcHttpClient, e
 	return builder.Build().PreInvoke(headers), nil
 }
 
-func buildHTTPConfig(c *AccessConfig) *config.HttpConfig {
-	httpConfig := config.DefaultHttpConfig()
-
-	if c.Insecure {
-		httpConfig = httpConfig.WithIgnoreSSLVerification(true)
+// buildHTTPConfig returns the HTTP config of the clients of the service, the service name is
+// recorded in the cassette.
+func buildHTTPConfig(c *AccessConfig, service string) *config.HttpConfig {
+	// the transport takes over the TLS and proxy settings of the HTTP config
+	transport := c.HTTPTransport()
+	if cassette := c.clientRegistry().cassette; cassette != nil {
+		transport = cassette.transport(service, transport)
 	}
+	httpConfig := config.DefaultHttpConfig().WithHttpTransport(transport)
 
-	if logEnabled() {
+	if LogEnabled() {
 		httpHandler := httphandler.NewHttpHandler().
 			AddRequestHandler(logRequestHandler).
 			AddResponseHandler(logResponseHandler)
 		httpConfig = httpConfig.WithHttpHandler(httpHandler)
 	}
 
-	if proxyURL := getProxyFromEnv(); proxyURL != "" {
-		if parsed, err := url.Parse(proxyURL); err == nil {
-			log.Printf("[DEBUG] using https proxy: %s://%s", parsed.Scheme, parsed.Host)
-
-			httpProxy := config.Proxy{
-				Schema:   parsed.Scheme,
-				Host:     parsed.Host,
-				Username: parsed.User.Username(),
-			}
-			if pwd, ok := parsed.User.Password(); ok {
-				httpProxy.Password = pwd
-			}
-
-			httpConfig = httpConfig.WithProxy(&httpProxy)
-		} else {
-			log.Printf("[WARN] parsing https proxy failed: %s", err)
-		}
-	}
-
 	return httpConfig
 }
 
 // HcImsClient is the IMS service client using huaweicloud-sdk-go-v3 package
 func (c *AccessConfig) HcImsClient(region string) (*ims.ImsClient, error) {
-	hcClient, err := NewHcClient(c, region, "ims")
-	if err != nil {
-		return nil, err
-	}
-
-	return ims.NewImsClient(hcClient), nil
+	return serviceClient(c, "ims", region, ims.NewImsClient)
 }
 
 // HcEcsClient is the ECS service client using huaweicloud-sdk-go-v3 package
 func (c *AccessConfig) HcEcsClient(region string) (*ecs.EcsClient, error) {
-	hcClient, err := NewHcClient(c, region, "ecs")
-	if err != nil {
-		return nil, err
-	}
-
-	return ecs.NewEcsClient(hcClient), nil
+	return serviceClient(c, "ecs", region, ecs.NewEcsClient)
 }
 
 // HcVpcClient is the VPC service client using huaweicloud-sdk-go-v3 package
 func (c *AccessConfig) HcVpcClient(region string) (*vpc.VpcClient, error) {
-	hcClient, err := NewHcClient(c, region, "vpc")
-	if err != nil {
-		return nil, err
-	}
-
-	return vpc.NewVpcClient(hcClient), nil
+	return serviceClient(c, "vpc", region, vpc.NewVpcClient)
 }
 
 // HcEipClient is the EIP service client using huaweicloud-sdk-go-v3 package
 func (c *AccessConfig) HcEipClient(region string) (*eip.EipClient, error) {
-	hcClient, err := NewHcClient(c, region, "eip")
-	if err != nil {
-		return nil, err
-	}
-
-	return eip.NewEipClient(hcClient), nil
+	return serviceClient(c, "eip", region, eip.NewEipClient)
 }
 
 // HcEvsClient is the EVS service client using huaweicloud-sdk-go-v3 package
 func (c *AccessConfig) HcEvsClient(region string) (*evs.EvsClient, error) {
-	hcClient, err := NewHcClient(c, region, "evs")
+	return serviceClient(c, "evs", region, evs.NewEvsClient)
+}
+
+// ForRegion returns a copy of the prepared AccessConfig for another region, the project ID is
+// queried for the project named after the region, or the sub-project with the same suffix. The copy
+// shares the credentials and the service clients. The custom endpoints are set for Region, so the
+// copy is refused if any of them except the iam endpoint is specified.
+func (c *AccessConfig) ForRegion(region string) (*AccessConfig, error) {
+	if region == c.Region {
+		return c, nil
+	}
+	var services []string
+	for srv := range c.Endpoints {
+		if srv != "iam" {
+			services = append(services, srv)
+		}
+	}
+	if len(services) > 0 {
+		sort.Strings(services)
+		return nil, fmt.Errorf("the custom endpoints of %s are set for region %s, they can not be used in region %s",
+			strings.Join(services, ", "), c.Region, region)
+	}
+
+	regional := *c
+	regional.Region = region
+	regional.ProjectName = region
+	if strings.HasPrefix(c.ProjectName, c.Region+"_") {
+		regional.ProjectName = region + strings.TrimPrefix(c.ProjectName, c.Region)
+	}
+	if err := regional.validateRegion(); err != nil {
+		return nil, err
+	}
+	projectID, err := regional.getProjectID()
 	if err != nil {
 		return nil, err
 	}
+	regional.ProjectID = projectID
+	return &regional, nil
+}
 
-	return evs.NewEvsClient(hcClient), nil
+// validateRegion checks that Region is one of the regions listed by IAM.
+func (c *AccessConfig) validateRegion() error {
+	domainID := c.DomainID
+	if c.AssumeRole.AgencyName != "" {
+		domainID = c.AssumeRole.DomainID
+	}
+	return validateRegion(c.newIamClient(domainID), c.Region)
 }
 
-func (c *AccessConfig) getProjectID(region string) (string, error) {
-	builder := core.NewHcHttpClientBuilder().WithEndpoint(c.IdentityEndpoint).WithHttpConfig(buildHTTPConfig(c))
+// getProjectID queries the project ID of ProjectName in Region, the project must be unique and
+// its name must be the region name or a sub-project name like "cn-north-4_dev".
+func (c *AccessConfig) getProjectID() (string, error) {
+	// the projects are listed in the account of the agency when assuming an agency
+	domainID, domainName := c.DomainID, c.DomainName
+	if c.AssumeRole.AgencyName != "" {
+		domainID, domainName = c.AssumeRole.DomainID, c.AssumeRole.DomainName
+	}
+	client := c.newIamClient(domainID)
 
-	credentials := global.Credentials{
-		AK:            c.AccessKey,
-		SK:            c.SecretKey,
-		SecurityToken: c.SecurityToken,
+	if domainID == "" && domainName != "" {
+		queriedID, err := getDomainID(client, domainName)
+		if err != nil {
+			return "", err
+		}
+		domainID = queriedID
 	}
-	builder.WithCredentialsType("global.Credentials").WithCredential(&credentials)
 
-	headers := map[string]string{
-		"User-Agent": UserAgent,
+	if c.ProjectName != c.Region && !strings.HasPrefix(c.ProjectName, c.Region+"_") {
+		return "", fmt.Errorf("the project %s does not belong to region %s, the project name must be "+
+			"the region name or start with \"%s_\"", c.ProjectName, c.Region, c.Region)
 	}
-	client := iam.NewIamClient(builder.Build().PreInvoke(headers))
+
 	request := &model.KeystoneListProjectsRequest{
 		Name: &c.ProjectName,
 	}
+	if domainID != "" {
+		request.DomainId = &domainID
+	}
 
 	response, err := client.KeystoneListProjects(request)
 	if err != nil {
 		return "", fmt.Errorf("can not get the project ID of %s: %s", c.ProjectName, err)
 	}
 
-	if response.Projects == nil || len(*response.Projects) == 0 {
+	var matched []model.ProjectResult
+	if response.Projects != nil {
+		for _, project := range *response.Projects {
+			if project.Name == c.ProjectName {
+				matched = append(matched, project)
+			}
+		}
+	}
+
+	switch len(matched) {
+	case 0:
 		return "", fmt.Errorf("can not get the project ID of %s", c.ProjectName)
+	case 1:
+		log.Printf("[DEBUG] the project ID of %s is %s", c.ProjectName, matched[0].Id)
+		return matched[0].Id, nil
+	default:
+		candidates := make([]string, len(matched))
+		for i, project := range matched {
+			candidates[i] = fmt.Sprintf("%s (domain: %s)", project.Id, project.DomainId)
+		}
+		return "", fmt.Errorf("found %d projects named %s: %s, please specify project_id, domain_id or domain_name",
+			len(matched), c.ProjectName, strings.Join(candidates, ", "))
 	}
+}
 
-	queriedProjects := *response.Projects
-	return queriedProjects[0].Id, nil
+func (c *AccessConfig) newIamClient(domainID string) *iam.IamClient {
+	builder := core.NewHcHttpClientBuilder().WithEndpoint(c.IdentityEndpoint).WithHttpConfig(buildHTTPConfig(c, "iam"))
+	builder.WithCredentialsType("global.Credentials," + refreshableCredentialsType).
+		WithCredential(c.buildCredentials(true, domainID))
+
+	headers := map[string]string{
+		"User-Agent": UserAgent,
+	}
+	return iam.NewIamClient(builder.Build().PreInvoke(headers))
 }
 
-func getProxyFromEnv() string {
-	var url string
+// getDomainID queries the ID of the account (domain) which is accessible with the credentials.
+func getDomainID(client *iam.IamClient, name string) (string, error) {
+	response, err := client.KeystoneListAuthDomains(&model.KeystoneListAuthDomainsRequest{})
+	if err != nil {
+		return "", fmt.Errorf("can not get the domain ID of %s: %s", name, err)
+	}
 
-	envNames := []string{"HTTPS_PROXY", "https_proxy"}
-	for _, n := range envNames {
-		if val := os.Getenv(n); val != "" {
-			url = val
-			break
+	var allNames []string
+	if response.Domains != nil {
+		for _, domain := range *response.Domains {
+			if domain.Name == name {
+				return domain.Id, nil
+			}
+			allNames = append(allNames, domain.Name)
 		}
 	}
 
-	return url
+	return "", fmt.Errorf("the domain %s is not accessible, available domains: %v", name, allNames)
 }
 
-func logEnabled() bool {
-	debugEnv := os.Getenv("HW_DEBUG")
-	return debugEnv != "" && debugEnv != "0"
+// validateRegion checks the region against the regions of IAM, the region is not validated if
+// the regions can not be listed, e.g. the API is not supported in some dedicated clouds.
+func validateRegion(client *iam.IamClient, region string) error {
+	response, err := client.KeystoneListRegions(&model.KeystoneListRegionsRequest{})
+	if err != nil {
+		log.Printf("[WARN] failed to list the regions to validate %s: %s", region, err)
+		return nil
+	}
+	if response.Regions == nil || len(*response.Regions) == 0 {
+		return nil
+	}
+
+	allRegions := make([]string, 0, len(*response.Regions))
+	for _, r := range *response.Regions {
+		if r.Id == region {
+			return nil
+		}
+		allRegions = append(allRegions, r.Id)
+	}
+	sort.Strings(allRegions)
+
+	return fmt.Errorf("the region %s is not found, available regions: %s", region, strings.Join(allRegions, ", "))
 }
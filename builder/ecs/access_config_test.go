@@ -0,0 +1,177 @@
+package ecs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestIamServer(t *testing.T, projects string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v3/regions", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"regions": [{"id": "cn-north-4"}, {"id": "cn-east-3"}]}`)
+	})
+	mux.HandleFunc("/v3/auth/domains", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"domains": [{"id": "domain-1", "name": "my-account"}]}`)
+	})
+	mux.HandleFunc("/v3/projects", func(w http.ResponseWriter, r *http.Request) {
+		if domainID := r.URL.Query().Get("domain_id"); domainID != "" {
+			fmt.Fprintf(w, `{"projects": [{"id": "project-of-%s", "name": %q, "domain_id": %q}]}`,
+				domainID, r.URL.Query().Get("name"), domainID)
+			return
+		}
+		fmt.Fprint(w, projects)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestGetProjectID(t *testing.T) {
+	projects := `{"projects": [
+	  {"id": "project-1", "name": "cn-north-4_dev", "domain_id": "domain-1"},
+	  {"id": "project-2", "name": "cn-north-4_dev_test", "domain_id": "domain-1"}
+	]}`
+	server := newTestIamServer(t, projects)
+	defer server.Close()
+
+	c := &AccessConfig{
+		AccessKey:        "ak",
+		SecretKey:        "sk",
+		Region:           "cn-north-4",
+		ProjectName:      "cn-north-4_dev",
+		IdentityEndpoint: server.URL,
+	}
+	projectID, err := c.getProjectID()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if projectID != "project-1" {
+		t.Fatalf("expected project-1, but got %s", projectID)
+	}
+
+	// the project lookup is scoped by the domain name
+	c.DomainName = "my-account"
+	projectID, err = c.getProjectID()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if projectID != "project-of-domain-1" {
+		t.Fatalf("expected project-of-domain-1, but got %s", projectID)
+	}
+}
+
+func TestGetProjectID_Invalid(t *testing.T) {
+	projects := `{"projects": [
+	  {"id": "project-1", "name": "cn-north-4", "domain_id": "domain-1"},
+	  {"id": "project-2", "name": "cn-north-4", "domain_id": "domain-2"}
+	]}`
+	server := newTestIamServer(t, projects)
+	defer server.Close()
+
+	cases := []struct {
+		region, project, domainName, message string
+	}{
+		{"cn-north-4", "cn-north-4", "", "project-1 (domain: domain-1), project-2 (domain: domain-2)"},
+		{"cn-east-3", "cn-north-4_dev", "", "does not belong to region cn-east-3"},
+		{"cn-north-4", "cn-north-4", "other-account", "the domain other-account is not accessible"},
+	}
+
+	for _, tc := range cases {
+		c := &AccessConfig{
+			AccessKey:        "ak",
+			SecretKey:        "sk",
+			Region:           tc.region,
+			ProjectName:      tc.project,
+			DomainName:       tc.domainName,
+			IdentityEndpoint: server.URL,
+		}
+		_, err := c.getProjectID()
+		if err == nil || !strings.Contains(err.Error(), tc.message) {
+			t.Errorf("expected the error to contain %q, but got %v", tc.message, err)
+		}
+	}
+}
+
+func TestAccessConfigPrepare_InvalidRegion(t *testing.T) {
+	server := newTestIamServer(t, `{"projects": []}`)
+	defer server.Close()
+
+	// the region is validated even if the project ID is specified
+	c := &AccessConfig{
+		AccessKey:        "ak",
+		SecretKey:        "sk",
+		Region:           "cn-north-1",
+		ProjectID:        "project-1",
+		IdentityEndpoint: server.URL,
+	}
+	errs := c.Prepare(nil)
+	if len(errs) == 0 || !strings.Contains(errs[0].Error(), "available regions: cn-east-3, cn-north-4") {
+		t.Fatalf("expected the region to be invalid, got %v", errs)
+	}
+}
+
+func TestGetProjectID_AssumeRoleDomainName(t *testing.T) {
+	server := newTestIamServer(t, `{"projects": []}`)
+	defer server.Close()
+
+	// the projects are listed in the assumed account named by assume_role.domain_name
+	c := &AccessConfig{
+		AccessKey:        "ak",
+		SecretKey:        "sk",
+		Region:           "cn-north-4",
+		ProjectName:      "cn-north-4",
+		DomainName:       "base-account",
+		IdentityEndpoint: server.URL,
+		AssumeRole:       AssumeRoleConfig{AgencyName: "packer", DomainName: "my-account"},
+	}
+	projectID, err := c.getProjectID()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if projectID != "project-of-domain-1" {
+		t.Fatalf("expected project-of-domain-1, but got %s", projectID)
+	}
+}
+
+func TestAccessConfig_ForRegion(t *testing.T) {
+	projects := `{"projects": [
+	  {"id": "project-1", "name": "cn-north-4_dev", "domain_id": "domain-1"},
+	  {"id": "project-2", "name": "cn-east-3_dev", "domain_id": "domain-1"}
+	]}`
+	server := newTestIamServer(t, projects)
+	defer server.Close()
+
+	c := &AccessConfig{
+		AccessKey:        "ak",
+		SecretKey:        "sk",
+		Region:           "cn-north-4",
+		ProjectName:      "cn-north-4_dev",
+		ProjectID:        "project-1",
+		IdentityEndpoint: server.URL,
+	}
+	if regional, err := c.ForRegion("cn-north-4"); err != nil || regional != c {
+		t.Fatalf("expected the same config, got %v, %v", regional, err)
+	}
+
+	regional, err := c.ForRegion("cn-east-3")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if regional.Region != "cn-east-3" || regional.ProjectName != "cn-east-3_dev" || regional.ProjectID != "project-2" {
+		t.Fatalf("unexpected regional config: %s, %s, %s", regional.Region, regional.ProjectName, regional.ProjectID)
+	}
+	if c.Region != "cn-north-4" || c.ProjectID != "project-1" {
+		t.Fatalf("the original config should not be changed")
+	}
+
+	if _, err := c.ForRegion("cn-north-1"); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// the custom endpoints are only used in the region of the config
+	c.Endpoints = map[string]string{"iam": server.URL + "/", "ims": "https://ims.example.com/"}
+	if _, err := c.ForRegion("cn-east-3"); err == nil || !strings.Contains(err.Error(), "custom endpoints of ims are set") {
+		t.Fatalf("expected the custom endpoint to be refused, got %v", err)
+	}
+}
@@ -3,24 +3,93 @@ package ecs
 import (
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/packer-plugin-sdk/packer"
 
-	ims "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2/model"
 )
 
+// RegionImagesState is the name of the artifact state which maps the regions to the image IDs
+// separated by ";", see Artifact.State.
+const RegionImagesState = "region_images"
+
 // Artifact is an artifact implementation that contains built images.
 type Artifact struct {
 	// ImageId of built image
 	ImageId string
 
+	// Region where the images are created, the IDs in "region:id" format are in other regions
+	Region string
+
 	// BuilderIdValue is the unique ID for the builder that created this image
 	BuilderIdValue string
 
 	// IMS client for performing API stuff.
-	Client *ims.ImsClient
+	Client ImageClient
+}
+
+// RegionImages is the images of an artifact in a region.
+type RegionImages struct {
+	Region string
+	Images []string
+}
+
+// ParseArtifactID groups the image IDs of the artifact by the regions. The IDs are separated by
+// ";" (the system and data disk images) or ",", each of them is either an image ID in the default
+// region or in "region:id" format. The regions are kept in order of appearance.
+func ParseArtifactID(artifactID, defaultRegion string) []RegionImages {
+	var result []RegionImages
+	fields := strings.FieldsFunc(artifactID, func(r rune) bool { return r == ';' || r == ',' })
+	for _, field := range fields {
+		region, id := defaultRegion, strings.TrimSpace(field)
+		if parts := strings.SplitN(id, ":", 2); len(parts) == 2 {
+			region, id = parts[0], parts[1]
+		}
+		if id != "" {
+			result = AppendRegionImage(result, region, id)
+		}
+	}
+	return result
+}
+
+// ArtifactRegionImages returns the images of the artifact grouped by the regions. They're taken
+// from the RegionImagesState of the artifact, which survives the RPC between packer and the
+// plugins, or parsed from the artifact ID with the default region if the state is missing.
+// The regions from the state are sorted by name.
+func ArtifactRegionImages(artifact packer.Artifact, defaultRegion string) []RegionImages {
+	state, ok := artifact.State(RegionImagesState).(map[string]string)
+	if !ok || len(state) == 0 {
+		return ParseArtifactID(artifact.Id(), defaultRegion)
+	}
+
+	regions := make([]string, 0, len(state))
+	for region := range state {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	var result []RegionImages
+	for _, region := range regions {
+		for _, id := range strings.Split(state[region], ";") {
+			if id != "" {
+				result = AppendRegionImage(result, region, id)
+			}
+		}
+	}
+	return result
+}
+
+// AppendRegionImage appends the image to its region, the regions are kept in order of appearance.
+func AppendRegionImage(items []RegionImages, region, id string) []RegionImages {
+	for i := range items {
+		if items[i].Region == region {
+			items[i].Images = append(items[i].Images, id)
+			return items
+		}
+	}
+	return append(items, RegionImages{Region: region, Images: []string{id}})
 }
 
 func (a *Artifact) BuilderId() string {
@@ -40,8 +109,18 @@ func (a *Artifact) String() string {
 	return fmt.Sprintf("An image was created: %v", a.ImageId)
 }
 
+// State returns the images grouped by the regions for RegionImagesState, the value is a
+// map[string]string which can be passed through the RPC.
 func (a *Artifact) State(name string) interface{} {
-	return nil
+	if name != RegionImagesState || a.Region == "" {
+		return nil
+	}
+
+	images := make(map[string]string)
+	for _, item := range ParseArtifactID(a.ImageId, a.Region) {
+		images[item.Region] = strings.Join(item.Images, ";")
+	}
+	return images
 }
 
 func (a *Artifact) Destroy() error {
@@ -1,6 +1,7 @@
 package ecs
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/packer-plugin-sdk/packer"
@@ -34,3 +35,56 @@ func TestArtifactString(t *testing.T) {
 		t.Fatalf("bad: %s", result)
 	}
 }
+
+func TestParseArtifactID(t *testing.T) {
+	cases := map[string][]RegionImages{
+		"image-1": {{Region: "cn-north-4", Images: []string{"image-1"}}},
+		"image-1;image-2": {
+			{Region: "cn-north-4", Images: []string{"image-1", "image-2"}},
+		},
+		"cn-east-3:image-1,cn-north-4:image-2;cn-east-3:image-3": {
+			{Region: "cn-east-3", Images: []string{"image-1", "image-3"}},
+			{Region: "cn-north-4", Images: []string{"image-2"}},
+		},
+		"": nil,
+	}
+	for artifactID, expected := range cases {
+		result := ParseArtifactID(artifactID, "cn-north-4")
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("%q: expected %v, got %v", artifactID, expected, result)
+		}
+	}
+}
+
+func TestArtifactRegionImages(t *testing.T) {
+	// the artifact of the builder reports its region in the state
+	a := &Artifact{ImageId: "image-1;image-2", Region: "cn-east-3", BuilderIdValue: BuilderId}
+	state := a.State(RegionImagesState)
+	if !reflect.DeepEqual(state, map[string]string{"cn-east-3": "image-1;image-2"}) {
+		t.Fatalf("unexpected state: %v", state)
+	}
+	expected := []RegionImages{{Region: "cn-east-3", Images: []string{"image-1", "image-2"}}}
+	if result := ArtifactRegionImages(a, "cn-north-4"); !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+
+	// the images in other regions are in "region:id" format
+	a.ImageId = "image-1,cn-north-4:image-2;cn-north-4:image-3"
+	expected = []RegionImages{
+		{Region: "cn-east-3", Images: []string{"image-1"}},
+		{Region: "cn-north-4", Images: []string{"image-2", "image-3"}},
+	}
+	if result := ArtifactRegionImages(a, "ap-southeast-1"); !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+
+	// the artifact ID is parsed without the state
+	mock := &packer.MockArtifact{IdValue: "image-1;cn-east-3:image-2"}
+	expected = []RegionImages{
+		{Region: "cn-north-4", Images: []string{"image-1"}},
+		{Region: "cn-east-3", Images: []string{"image-2"}},
+	}
+	if result := ArtifactRegionImages(mock, "cn-north-4"); !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+}
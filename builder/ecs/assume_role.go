@@ -0,0 +1,51 @@
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type AssumeRoleConfig
+
+package ecs
+
+import (
+	"fmt"
+	"time"
+)
+
+// AssumeRoleConfig is the configuration to assume an IAM agency of another account. The base
+// credentials are exchanged for temporary credentials of the agency before building the service
+// clients.
+type AssumeRoleConfig struct {
+	// The name of the agency to assume.
+	AgencyName string `mapstructure:"agency_name" required:"true"`
+	// The name of the account which the agency belongs to.
+	// Either `domain_name` or `domain_id` must be specified.
+	DomainName string `mapstructure:"domain_name" required:"false"`
+	// The ID of the account which the agency belongs to.
+	// Either `domain_name` or `domain_id` must be specified.
+	DomainID string `mapstructure:"domain_id" required:"false"`
+	// The validity period of the temporary credentials, such as `1h` or `90m`.
+	// The value ranges from `15m` to `24h`, defaults to `15m`.
+	// The temporary credentials are refreshed automatically if the build outlives them.
+	Duration string `mapstructure:"duration" required:"false"`
+
+	duration time.Duration
+}
+
+func (c *AssumeRoleConfig) Prepare() []error {
+	if c.AgencyName == "" {
+		if c.DomainName != "" || c.DomainID != "" || c.Duration != "" {
+			return []error{fmt.Errorf("assume_role.agency_name must be specified")}
+		}
+		return nil
+	}
+
+	var errs []error
+	if c.DomainName == "" && c.DomainID == "" {
+		errs = append(errs, fmt.Errorf("either assume_role.domain_name or assume_role.domain_id must be specified"))
+	}
+
+	duration, err := parseCredentialDuration("assume_role.duration", c.Duration)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	c.duration = duration
+
+	return errs
+}
@@ -0,0 +1,37 @@
+// Code generated by "packer-sdc mapstructure-to-hcl2"; DO NOT EDIT.
+
+package ecs
+
+import (
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FlatAssumeRoleConfig is an auto-generated flat version of AssumeRoleConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatAssumeRoleConfig struct {
+	AgencyName *string `mapstructure:"agency_name" required:"true" cty:"agency_name" hcl:"agency_name"`
+	DomainName *string `mapstructure:"domain_name" required:"false" cty:"domain_name" hcl:"domain_name"`
+	DomainID   *string `mapstructure:"domain_id" required:"false" cty:"domain_id" hcl:"domain_id"`
+	Duration   *string `mapstructure:"duration" required:"false" cty:"duration" hcl:"duration"`
+}
+
+// FlatMapstructure returns a new FlatAssumeRoleConfig.
+// FlatAssumeRoleConfig is an auto-generated flat version of AssumeRoleConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*AssumeRoleConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatAssumeRoleConfig)
+}
+
+// HCL2Spec returns the hcl spec of a AssumeRoleConfig.
+// This spec is used by HCL to read the fields of AssumeRoleConfig.
+// The decoded values from this spec will then be applied to a FlatAssumeRoleConfig.
+func (*FlatAssumeRoleConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"agency_name": &hcldec.AttrSpec{Name: "agency_name", Type: cty.String, Required: false},
+		"domain_name": &hcldec.AttrSpec{Name: "domain_name", Type: cty.String, Required: false},
+		"domain_id":   &hcldec.AttrSpec{Name: "domain_id", Type: cty.String, Required: false},
+		"duration":    &hcldec.AttrSpec{Name: "duration", Type: cty.String, Required: false},
+	}
+	return s
+}
@@ -0,0 +1,78 @@
+package ecs
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	iam "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/iam/v3"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/iam/v3/model"
+)
+
+// assumeRoleProvider retrieves the temporary credentials of an agency through IAM.
+type assumeRoleProvider struct {
+	config     *AssumeRoleConfig
+	iamClient  *iam.IamClient
+	agencyName string
+}
+
+// newAssumeRoleProvider returns a provider which signs the IAM requests with the base credentials
+// of AccessConfig, so it must be called before the credential provider of AccessConfig is replaced.
+func newAssumeRoleProvider(c *AccessConfig) *assumeRoleProvider {
+	return &assumeRoleProvider{
+		config:     &c.AssumeRole,
+		iamClient:  c.newIamClient(c.DomainID),
+		agencyName: c.AssumeRole.AgencyName,
+	}
+}
+
+func (p *assumeRoleProvider) Retrieve() (*Credential, error) {
+	assumeRole := model.IdentityAssumerole{
+		AgencyName: p.agencyName,
+	}
+	if p.config.DomainID != "" {
+		assumeRole.DomainId = &p.config.DomainID
+	}
+	if p.config.DomainName != "" {
+		assumeRole.DomainName = &p.config.DomainName
+	}
+	if p.config.duration > 0 {
+		seconds := int32(p.config.duration.Seconds())
+		assumeRole.DurationSeconds = &seconds
+	}
+
+	request := model.CreateTemporaryAccessKeyByAgencyRequest{
+		Body: &model.CreateTemporaryAccessKeyByAgencyRequestBody{
+			Auth: &model.AgencyAuth{
+				Identity: &model.AgencyAuthIdentity{
+					Methods: []model.AgencyAuthIdentityMethods{
+						model.GetAgencyAuthIdentityMethodsEnum().ASSUME_ROLE,
+					},
+					AssumeRole: &assumeRole,
+				},
+			},
+		},
+	}
+
+	log.Printf("[DEBUG] assuming the agency %s", p.agencyName)
+	response, err := p.iamClient.CreateTemporaryAccessKeyByAgency(&request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assume the agency %s: %s", p.agencyName, err)
+	}
+	if response.Credential == nil {
+		return nil, fmt.Errorf("failed to assume the agency %s: the credential is empty", p.agencyName)
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, response.Credential.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the expiration time of the temporary credential: %s", err)
+	}
+
+	credential := Credential{
+		AccessKey:     response.Credential.Access,
+		SecretKey:     response.Credential.Secret,
+		SecurityToken: response.Credential.Securitytoken,
+		ExpiresAt:     expiresAt,
+	}
+	return &credential, nil
+}
@@ -0,0 +1,75 @@
+package ecs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAssumeRoleConfigPrepare(t *testing.T) {
+	cases := []struct {
+		config AssumeRoleConfig
+		valid  bool
+	}{
+		{AssumeRoleConfig{}, true},
+		{AssumeRoleConfig{AgencyName: "packer", DomainName: "target"}, true},
+		{AssumeRoleConfig{AgencyName: "packer", DomainID: "abc", Duration: "1h"}, true},
+		{AssumeRoleConfig{DomainName: "target"}, false},
+		{AssumeRoleConfig{AgencyName: "packer"}, false},
+		{AssumeRoleConfig{AgencyName: "packer", DomainName: "target", Duration: "10m"}, false},
+		{AssumeRoleConfig{AgencyName: "packer", DomainName: "target", Duration: "25h"}, false},
+		{AssumeRoleConfig{AgencyName: "packer", DomainName: "target", Duration: "one hour"}, false},
+	}
+
+	for _, tc := range cases {
+		errs := tc.config.Prepare()
+		if tc.valid && len(errs) > 0 {
+			t.Errorf("%#v should be valid: %v", tc.config, errs)
+		}
+		if !tc.valid && len(errs) == 0 {
+			t.Errorf("%#v should be invalid", tc.config)
+		}
+	}
+}
+
+type testCredentialProvider struct {
+	count    int
+	lifetime time.Duration
+}
+
+func (p *testCredentialProvider) Retrieve() (*Credential, error) {
+	p.count++
+	credential := Credential{
+		AccessKey: "temp-ak",
+		SecretKey: "temp-sk",
+		ExpiresAt: time.Now().Add(p.lifetime),
+	}
+	return &credential, nil
+}
+
+func TestCachedCredentialProvider(t *testing.T) {
+	provider := &testCredentialProvider{lifetime: time.Hour}
+	c := AccessConfig{
+		credentialProvider: newCachedCredentialProvider(provider),
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetCredential(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if provider.count != 1 {
+		t.Fatalf("the credential should be retrieved once, but got %d", provider.count)
+	}
+
+	// the credential expires within the refresh window
+	provider = &testCredentialProvider{lifetime: time.Minute}
+	c.credentialProvider = newCachedCredentialProvider(provider)
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetCredential(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if provider.count != 3 {
+		t.Fatalf("the credential should be refreshed every time, but got %d", provider.count)
+	}
+}
@@ -1,20 +1,18 @@
 package ecs
 
 import (
-	"fmt"
+	"context"
 	"log"
 	"net/http"
 
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/sdkerr"
-	ecs "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2"
 	ecsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2/model"
-	evs "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/evs/v2"
 	evsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/evs/v2/model"
 )
 
-// serverStateRefreshFunc returns a StateRefreshFunc that is used to watch an ECS server.
-func serverStateRefreshFunc(client *ecs.EcsClient, serverID string) StateRefreshFunc {
-	return func() (interface{}, string, error) {
+// serverStateRefreshFunc returns a RefreshFunc that is used to watch an ECS server.
+func serverStateRefreshFunc(client ServerClient, serverID string) RefreshFunc[*ecsmodel.ServerDetail] {
+	return func(context.Context) (*ecsmodel.ServerDetail, string, error) {
 		request := &ecsmodel.ShowServerRequest{
 			ServerId: serverID,
 		}
@@ -42,9 +40,9 @@ func serverStateRefreshFunc(client *ecs.EcsClient, serverID string) StateRefresh
 	}
 }
 
-// serverJobStateRefreshFunc returns a StateRefreshFunc that is used to watch an ECS job.
-func serverJobStateRefreshFunc(client *ecs.EcsClient, jobID string) StateRefreshFunc {
-	return func() (interface{}, string, error) {
+// serverJobStateRefreshFunc returns a RefreshFunc that is used to watch an ECS job.
+func serverJobStateRefreshFunc(client ServerJobClient, jobID string) RefreshFunc[*ecsmodel.ShowJobResponse] {
+	return func(context.Context) (*ecsmodel.ShowJobResponse, string, error) {
 		request := &ecsmodel.ShowJobRequest{
 			JobId: jobID,
 		}
@@ -56,13 +54,16 @@ func serverJobStateRefreshFunc(client *ecs.EcsClient, jobID string) StateRefresh
 		}
 
 		status := response.Status.Value()
+		if status == "FAIL" {
+			return response, status, newECSJobError(response)
+		}
 		return response, status, nil
 	}
 }
 
-// volumeJobStateRefreshFunc returns a StateRefreshFunc that is used to watch an EVS job.
-func volumeJobStateRefreshFunc(client *evs.EvsClient, jobID string) StateRefreshFunc {
-	return func() (interface{}, string, error) {
+// volumeJobStateRefreshFunc returns a RefreshFunc that is used to watch an EVS job.
+func volumeJobStateRefreshFunc(client VolumeClient, jobID string) RefreshFunc[*evsmodel.ShowJobResponse] {
+	return func(context.Context) (*evsmodel.ShowJobResponse, string, error) {
 		request := &evsmodel.ShowJobRequest{
 			JobId: jobID,
 		}
@@ -77,8 +78,7 @@ func volumeJobStateRefreshFunc(client *evs.EvsClient, jobID string) StateRefresh
 			return response, status, nil
 		}
 		if status == "FAIL" {
-			return response, status, fmt.Errorf("the EVS job (%s) status is FAIL: %s",
-				jobID, *response.FailReason)
+			return response, status, newEVSJobError(response)
 		}
 		return response, "PENDING", nil
 	}
@@ -1,271 +1,200 @@
 package ecs
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"log"
+	"math/rand"
 	"strings"
 	"time"
 
-	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
 )
 
-var refreshGracePeriod = 30 * time.Second
+const (
+	defaultWaitMinInterval      = 1 * time.Second
+	defaultWaitMaxInterval      = 10 * time.Second
+	defaultWaitNotFoundChecks   = 20
+	defaultWaitProgressInterval = 1 * time.Minute
+)
 
-// StateRefreshFunc is a function type used for xxxStateChangeConf that is
-// responsible for refreshing the item being watched for a state change.
+// RefreshFunc is responsible for refreshing the resource being waited for.
 // It returns three results:
 //
-// `result` is any object that will be returned as the final object after
-// waiting for state change. This allows you to return the final updated object,
-// for example an HuaweiCloud instance after refreshing it.
-// `state` is the latest state of that object.
-// `err` is any error that may have happened while refreshing the state.
-type StateRefreshFunc func() (result interface{}, state string, err error)
-
-// StateChangeConf is the configuration struct used for `WaitForState`.
-type StateChangeConf struct {
-	Delay          time.Duration    // Wait this time before starting checks
-	Pending        []string         // States that are "allowed" and will continue trying
-	Refresh        StateRefreshFunc // Refreshes the current state
-	Target         []string         // Target state
-	Timeout        time.Duration    // The amount of time to wait before timeout
-	MinTimeout     time.Duration    // Smallest time to wait before refreshes
-	PollInterval   time.Duration    // Override MinTimeout/backoff and only poll this often
-	NotFoundChecks int              // Number of times to allow not found
-	StateBag       multistep.StateBag
-
-	// This is to work around inconsistent APIs
-	ContinuousTargetOccurence int // Number of times the Target state has to occur continuously
+// `result` is the latest resource which will be returned after the resource reaches the target state.
+// `state` is the latest state of the resource, an empty state means the resource is not found.
+// `err` is any error that may have happened while refreshing the state, the wait stops immediately
+// with the error.
+//
+// The context is done when the wait is cancelled or times out, no more requests should be sent then.
+type RefreshFunc[T any] func(ctx context.Context) (result T, state string, err error)
+
+// ProgressFunc is called when the state changes and periodically while waiting.
+type ProgressFunc func(state string, elapsed time.Duration)
+
+// SleepFunc sleeps for the duration, it returns the error of the context if it's done before.
+type SleepFunc func(ctx context.Context, d time.Duration) error
+
+// Waiter waits for a resource to reach one of the target states.
+type Waiter[T any] struct {
+	Pending          []string       // States that are "allowed" and will continue trying
+	Target           []string       // Target states
+	Refresh          RefreshFunc[T] // Refreshes the current state
+	Timeout          time.Duration  // The amount of time to wait before timeout, no timeout if zero
+	Delay            time.Duration  // Wait this time before starting checks
+	MinInterval      time.Duration  // The interval before the second check, it doubles after each check
+	MaxInterval      time.Duration  // The maximum interval between checks
+	NotFoundChecks   int            // Number of times to allow not found
+	Progress         ProgressFunc   // Reports the progress of waiting, optional
+	ProgressInterval time.Duration  // Reports the progress at least this often, defaults to 1 minute
+	Sleep            SleepFunc      // Sleeps between the checks, defaults to sleeping for the duration
 }
 
-// WaitForState watches an object and waits for it to achieve the state
-// specified in the configuration using the specified Refresh() func,
-// waiting the number of seconds specified in the timeout configuration.
+// Wait refreshes the resource with jittered exponential backoff until it reaches one of the
+// target states, and returns the result of the refresh which reaches the target state.
 //
-// If the Refresh function returns an error, exit immediately with that error.
-//
-// If the Refresh function returns a state other than the Target state or one
-// listed in Pending, return immediately with an error.
-//
-// If the Timeout is exceeded before reaching the Target state, return an
-// error.
-//
-// Otherwise, the result is the result of the first call to the Refresh function to
-// reach the target state.
-func (conf *StateChangeConf) WaitForState() (interface{}, error) {
-	log.Printf("[DEBUG] Waiting for state to become: %s", conf.Target)
-
-	notfoundTick := 0
-	targetOccurence := 0
-
-	// Set a default for times to check for not found
-	if conf.NotFoundChecks == 0 {
-		conf.NotFoundChecks = 20
+// It returns when the context is cancelled, when the Refresh function returns an error, or when the
+// Refresh function returns a state other than the Target and Pending states. The Refresh function
+// is called with the context of the wait, so a cancelled wait returns once the request in flight
+// completes, the SDK clients can not abort it.
+// A TimeoutError is returned if the Timeout is exceeded before reaching the target state.
+func (w *Waiter[T]) Wait(ctx context.Context) (T, error) {
+	var zero T
+	log.Printf("[DEBUG] Waiting for state to become: %s", w.Target)
+
+	minInterval := w.MinInterval
+	if minInterval <= 0 {
+		minInterval = defaultWaitMinInterval
 	}
-
-	if conf.ContinuousTargetOccurence == 0 {
-		conf.ContinuousTargetOccurence = 1
+	maxInterval := w.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = defaultWaitMaxInterval
 	}
-
-	type Result struct {
-		Result interface{}
-		State  string
-		Error  error
-		Done   bool
+	if minInterval > maxInterval {
+		minInterval = maxInterval
+	}
+	notFoundChecks := w.NotFoundChecks
+	if notFoundChecks <= 0 {
+		notFoundChecks = defaultWaitNotFoundChecks
+	}
+	progressInterval := w.ProgressInterval
+	if progressInterval <= 0 {
+		progressInterval = defaultWaitProgressInterval
+	}
+	sleep := w.Sleep
+	if sleep == nil {
+		sleep = sleepContext
 	}
 
-	// Read every result from the refresh loop, waiting for a positive result.Done.
-	resCh := make(chan Result, 1)
-	// cancellation channel for the refresh loop
-	cancelCh := make(chan struct{})
-
-	result := Result{}
-
-	go func() {
-		defer close(resCh)
-
-		time.Sleep(conf.Delay)
-
-		// start with 0 delay for the first loop
-		var wait time.Duration
-
-		for {
-			// store the last result
-			resCh <- result
-
-			// wait and watch for cancellation
-			select {
-			case <-cancelCh:
-				return
-			case <-time.After(wait):
-				// first round had no wait
-				if wait == 0 {
-					wait = 100 * time.Millisecond
-				}
-			}
-
-			res, currentState, err := conf.Refresh()
-			result = Result{
-				Result: res,
-				State:  currentState,
-				Error:  err,
-			}
-
-			if err != nil {
-				resCh <- result
-				return
-			}
-
-			// If we're waiting for the absence of a thing, then return
-			if res == nil && len(conf.Target) == 0 {
-				targetOccurence++
-				if conf.ContinuousTargetOccurence == targetOccurence {
-					result.Done = true
-					resCh <- result
-					return
-				}
-				continue
-			}
-
-			if res == nil {
-				// If we didn't find the resource, check if we have been
-				// not finding it for awhile, and if so, report an error.
-				notfoundTick++
-				if notfoundTick > conf.NotFoundChecks {
-					result.Error = fmt.Errorf("couldn't find resource (%d retries)", notfoundTick)
-					resCh <- result
-					return
-				}
-			} else {
-				// Reset the counter for when a resource isn't found
-				notfoundTick = 0
-				found := false
-
-				for _, allowed := range conf.Target {
-					if currentState == allowed {
-						found = true
-						targetOccurence++
-						if conf.ContinuousTargetOccurence == targetOccurence {
-							result.Done = true
-							resCh <- result
-							return
-						}
-						continue
-					}
-				}
-
-				for _, allowed := range conf.Pending {
-					if currentState == allowed {
-						found = true
-						targetOccurence = 0
-						break
-					}
-				}
-
-				if !found && len(conf.Pending) > 0 {
-					result.Error = fmt.Errorf("unexpected state '%s', wanted target '%s'. last error: %s",
-						result.State,
-						strings.Join(conf.Target, ", "),
-						err,
-					)
-					resCh <- result
-					return
-				}
-			}
-
-			if conf.StateBag != nil {
-				if _, ok := conf.StateBag.GetOk(multistep.StateCancelled); ok {
-					result.Error = errors.New("forced cancel")
-					resCh <- result
-					return
-				}
-			}
-
-			// Wait between refreshes using exponential backoff, except when
-			// waiting for the target state to reoccur.
-			if targetOccurence == 0 {
-				wait *= 2
-			}
+	waitCtx := ctx
+	if w.Timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, w.Timeout)
+		defer cancel()
+	}
 
-			// If a poll interval has been specified, choose that interval.
-			// Otherwise bound the default value.
-			if conf.PollInterval > 0 && conf.PollInterval < 180*time.Second {
-				wait = conf.PollInterval
-			} else {
-				if wait < conf.MinTimeout {
-					wait = conf.MinTimeout
-				} else if wait > 10*time.Second {
-					wait = 10 * time.Second
-				}
-			}
+	start := time.Now()
+	var lastState string
+	var lastErr error
+	var lastProgress time.Time
+	notFoundTick := 0
+	interval := minInterval
+	wait := w.Delay
 
-			log.Printf("[TRACE] Waiting %s before next try", wait)
+	for {
+		if err := sleep(waitCtx, wait); err != nil {
+			return zero, w.waitError(ctx, lastState, lastErr)
 		}
-	}()
 
-	// store the last value result from the refresh loop
-	lastResult := Result{}
+		result, state, err := w.Refresh(waitCtx)
+		if waitCtx.Err() != nil {
+			return zero, w.waitError(ctx, lastState, lastErr)
+		}
+		if err != nil {
+			return result, err
+		}
 
-	timeout := time.After(conf.Timeout)
-	for {
-		select {
-		case r, ok := <-resCh:
-			// channel closed, so return the last result
-			if !ok {
-				return lastResult.Result, lastResult.Error
+		if w.Progress != nil && state != "" {
+			if state != lastState || time.Since(lastProgress) >= progressInterval {
+				w.Progress(state, time.Since(start))
+				lastProgress = time.Now()
 			}
-
-			// we reached the intended state
-			if r.Done {
-				return r.Result, r.Error
+		}
+		lastState = state
+
+		switch {
+		case containsState(w.Target, state):
+			return result, nil
+		case state == "":
+			notFoundTick++
+			if notFoundTick > notFoundChecks {
+				return zero, fmt.Errorf("couldn't find resource (%d retries)", notFoundTick)
 			}
+			lastErr = fmt.Errorf("resource not found")
+		case containsState(w.Pending, state) || len(w.Pending) == 0:
+			notFoundTick = 0
+			lastErr = nil
+		default:
+			return result, fmt.Errorf("unexpected state '%s', wanted target '%s'",
+				state, strings.Join(w.Target, ", "))
+		}
 
-			// still waiting, store the last result
-			lastResult = r
+		// full jitter, but wait at least half of the interval
+		// #nosec G404 -- the jitter does not require a secure random number
+		wait = interval/2 + time.Duration(rand.Int63n(int64(interval/2)+1))
+		interval *= 2
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+		log.Printf("[TRACE] Waiting %s before next try", wait)
+	}
+}
 
-		case <-timeout:
-			log.Printf("[WARN] WaitForState timeout after %s", conf.Timeout)
-			log.Printf("[WARN] WaitForState starting %s refresh grace period", refreshGracePeriod)
+func (w *Waiter[T]) waitError(ctx context.Context, lastState string, lastErr error) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("cancelled while waiting for state to become '%s': %s",
+			strings.Join(w.Target, ", "), err)
+	}
 
-			// cancel the goroutine and start our grace period timer
-			close(cancelCh)
-			timeout := time.After(refreshGracePeriod)
+	log.Printf("[WARN] Wait timeout after %s", w.Timeout)
+	return &TimeoutError{
+		LastError:     lastErr,
+		LastState:     lastState,
+		Timeout:       w.Timeout,
+		ExpectedState: w.Target,
+	}
+}
 
-			// we need a for loop and a label to break on, because we may have
-			// an extra response value to read, but still want to wait for the
-			// channel to close.
-		forSelect:
-			for {
-				select {
-				case r, ok := <-resCh:
-					if r.Done {
-						// the last refresh loop reached the desired state
-						return r.Result, r.Error
-					}
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
 
-					if !ok {
-						// the goroutine returned
-						break forSelect
-					}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
 
-					// target state not reached, save the result for the
-					// TimeoutError and wait for the channel to close
-					lastResult = r
-				case <-timeout:
-					log.Println("[ERROR] WaitForState exceeded refresh grace period")
-					break forSelect
-				}
-			}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
 
-			return nil, &TimeoutError{
-				LastError:     lastResult.Error,
-				LastState:     lastResult.State,
-				Timeout:       conf.Timeout,
-				ExpectedState: conf.Target,
-			}
+func containsState(states []string, state string) bool {
+	for _, s := range states {
+		if s == state {
+			return true
 		}
 	}
+	return false
+}
+
+// UiProgress returns a ProgressFunc which reports the state of the resource to the UI.
+func UiProgress(ui packer.Ui, resource string) ProgressFunc {
+	return func(state string, elapsed time.Duration) {
+		ui.Message(fmt.Sprintf("%s is %s (%s elapsed)", resource, state, elapsed.Truncate(time.Second)))
+	}
 }
 
 type TimeoutError struct {
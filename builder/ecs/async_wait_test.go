@@ -0,0 +1,162 @@
+package ecs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// skipSleep is the sleep of the waiters and the cleanup retries in the tests, it returns
+// immediately unless the context is done.
+func skipSleep(ctx context.Context, _ time.Duration) error {
+	return ctx.Err()
+}
+
+func testRefreshFunc(states ...string) RefreshFunc[int] {
+	count := 0
+	return func(context.Context) (int, string, error) {
+		state := states[len(states)-1]
+		if count < len(states) {
+			state = states[count]
+		}
+		count++
+		return count, state, nil
+	}
+}
+
+func TestWaiter(t *testing.T) {
+	var progress []string
+	waiter := Waiter[int]{
+		Pending:     []string{"INIT", "RUNNING"},
+		Target:      []string{"SUCCESS"},
+		Refresh:     testRefreshFunc("INIT", "RUNNING", "RUNNING", "SUCCESS"),
+		Timeout:     time.Second,
+		MinInterval: time.Millisecond,
+		MaxInterval: time.Millisecond,
+		Progress: func(state string, _ time.Duration) {
+			progress = append(progress, state)
+		},
+	}
+
+	result, err := waiter.Wait(context.Background())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result != 4 {
+		t.Fatalf("expected the result of the 4th refresh, but got %d", result)
+	}
+	// the progress is reported only when the state changes
+	if strings.Join(progress, ",") != "INIT,RUNNING,SUCCESS" {
+		t.Fatalf("unexpected progress: %v", progress)
+	}
+}
+
+func TestWaiter_Errors(t *testing.T) {
+	refreshErr := errors.New("job failed")
+	cases := []struct {
+		name    string
+		refresh RefreshFunc[int]
+		message string
+	}{
+		{"unexpected", testRefreshFunc("RUNNING", "ERROR"), "unexpected state 'ERROR'"},
+		{"refresh", func(context.Context) (int, string, error) { return 0, "FAIL", refreshErr }, "job failed"},
+		{"not found", testRefreshFunc(""), "couldn't find resource (4 retries)"},
+		{"timeout", testRefreshFunc("RUNNING"), "timeout while waiting for state to become 'SUCCESS'"},
+	}
+
+	for _, tc := range cases {
+		waiter := Waiter[int]{
+			Pending:        []string{"RUNNING"},
+			Target:         []string{"SUCCESS"},
+			Refresh:        tc.refresh,
+			Timeout:        50 * time.Millisecond,
+			MinInterval:    time.Millisecond,
+			MaxInterval:    time.Millisecond,
+			NotFoundChecks: 3,
+		}
+		_, err := waiter.Wait(context.Background())
+		if err == nil || !strings.Contains(err.Error(), tc.message) {
+			t.Errorf("%s: expected the error to contain %q, but got %v", tc.name, tc.message, err)
+		}
+	}
+}
+
+func TestWaiter_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	waiter := Waiter[int]{
+		Pending: []string{"RUNNING"},
+		Target:  []string{"SUCCESS"},
+		Refresh: testRefreshFunc("RUNNING"),
+		Timeout: time.Hour,
+		Delay:   time.Hour,
+	}
+
+	start := time.Now()
+	_, err := waiter.Wait(ctx)
+	if err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Fatalf("expected the wait to be cancelled, but got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the wait to return immediately, but it took %s", elapsed)
+	}
+}
+
+func TestWaiter_Sleep(t *testing.T) {
+	var sleeps []time.Duration
+	waiter := Waiter[int]{
+		Pending:     []string{"RUNNING"},
+		Target:      []string{"SUCCESS"},
+		Refresh:     testRefreshFunc("RUNNING", "RUNNING", "SUCCESS"),
+		Delay:       time.Hour,
+		MinInterval: 2 * time.Hour,
+		MaxInterval: 4 * time.Hour,
+		Sleep: func(ctx context.Context, d time.Duration) error {
+			sleeps = append(sleeps, d)
+			return ctx.Err()
+		},
+	}
+
+	if _, err := waiter.Wait(context.Background()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(sleeps) != 3 || sleeps[0] != time.Hour {
+		t.Fatalf("expected the delay and a sleep before each check, but got %v", sleeps)
+	}
+	if sleeps[1] < time.Hour || sleeps[1] > 2*time.Hour || sleeps[2] < 2*time.Hour || sleeps[2] > 4*time.Hour {
+		t.Fatalf("expected the sleeps to be within the doubling intervals, but got %v", sleeps)
+	}
+}
+
+func TestWaiter_CancelRefresh(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	waiter := Waiter[int]{
+		Pending: []string{"RUNNING"},
+		Target:  []string{"SUCCESS"},
+		Refresh: func(ctx context.Context) (int, string, error) {
+			calls++
+			// the wait is cancelled while the request is in flight
+			cancel()
+			if ctx.Err() == nil {
+				t.Error("expected the context of the refresh to be done after cancelling the wait")
+			}
+			return calls, "RUNNING", nil
+		},
+		Timeout: time.Hour,
+		Sleep:   skipSleep,
+	}
+
+	_, err := waiter.Wait(ctx)
+	if err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Fatalf("expected the wait to be cancelled, but got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected no refresh after the wait is cancelled, but got %d", calls)
+	}
+}
@@ -0,0 +1,273 @@
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2/model"
+)
+
+// the final states of the temporary resources in the build report
+const (
+	resourceStateDeleted    = "deleted"
+	resourceStateLeftBehind = "left_behind"
+	resourceStateNotDeleted = "not_deleted"
+)
+
+// the options by which the source image is resolved
+const (
+	sourceImageByID     = "source_image"
+	sourceImageByName   = "source_image_name"
+	sourceImageByFilter = "source_image_filter"
+)
+
+// buildReport is the machine-readable report of a build, it's written to build_report_file when the
+// build ends. All the methods can be called on a nil report, which records nothing, so that the steps
+// don't need to check whether the report is enabled.
+type buildReport struct {
+	mu sync.Mutex
+
+	BuildName          string             `json:"build_name,omitempty"`
+	Region             string             `json:"region"`
+	AvailabilityZone   string             `json:"availability_zone,omitempty"`
+	Flavor             string             `json:"flavor,omitempty"`
+	SourceImage        *sourceImageReport `json:"source_image,omitempty"`
+	StartedAt          time.Time          `json:"started_at"`
+	FinishedAt         time.Time          `json:"finished_at"`
+	Duration           float64            `json:"duration_seconds"`
+	Artifact           string             `json:"artifact_id,omitempty"`
+	Error              string             `json:"error,omitempty"`
+	Steps              []*stepReport      `json:"steps"`
+	TemporaryResources []resourceReport   `json:"temporary_resources"`
+	ImageJobs          []imageJobReport   `json:"image_jobs"`
+}
+
+type sourceImageReport struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// ResolvedBy is the option which the image is resolved by, one of source_image,
+	// source_image_name and source_image_filter
+	ResolvedBy string                   `json:"resolved_by"`
+	Filters    *model.ListImagesRequest `json:"filters,omitempty"`
+	MostRecent bool                     `json:"most_recent,omitempty"`
+	// Matches is the number of the images matching the name or the filters
+	Matches int `json:"matches,omitempty"`
+}
+
+type stepReport struct {
+	Name      string    `json:"name"`
+	StartedAt time.Time `json:"started_at"`
+	Duration  float64   `json:"duration_seconds"`
+	Action    string    `json:"action"`
+	// Cleanup is the duration of the cleanup, which is missing if the step has not been cleaned up
+	Cleanup *float64 `json:"cleanup_seconds,omitempty"`
+}
+
+type resourceReport struct {
+	Kind      string     `json:"kind"`
+	ID        string     `json:"id"`
+	Name      string     `json:"name,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// State is deleted, left_behind or not_deleted if the resource is not cleaned up
+	State string `json:"state"`
+	Error string `json:"error,omitempty"`
+}
+
+type imageJobReport struct {
+	JobID     string    `json:"job_id"`
+	ImageID   string    `json:"image_id,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	Duration  float64   `json:"duration_seconds"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func newBuildReport(conf *Config) *buildReport {
+	return &buildReport{
+		BuildName:          conf.PackerBuildName,
+		Region:             conf.Region,
+		StartedAt:          time.Now(),
+		Steps:              []*stepReport{},
+		TemporaryResources: []resourceReport{},
+		ImageJobs:          []imageJobReport{},
+	}
+}
+
+func durationSeconds(d time.Duration) float64 {
+	return d.Round(time.Millisecond).Seconds()
+}
+
+// setSourceImage records how the source image is resolved.
+func (r *buildReport) setSourceImage(image sourceImageReport) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.SourceImage = &image
+}
+
+// addImageJob records an image job which started at the time, the job fails if err is not nil.
+func (r *buildReport) addImageJob(jobID, imageID string, startedAt time.Time, err error) {
+	if r == nil {
+		return
+	}
+
+	job := imageJobReport{
+		JobID:     jobID,
+		ImageID:   imageID,
+		StartedAt: startedAt,
+		Duration:  durationSeconds(time.Since(startedAt)),
+		Status:    "SUCCESS",
+	}
+	if err != nil {
+		job.Status = "FAIL"
+		job.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ImageJobs = append(r.ImageJobs, job)
+}
+
+// timeSteps wraps the steps to record the durations of them.
+func (r *buildReport) timeSteps(steps []multistep.Step) []multistep.Step {
+	if r == nil {
+		return steps
+	}
+
+	timed := make([]multistep.Step, len(steps))
+	for i, step := range steps {
+		timed[i] = &timedStep{
+			Step:   step,
+			name:   strings.TrimPrefix(fmt.Sprintf("%T", step), "*"),
+			report: r,
+		}
+	}
+	return timed
+}
+
+// finish records the result of the build, and the resources tracked in the state.
+func (r *buildReport) finish(state multistep.StateBag, artifactID string, err error) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.FinishedAt = time.Now()
+	r.Duration = durationSeconds(r.FinishedAt.Sub(r.StartedAt))
+	r.Artifact = artifactID
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if az, ok := state.GetOk("availability_zone"); ok {
+		r.AvailabilityZone = az.(string)
+	}
+	if flavor, ok := state.GetOk("flavor_id"); ok {
+		r.Flavor = flavor.(string)
+	}
+
+	if tracker, ok := state.GetOk(resourceTrackerKey); ok {
+		for _, resource := range tracker.(*resourceTracker).all() {
+			r.TemporaryResources = append(r.TemporaryResources, newResourceReport(resource))
+		}
+	}
+}
+
+func newResourceReport(resource *temporaryResource) resourceReport {
+	report := resourceReport{
+		Kind:      resource.Kind,
+		ID:        resource.ID,
+		Name:      resource.Name,
+		CreatedAt: resource.createdAt,
+		State:     resourceStateNotDeleted,
+	}
+
+	switch {
+	case resource.err != nil:
+		report.State = resourceStateLeftBehind
+		report.Error = resource.err.Error()
+	case !resource.deletedAt.IsZero():
+		deletedAt := resource.deletedAt
+		report.State = resourceStateDeleted
+		report.DeletedAt = &deletedAt
+	}
+	return report
+}
+
+// write writes the report to the file as JSON.
+func (r *buildReport) write(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// timedStep records the durations of the Run and Cleanup of a step in the build report.
+type timedStep struct {
+	multistep.Step
+
+	name   string
+	report *buildReport
+	entry  *stepReport
+}
+
+func (s *timedStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	entry := &stepReport{
+		Name:      s.name,
+		StartedAt: time.Now(),
+	}
+	s.report.mu.Lock()
+	s.report.Steps = append(s.report.Steps, entry)
+	s.report.mu.Unlock()
+
+	action := s.Step.Run(ctx, state)
+
+	s.report.mu.Lock()
+	defer s.report.mu.Unlock()
+	entry.Duration = durationSeconds(time.Since(entry.StartedAt))
+	entry.Action = "continue"
+	if action == multistep.ActionHalt {
+		entry.Action = "halt"
+	}
+	s.entry = entry
+	return action
+}
+
+// InnerStepName implements multistep.StepWrapper, so that the debug runner shows the name of the
+// wrapped step.
+func (s *timedStep) InnerStepName() string {
+	if wrapped, ok := s.Step.(multistep.StepWrapper); ok {
+		return wrapped.InnerStepName()
+	}
+	return reflect.Indirect(reflect.ValueOf(s.Step)).Type().Name()
+}
+
+func (s *timedStep) Cleanup(state multistep.StateBag) {
+	startedAt := time.Now()
+	s.Step.Cleanup(state)
+
+	if s.entry == nil {
+		return
+	}
+	s.report.mu.Lock()
+	defer s.report.mu.Unlock()
+	cleanup := durationSeconds(time.Since(startedAt))
+	s.entry.Cleanup = &cleanup
+}
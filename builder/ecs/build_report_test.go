@@ -0,0 +1,135 @@
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/huaweicloud/packer-builder-huaweicloud/internal/fakecloud"
+)
+
+func readBuildReport(t *testing.T, path string) *buildReport {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("the build report is not written: %s", err)
+	}
+	report := &buildReport{}
+	if err := json.Unmarshal(content, report); err != nil {
+		t.Fatalf("the build report is not valid JSON: %s\n%s", err, content)
+	}
+	return report
+}
+
+func TestBuilder_Run_FakeCloudBuildReport(t *testing.T) {
+	cloud := fakecloud.New(t)
+	path := filepath.Join(t.TempDir(), "report.json")
+	b := testFakeCloudBuilder(t, cloud, map[string]interface{}{
+		"build_report_file": path,
+	})
+
+	artifact, err := b.Run(context.Background(), packer.TestUi(t), &packer.MockHook{})
+	if err != nil {
+		t.Fatalf("build failed: %s", err)
+	}
+
+	report := readBuildReport(t, path)
+	if report.Region != cloud.Region || report.AvailabilityZone == "" || report.Flavor != "s6.large.2" {
+		t.Fatalf("unexpected region, availability zone or flavor: %s, %s, %s",
+			report.Region, report.AvailabilityZone, report.Flavor)
+	}
+	if report.Artifact != artifact.Id() || report.Error != "" {
+		t.Fatalf("unexpected result: %s, %s", report.Artifact, report.Error)
+	}
+
+	source := report.SourceImage
+	if source == nil || source.ResolvedBy != sourceImageByName || source.Name != "Ubuntu 22.04 server 64bit" ||
+		source.ID == "" || source.Matches != 1 {
+		t.Fatalf("unexpected source image: %+v", source)
+	}
+
+	if len(report.ImageJobs) != 1 || report.ImageJobs[0].ImageID != artifact.Id() ||
+		report.ImageJobs[0].JobID == "" || report.ImageJobs[0].Status != "SUCCESS" {
+		t.Fatalf("unexpected image jobs: %+v", report.ImageJobs)
+	}
+
+	kinds := map[string]bool{}
+	for _, resource := range report.TemporaryResources {
+		kinds[resource.Kind] = true
+		if resource.State != resourceStateDeleted || resource.DeletedAt == nil ||
+			resource.DeletedAt.Before(resource.CreatedAt) {
+			t.Fatalf("unexpected temporary resource: %+v", resource)
+		}
+	}
+	for _, kind := range []string{"keypair", "VPC", "subnet", "public IP", "server"} {
+		if !kinds[kind] {
+			t.Errorf("the temporary %s is missing in the report: %+v", kind, report.TemporaryResources)
+		}
+	}
+
+	steps := map[string]*stepReport{}
+	for _, step := range report.Steps {
+		steps[step.Name] = step
+	}
+	if len(report.Steps) != len(b.buildExecuteSteps()) {
+		t.Fatalf("expected all the steps to be timed, got %d", len(report.Steps))
+	}
+	for _, name := range []string{"ecs.stepCleanupResources", "ecs.StepLoadAZ", "ecs.stepCreateImage"} {
+		step, ok := steps[name]
+		if !ok || step.Action != "continue" || step.Cleanup == nil {
+			t.Fatalf("unexpected timing of the step %s: %+v", name, step)
+		}
+	}
+}
+
+func TestBuilder_Run_FakeCloudBuildReportOnFailure(t *testing.T) {
+	cloud := fakecloud.New(t)
+	cloud.FailJob(fakecloud.JobCreateImage, "IMG.0030", "the disk of the server is broken")
+	path := filepath.Join(t.TempDir(), "report.json")
+	b := testFakeCloudBuilder(t, cloud, map[string]interface{}{
+		"build_report_file": path,
+	})
+
+	if _, err := b.Run(context.Background(), packer.TestUi(t), &packer.MockHook{}); err == nil {
+		t.Fatal("the build should fail")
+	}
+
+	report := readBuildReport(t, path)
+	if report.Error == "" || report.Artifact != "" {
+		t.Fatalf("unexpected result: %s, %s", report.Artifact, report.Error)
+	}
+	if len(report.ImageJobs) != 1 || report.ImageJobs[0].Status != "FAIL" || report.ImageJobs[0].Error == "" {
+		t.Fatalf("unexpected image jobs: %+v", report.ImageJobs)
+	}
+
+	last := report.Steps[len(report.Steps)-1]
+	if last.Name != "ecs.stepCreateImage" || last.Action != "halt" {
+		t.Fatalf("expected the build to halt at creating the image, got %+v", last)
+	}
+	for _, resource := range report.TemporaryResources {
+		if resource.State != resourceStateDeleted {
+			t.Fatalf("unexpected temporary resource: %+v", resource)
+		}
+	}
+}
+
+func TestTimedStep_InnerStepName(t *testing.T) {
+	report := &buildReport{}
+	steps := report.timeSteps([]multistep.Step{&StepStopServer{}, &commonsteps.StepProvision{}})
+	for i, expected := range []string{"StepStopServer", "StepProvision"} {
+		wrapped, ok := steps[i].(multistep.StepWrapper)
+		if !ok {
+			t.Fatalf("the timed step should implement multistep.StepWrapper")
+		}
+		if name := wrapped.InnerStepName(); name != expected {
+			t.Errorf("expected the step name %s, got %s", expected, name)
+		}
+	}
+}
@@ -33,11 +33,31 @@ type Config struct {
 	RunConfig    `mapstructure:",squash"`
 
 	ctx interpolate.Context
+	// report records the build report when build_report_file is set
+	report *buildReport
+	// waitSleep sleeps between the checks of the waiters and the cleanup retries, it sleeps for
+	// the duration if nil, the tests skip the sleeps with it
+	waitSleep SleepFunc
 }
 
 type Builder struct {
 	config Config
 	runner multistep.Runner
+	// connectStep connects to the server with the communicator, it defaults to
+	// communicator.StepConnect if nil, the tests connect with a mock communicator
+	connectStep multistep.Step
+}
+
+// connect returns the step which connects to the server with the communicator.
+func (b *Builder) connect() multistep.Step {
+	if b.connectStep != nil {
+		return b.connectStep
+	}
+	return &communicator.StepConnect{
+		Config:    &b.config.RunConfig.Comm,
+		Host:      CommHost(b.config.RunConfig.Comm.SSHHost),
+		SSHConfig: b.config.RunConfig.Comm.SSHConfigFunc(),
+	}
 }
 
 func (b *Builder) ConfigSpec() hcldec.ObjectSpec {
@@ -80,7 +100,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 
 func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 	region := b.config.Region
-	imsClient, err := b.config.HcImsClient(region)
+	imsClient, err := b.config.imageClient(region)
 	if err != nil {
 		return nil, fmt.Errorf("Error initializing image client: %s", err)
 	}
@@ -91,19 +111,47 @@ func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (pack
 	state.Put("hook", hook)
 	state.Put("ui", ui)
 
+	b.config.report = nil
+	if b.config.BuildReportFile != "" {
+		b.config.report = newBuildReport(&b.config)
+	}
+
 	// Build the steps
-	steps := b.buildExecuteSteps()
+	steps := b.config.report.timeSteps(b.buildExecuteSteps())
 
 	// Run!
 	b.runner = commonsteps.NewRunner(steps, b.config.PackerConfig, ui)
 	b.runner.Run(ctx, state)
 
+	artifact, err := b.artifact(state, imsClient)
+	if b.config.report != nil {
+		var artifactID string
+		if artifact != nil {
+			artifactID = artifact.ImageId
+		}
+		b.config.report.finish(state, artifactID, err)
+		if reportErr := b.config.report.write(b.config.BuildReportFile); reportErr != nil {
+			ui.Error(fmt.Sprintf("Error writing the build report to %s: %s", b.config.BuildReportFile, reportErr))
+		}
+	}
+
+	if err != nil || artifact == nil {
+		_, halted := state.GetOk(multistep.StateHalted)
+		if err == nil && b.config.SkipCreateImage && !halted {
+			ui.Say("No image was created since skip_create_image is set. No artifact is returned")
+		}
+		return nil, err
+	}
+	return artifact, nil
+}
+
+func (b *Builder) artifact(state multistep.StateBag, imsClient ImageClient) (*Artifact, error) {
 	// If there was an error, return that
 	if rawErr, ok := state.GetOk("error"); ok {
 		return nil, rawErr.(error)
 	}
 
-	// If there are no images, then just return
+	// If there are no images, then just return, e.g. with validate_only or skip_create_image
 	if _, ok := state.GetOk("image"); !ok {
 		return nil, nil
 	}
@@ -111,6 +159,7 @@ func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (pack
 	// Build the artifact and return it
 	artifact := &Artifact{
 		ImageId:        state.Get("image").(string),
+		Region:         b.config.Region,
 		BuilderIdValue: BuilderId,
 		Client:         imsClient,
 	}
@@ -120,6 +169,12 @@ func (b *Builder) Run(ctx context.Context, ui packer.Ui, hook packer.Hook) (pack
 
 func (b *Builder) buildExecuteSteps() []multistep.Step {
 	steps := []multistep.Step{
+		// the quotas and the referenced resources are checked before any resource is created
+		&stepPreflight{
+			ValidateOnly: b.config.ValidateOnly,
+		},
+		// the temporary resources are deleted when the step is cleaned up
+		&stepCleanupResources{},
 		&StepLoadAZ{
 			AvailabilityZone: b.config.AvailabilityZone,
 		},
@@ -177,20 +232,25 @@ func (b *Builder) buildExecuteSteps() []multistep.Step {
 			Debug: b.config.PackerDebug,
 			Comm:  &b.config.RunConfig.Comm,
 		},
-		&communicator.StepConnect{
-			Config:    &b.config.RunConfig.Comm,
-			Host:      CommHost(b.config.RunConfig.Comm.SSHHost),
-			SSHConfig: b.config.RunConfig.Comm.SSHConfigFunc(),
+		&stepCaptureConsole{
+			LogPath: fmt.Sprintf("ecs_%s_console.log", b.config.PackerBuildName),
 		},
+		b.connect(),
 		&commonsteps.StepProvision{},
+		&stepProvisioned{},
 		&commonsteps.StepCleanupTempKeys{
 			Comm: &b.config.RunConfig.Comm,
 		},
 		&StepStopServer{},
-		&stepCreateImage{
-			WaitTimeout: b.config.WaitImageReadyTimeout,
-		},
-		&stepAddImageMembers{},
+	}
+	if !b.config.SkipCreateImage {
+		nextSteps = append(nextSteps,
+			&stepCreateImage{
+				WaitTimeout: b.config.WaitImageReadyTimeout,
+			},
+			&stepAddImageMembers{},
+			&stepReplaceImages{},
+		)
 	}
 	steps = append(steps, nextSteps...)
 
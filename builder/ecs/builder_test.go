@@ -1,9 +1,20 @@
 package ecs
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/huaweicloud/packer-builder-huaweicloud/internal/fakecloud"
 )
 
 func testConfig() map[string]interface{} {
@@ -40,3 +51,281 @@ func TestBuilder_Prepare_BadType(t *testing.T) {
 		t.Fatalf("prepare should fail")
 	}
 }
+
+// fakeConnect puts a mock communicator into the state instead of connecting to the server,
+// or fails with err.
+type fakeConnect struct {
+	comm *packer.MockCommunicator
+	err  error
+}
+
+func (s *fakeConnect) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	if s.err != nil {
+		state.Put("error", s.err)
+		return multistep.ActionHalt
+	}
+	state.Put("communicator", s.comm)
+	return multistep.ActionContinue
+}
+
+func (s *fakeConnect) Cleanup(multistep.StateBag) {}
+
+// testFakeCloudBuilder prepares a builder which talks to the fake cloud, the project ID is
+// queried from the fake IAM service.
+func testFakeCloudBuilder(t *testing.T, cloud *fakecloud.Server, extra map[string]interface{}) *Builder {
+	t.Helper()
+
+	delay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = delay })
+
+	cloud.AddImage(fakecloud.Image{Name: "Ubuntu 22.04 server 64bit", MinDisk: 40})
+
+	raw := map[string]interface{}{
+		"access_key":         "FAKEACCESSKEY",
+		"secret_key":         "FakeSecretKey",
+		"region":             cloud.Region,
+		"domain_name":        cloud.DomainName,
+		"endpoints":          cloud.Endpoints(),
+		"image_name":         "packer-fake",
+		"source_image_name":  "Ubuntu 22.04 server 64bit",
+		"flavor":             "s6.large.2",
+		"ssh_username":       "root",
+		"eip_type":           "5_bgp",
+		"eip_bandwidth_size": 5,
+	}
+	for k, v := range extra {
+		raw[k] = v
+	}
+
+	b := &Builder{}
+	if _, _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("prepare failed: %s", err)
+	}
+	if b.config.ProjectID != cloud.ProjectID {
+		t.Fatalf("expected the project ID %s, got %s", cloud.ProjectID, b.config.ProjectID)
+	}
+	b.config.waitSleep = skipSleep
+
+	b.connectStep = &fakeConnect{comm: new(packer.MockCommunicator)}
+	return b
+}
+
+func TestBuilder_Run_FakeCloud(t *testing.T) {
+	cloud := fakecloud.New(t)
+	b := testFakeCloudBuilder(t, cloud, map[string]interface{}{
+		"data_disks":    []map[string]interface{}{{"volume_size": 20, "volume_type": "SSD"}},
+		"image_members": []string{"0970dd7a1300f5672ff2c003c60ae116"},
+		"image_tags":    map[string]string{"build": "fake"},
+		"image_type":    SystemDataImageType,
+	})
+
+	hook := &packer.MockHook{}
+	artifact, err := b.Run(context.Background(), packer.TestUi(t), hook)
+	if err != nil {
+		t.Fatalf("build failed: %s", err)
+	}
+	if !hook.RunCalled {
+		t.Fatal("the provision hook should be run")
+	}
+
+	// the system image and the data disk image are created
+	ids := strings.Split(artifact.Id(), ";")
+	if len(ids) != 2 {
+		t.Fatalf("expected 2 images, got %s", artifact.Id())
+	}
+	// the post-processors find the images in the region of the build
+	regionImages := map[string]string{cloud.Region: artifact.Id()}
+	if state := artifact.State(RegionImagesState); !reflect.DeepEqual(state, regionImages) {
+		t.Fatalf("expected the region images %v, got %v", regionImages, state)
+	}
+
+	image, ok := cloud.Image(ids[0])
+	if !ok {
+		t.Fatalf("the image %s does not exist", ids[0])
+	}
+	if image.Name != "packer-fake" || image.MinDisk != 40 || image.Tags["build"] != "fake" {
+		t.Fatalf("unexpected image: %+v", image)
+	}
+	if len(image.Members) != 1 || image.Members[0] != "0970dd7a1300f5672ff2c003c60ae116" {
+		t.Fatalf("unexpected image members: %v", image.Members)
+	}
+
+	dataImage, ok := cloud.Image(ids[1])
+	if !ok {
+		t.Fatalf("the image %s does not exist", ids[1])
+	}
+	if dataImage.Name != "packer-fake-vdb" || dataImage.MinDisk != 20 || dataImage.VolumeID == "" {
+		t.Fatalf("unexpected data disk image: %+v", dataImage)
+	}
+
+	if leftovers := cloud.Leftovers(); len(leftovers) > 0 {
+		t.Fatalf("the temporary resources are not deleted: %v", leftovers)
+	}
+}
+
+func TestBuilder_Run_FakeCloudSkipCreateImage(t *testing.T) {
+	cloud := fakecloud.New(t)
+	b := testFakeCloudBuilder(t, cloud, map[string]interface{}{
+		"data_disks":        []map[string]interface{}{{"volume_size": 20, "volume_type": "SSD"}},
+		"image_members":     []string{"0970dd7a1300f5672ff2c003c60ae116"},
+		"skip_create_image": true,
+	})
+
+	hook := &packer.MockHook{}
+	output := new(bytes.Buffer)
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: output, ErrorWriter: output}
+	artifact, err := b.Run(context.Background(), ui, hook)
+	if err != nil {
+		t.Fatalf("build failed: %s", err)
+	}
+	if !hook.RunCalled {
+		t.Fatal("the provision hook should be run")
+	}
+	// no artifact is passed to the post-processors
+	if artifact != nil {
+		t.Fatalf("expected no artifact, got %v", artifact)
+	}
+	if !strings.Contains(output.String(), "No image was created since skip_create_image is set") {
+		t.Fatalf("expected the skip_create_image message in the output, got:\n%s", output.String())
+	}
+
+	// the server is stopped, but no image is created
+	if n := cloud.CountRequests("ecs", http.MethodPost, `/cloudservers/action$`); n == 0 {
+		t.Fatal("the server should be stopped")
+	}
+	if n := cloud.CountRequests("ims", http.MethodPost, ""); n > 0 {
+		t.Fatalf("expected no image to be created, got %d requests", n)
+	}
+	if leftovers := cloud.Leftovers(); len(leftovers) > 0 {
+		t.Fatalf("the temporary resources are not deleted: %v", leftovers)
+	}
+}
+
+func TestBuilder_Run_FakeCloudImageJobFailure(t *testing.T) {
+	cloud := fakecloud.New(t)
+	cloud.FailJob(fakecloud.JobCreateImage, "IMG.0030", "the disk of the server is broken")
+	b := testFakeCloudBuilder(t, cloud, nil)
+
+	_, err := b.Run(context.Background(), packer.TestUi(t), &packer.MockHook{})
+	if err == nil {
+		t.Fatal("the build should fail")
+	}
+	if !strings.Contains(err.Error(), "IMG.0030") {
+		t.Fatalf("the error should contain the job error code: %s", err)
+	}
+
+	if images := cloud.Images("private"); len(images) > 0 {
+		t.Fatalf("no image should be created: %v", images)
+	}
+	if leftovers := cloud.Leftovers(); len(leftovers) > 0 {
+		t.Fatalf("the temporary resources are not deleted: %v", leftovers)
+	}
+}
+
+func TestBuilder_Run_FakeCloudRetry(t *testing.T) {
+	cloud := fakecloud.New(t)
+	cloud.AddRule(fakecloud.Rule{
+		Service: "ecs",
+		Method:  http.MethodPost,
+		Path:    `/cloudservers$`,
+		Status:  http.StatusServiceUnavailable,
+		Code:    "Ecs.0000",
+		Times:   2,
+	})
+	b := testFakeCloudBuilder(t, cloud, nil)
+
+	if _, err := b.Run(context.Background(), packer.TestUi(t), &packer.MockHook{}); err != nil {
+		t.Fatalf("build failed: %s", err)
+	}
+	if n := cloud.CountRequests("ecs", http.MethodPost, `/cloudservers$`); n != 3 {
+		t.Fatalf("expected the server to be created in 3 requests, got %d", n)
+	}
+	if n := len(cloud.Instances()); n != 0 {
+		t.Fatalf("expected no servers, got %d", n)
+	}
+}
+
+func TestBuilder_Run_FakeCloudWindowsPassword(t *testing.T) {
+	cloud := fakecloud.New(t)
+	cloud.AddImage(fakecloud.Image{Name: "Windows Server 2019 Datacenter 64bit", OsType: "Windows", MinDisk: 50})
+	b := testFakeCloudBuilder(t, cloud, map[string]interface{}{
+		"source_image_name": "Windows Server 2019 Datacenter 64bit",
+		"communicator":      "winrm",
+		"winrm_username":    "Administrator",
+	})
+
+	if _, err := b.Run(context.Background(), packer.TestUi(t), &packer.MockHook{}); err != nil {
+		t.Fatalf("build failed: %s", err)
+	}
+	if b.config.Comm.WinRMPassword != fakecloud.WindowsPassword {
+		t.Fatalf("expected the password to be decrypted, got %q", b.config.Comm.WinRMPassword)
+	}
+	if leftovers := cloud.Leftovers(); len(leftovers) > 0 {
+		t.Fatalf("the temporary resources are not deleted: %v", leftovers)
+	}
+}
+
+func TestBuilder_Run_FakeCloudConsoleOnTimeout(t *testing.T) {
+	// the console log is saved to the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	cloud := fakecloud.New(t)
+	b := testFakeCloudBuilder(t, cloud, map[string]interface{}{"packer_build_name": "timeout"})
+	b.connectStep = &fakeConnect{err: errors.New("Timeout waiting for SSH.")}
+
+	output := new(bytes.Buffer)
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: output, ErrorWriter: output}
+	if _, err := b.Run(context.Background(), ui, &packer.MockHook{}); err == nil || !strings.Contains(err.Error(), "Timeout") {
+		t.Fatalf("expected the timeout error, got %v", err)
+	}
+
+	data, err := os.ReadFile("ecs_timeout_console.log")
+	if err != nil {
+		t.Fatalf("the console log is not saved: %s", err)
+	}
+	if string(data) != cloud.ConsoleOutput {
+		t.Fatalf("unexpected console log: %q", data)
+	}
+	for _, expected := range []string{"Ubuntu 22.04.3 LTS ecs-packer ttyS0", "vnc_auto.html?instance_id="} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("expected %q in the output, got:\n%s", expected, output.String())
+		}
+	}
+
+	// the console is captured before the server is deleted
+	if leftovers := cloud.Leftovers(); len(leftovers) > 0 {
+		t.Fatalf("the temporary resources are not deleted: %v", leftovers)
+	}
+	if n := cloud.CountRequests("ecs", http.MethodPost, "/servers/[^/]+/action$"); n != 1 {
+		t.Fatalf("expected the console log to be queried once, got %d", n)
+	}
+}
+
+func TestBuilder_Run_FakeCloudNoConsoleAfterProvision(t *testing.T) {
+	cloud := fakecloud.New(t)
+	cloud.AddRule(fakecloud.Rule{
+		Service: "ims",
+		Method:  http.MethodPost,
+		Path:    `/cloudimages/action$`,
+		Status:  http.StatusBadRequest,
+		Code:    "IMG.0001",
+		Message: "The image can not be created.",
+	})
+	b := testFakeCloudBuilder(t, cloud, nil)
+
+	if _, err := b.Run(context.Background(), packer.TestUi(t), &packer.MockHook{}); err == nil {
+		t.Fatal("the build should fail")
+	}
+	// the console is only captured when the build fails to connect or provision
+	if n := cloud.CountRequests("ecs", http.MethodPost, "/servers/[^/]+/action$"); n > 0 {
+		t.Fatalf("expected the console not to be captured, got %d requests", n)
+	}
+}
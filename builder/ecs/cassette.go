@@ -0,0 +1,339 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	cassetteModeRecord = "record"
+	cassetteModeReplay = "replay"
+)
+
+// cassetteGeneratedFields are the paths of the request body fields which are generated in each
+// build, e.g. the random names of the temporary VPC and subnet and the randomly selected zone,
+// they're not compared when replaying.
+var cassetteGeneratedFields = []string{
+	"vpc.name",
+	"subnet.name",
+	"bandwidth.name",
+	"server.availability_zone",
+}
+
+// cassetteFromEnv returns the cassette specified by HW_HTTP_CASSETTE, the requests of the service
+// clients are recorded to the file or replayed from it, depending on HW_HTTP_CASSETTE_MODE
+// ("record" or "replay", the default is "replay"). It returns nil if HW_HTTP_CASSETTE is not set.
+func cassetteFromEnv() *cassette {
+	path := os.Getenv("HW_HTTP_CASSETTE")
+	if path == "" {
+		return nil
+	}
+
+	mode := os.Getenv("HW_HTTP_CASSETTE_MODE")
+	if mode == "" {
+		mode = cassetteModeReplay
+	}
+	return &cassette{path: path, mode: mode}
+}
+
+// cassetteFile is the content of a cassette file.
+type cassetteFile struct {
+	// Variables are the inputs of the recorded build, e.g. the IDs of the existing resources,
+	// so that the same requests are sent when the build is replayed
+	Variables    map[string]string `json:"variables,omitempty"`
+	Interactions []*interaction    `json:"interactions"`
+}
+
+// interaction is a recorded request and its response, the sensitive fields are redacted.
+type interaction struct {
+	Request struct {
+		// Service is the name of the service which the client sends the request to, e.g. "ecs"
+		Service string      `json:"service"`
+		Method  string      `json:"method"`
+		URL     string      `json:"url"`
+		Body    interface{} `json:"body,omitempty"`
+	} `json:"request"`
+	Response struct {
+		StatusCode int               `json:"status_code"`
+		Headers    map[string]string `json:"headers,omitempty"`
+		// Body is the decoded JSON body
+		Body interface{} `json:"body,omitempty"`
+	} `json:"response"`
+
+	used bool
+}
+
+// cassette records the HTTP interactions to a file or replays them. When replaying, a request is
+// answered with the first unused interaction of the same service, method, path, query and body,
+// the sensitive fields are redacted before comparing. The host is ignored so that the interactions
+// recorded with the endpoint overrides can be replayed with the default endpoints.
+// Only the JSON bodies are recorded, as the other bodies can not be redacted.
+type cassette struct {
+	path string
+	mode string
+
+	mu      sync.Mutex
+	loaded  bool
+	loadErr error
+	file    cassetteFile
+}
+
+// loadCassette reads the cassette file.
+func loadCassette(path string) (*cassetteFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the cassette %s: %w", path, err)
+	}
+
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse the cassette %s: %s", path, err)
+	}
+	return &file, nil
+}
+
+// load reads the cassette file in replay mode, it's called with the lock held.
+func (c *cassette) load() error {
+	if c.loaded {
+		return c.loadErr
+	}
+	c.loaded = true
+
+	if c.mode != cassetteModeReplay {
+		return nil
+	}
+	file, err := loadCassette(c.path)
+	if err != nil {
+		c.loadErr = err
+		return err
+	}
+	c.file = *file
+	return nil
+}
+
+// SetVariables records the inputs of the build, they're saved with the interactions.
+func (c *cassette) SetVariables(variables map[string]string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.file.Variables = variables
+	if c.mode == cassetteModeRecord {
+		return c.save()
+	}
+	return nil
+}
+
+// save writes the cassette file, it's called with the lock held.
+func (c *cassette) save() error {
+	data, err := json.MarshalIndent(c.file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, append(data, '\n'), 0o600)
+}
+
+// transport returns an HTTP transport which records the requests sent to the service through
+// the transport or replays them without sending.
+func (c *cassette) transport(service string, rt http.RoundTripper) *http.Transport {
+	// the SDK clients require an *http.Transport, see NewTransport
+	transport := &http.Transport{}
+	cassetteTransport := &requestIDRoundTripper{
+		rt: &cassetteRoundTripper{rt: rt, cassette: c, service: service},
+	}
+	transport.RegisterProtocol("https", cassetteTransport)
+	transport.RegisterProtocol("http", cassetteTransport)
+	return transport
+}
+
+// cassetteKey returns the service, method, path, the redacted query and the redacted body of
+// the request, the body is encoded with the keys sorted and the generated fields masked.
+func cassetteKey(service, method string, u *url.URL, body interface{}) string {
+	redacted := *u
+	redacted.Scheme = ""
+	redacted.Host = ""
+	redacted.User = nil
+	// the SDK may join the endpoint and the path with an extra slash
+	redacted.Path = "/" + strings.TrimLeft(redacted.Path, "/")
+	redacted.RawPath = ""
+
+	key := service + " " + method + " " + defaultRedactor().RedactQuery(&redacted)
+	if body != nil {
+		// the body is decoded from JSON, so it can always be encoded, it's decoded again as
+		// the generated fields are masked in place
+		var masked interface{}
+		data, _ := json.Marshal(body)
+		_ = json.Unmarshal(data, &masked)
+		data, _ = json.Marshal(NewRedactor(cassetteGeneratedFields...).RedactJSON(masked))
+		key += " " + string(data)
+	}
+	return key
+}
+
+type cassetteRoundTripper struct {
+	rt       http.RoundTripper
+	cassette *cassette
+	service  string
+}
+
+func (r *cassetteRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := r.cassette
+	c.mu.Lock()
+	err := c.load()
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.mode {
+	case cassetteModeRecord:
+		return r.record(req)
+	case cassetteModeReplay:
+		return r.replay(req)
+	default:
+		return nil, fmt.Errorf("invalid HW_HTTP_CASSETTE_MODE %q, expected %q or %q",
+			c.mode, cassetteModeRecord, cassetteModeReplay)
+	}
+}
+
+// readRequestBody reads the body of the request, the body is restored so that the request can
+// still be sent.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func (r *cassetteRoundTripper) record(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	requestBody, err := redactCassetteBody(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record the request %s %s: %s", req.Method, req.URL.Path, err)
+	}
+
+	resp, err := r.rt.RoundTrip(req)
+	if err != nil {
+		// the failed requests are not recorded, they can not be replayed
+		return resp, err
+	}
+
+	responseBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	item := &interaction{}
+	item.Request.Service = r.service
+	item.Request.Method = req.Method
+	item.Request.URL = defaultRedactor().RedactQuery(req.URL)
+	item.Request.Body = requestBody
+	item.Response.StatusCode = resp.StatusCode
+	item.Response.Headers = redactHeaderMap(resp.Header)
+	// the replayed body is encoded again, so its length may be different
+	delete(item.Response.Headers, "Content-Length")
+	item.Response.Body, err = redactCassetteBody(responseBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record the response of %s %s: %s", req.Method, req.URL.Path, err)
+	}
+
+	c := r.cassette
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.file.Interactions = append(c.file.Interactions, item)
+	if err := c.save(); err != nil {
+		log.Printf("[WARN] failed to save the cassette %s: %s", c.path, err)
+	}
+	return resp, nil
+}
+
+func (r *cassetteRoundTripper) replay(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	requestBody, err := redactCassetteBody(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay the request %s %s: %s", req.Method, req.URL.Path, err)
+	}
+	key := cassetteKey(r.service, req.Method, req.URL, requestBody)
+
+	c := r.cassette
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, item := range c.file.Interactions {
+		if item.used {
+			continue
+		}
+		recorded, err := url.Parse(item.Request.URL)
+		if err != nil || cassetteKey(item.Request.Service, item.Request.Method, recorded, item.Request.Body) != key {
+			continue
+		}
+
+		item.used = true
+		var body []byte
+		if item.Response.Body != nil {
+			if body, err = json.Marshal(item.Response.Body); err != nil {
+				return nil, err
+			}
+		}
+
+		header := make(http.Header, len(item.Response.Headers))
+		for name, value := range item.Response.Headers {
+			header.Set(name, value)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", item.Response.StatusCode, http.StatusText(item.Response.StatusCode)),
+			StatusCode:    item.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no interaction in the cassette %s matches the request %s", c.path, key)
+}
+
+// redactCassetteBody decodes the JSON body and masks the sensitive fields, see Redactor.
+// The other bodies are refused, they may contain sensitive information which can not be redacted.
+func redactCassetteBody(body []byte) (interface{}, error) {
+	if len(body) == 0 {
+		return nil, nil
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("only the JSON bodies can be recorded: %s", err)
+	}
+	return defaultRedactor().RedactJSON(data), nil
+}
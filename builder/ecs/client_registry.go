@@ -0,0 +1,85 @@
+package ecs
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core"
+)
+
+// registryMu guards the lazy creation of the client registry of AccessConfig.
+var registryMu sync.Mutex
+
+type clientKey struct {
+	service string
+	region  string
+}
+
+// clientRegistry caches the service clients per service and region. All the clients share
+// the same HTTP transport, so the connections are reused across the steps.
+type clientRegistry struct {
+	mu        sync.Mutex
+	transport *http.Transport
+	clients   map[clientKey]interface{}
+	// cassette records or replays the requests of the service clients, see cassetteFromEnv
+	cassette *cassette
+}
+
+// clientRegistry returns the client registry of AccessConfig, it's created on the first call
+// so that the TLS settings have been prepared.
+func (c *AccessConfig) clientRegistry() *clientRegistry {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if c.clients == nil {
+		c.clients = &clientRegistry{
+			transport: c.NewTransport(),
+			clients:   make(map[clientKey]interface{}),
+			cassette:  cassetteFromEnv(),
+		}
+	}
+	return c.clients
+}
+
+// HTTPTransport returns the HTTP transport shared by all the service clients.
+func (c *AccessConfig) HTTPTransport() *http.Transport {
+	return c.clientRegistry().transport
+}
+
+// setClient replaces the client of the service in the region, it's used to inject test doubles.
+func (c *AccessConfig) setClient(service, region string, client interface{}) {
+	r := c.clientRegistry()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.clients[clientKey{service: service, region: region}] = client
+}
+
+// serviceClient returns the cached client of the service in the region,
+// the client is built with newClient on the first call.
+func serviceClient[T any](c *AccessConfig, service, region string, newClient func(*core.HcHttpClient) T) (T, error) {
+	r := c.clientRegistry()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := clientKey{service: service, region: region}
+	if cached, ok := r.clients[key]; ok {
+		if client, ok := cached.(T); ok {
+			return client, nil
+		}
+
+		var zero T
+		return zero, fmt.Errorf("the cached %s client in region %s is %T, not %T", service, region, cached, zero)
+	}
+
+	hcClient, err := NewHcClient(c, region, service)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	client := newClient(hcClient)
+	r.clients[key] = client
+	return client, nil
+}
@@ -0,0 +1,52 @@
+package ecs
+
+import (
+	"testing"
+
+	ecs "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2"
+)
+
+func TestClientRegistry(t *testing.T) {
+	c := &AccessConfig{
+		AccessKey: "ak",
+		SecretKey: "sk",
+		ProjectID: "project-1",
+		Endpoints: map[string]string{"ecs": "https://ecs.example.com/"},
+	}
+
+	client, err := c.HcEcsClient("cn-north-4")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cached, err := c.HcEcsClient("cn-north-4")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if client != cached {
+		t.Fatalf("expected the ECS client to be cached")
+	}
+
+	other, err := c.HcEcsClient("cn-east-3")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if client == other {
+		t.Fatalf("expected a new ECS client in another region")
+	}
+
+	// the test doubles are injected into the registry
+	injected := &ecs.EcsClient{}
+	c.setClient("ecs", "cn-south-1", injected)
+	if client, _ := c.HcEcsClient("cn-south-1"); client != injected {
+		t.Fatalf("expected the injected ECS client")
+	}
+
+	c.setClient("ims", "cn-south-1", injected)
+	if _, err := c.HcImsClient("cn-south-1"); err == nil {
+		t.Fatalf("expected an error for the mismatched client type")
+	}
+
+	if c.HTTPTransport() != c.HTTPTransport() {
+		t.Fatalf("expected the HTTP transport to be shared")
+	}
+}
@@ -0,0 +1,256 @@
+package ecs
+
+import (
+	"fmt"
+
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core"
+	cbr "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/cbr/v1"
+	cbrmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/cbr/v1/model"
+	ecs "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2"
+	ecsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2/model"
+	eip "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/eip/v2"
+	eipmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/eip/v2/model"
+	evs "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/evs/v2"
+	evsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/evs/v2/model"
+	ims "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2"
+	imsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2/model"
+	kms "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/kms/v2"
+	kmsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/kms/v2/model"
+	vpc "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/vpc/v2"
+	vpcmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/vpc/v2/model"
+)
+
+// The steps depend on the narrow interfaces below rather than the SDK clients, so that they can
+// be tested with fakes. The SDK clients implement them, e.g. *ecs.EcsClient implements ServerClient,
+// ServerVolumeClient and KeypairClient.
+
+// ServerJobClient queries the asynchronous ECS jobs.
+type ServerJobClient interface {
+	ShowJob(*ecsmodel.ShowJobRequest) (*ecsmodel.ShowJobResponse, error)
+}
+
+// ServerClient manages the lifecycle of the ECS servers.
+type ServerClient interface {
+	ServerJobClient
+	CreatePostPaidServers(*ecsmodel.CreatePostPaidServersRequest) (*ecsmodel.CreatePostPaidServersResponse, error)
+	DeleteServers(*ecsmodel.DeleteServersRequest) (*ecsmodel.DeleteServersResponse, error)
+	ShowServer(*ecsmodel.ShowServerRequest) (*ecsmodel.ShowServerResponse, error)
+	BatchStopServers(*ecsmodel.BatchStopServersRequest) (*ecsmodel.BatchStopServersResponse, error)
+	ShowServerPassword(*ecsmodel.ShowServerPasswordRequest) (*ecsmodel.ShowServerPasswordResponse, error)
+	ListServerInterfaces(*ecsmodel.ListServerInterfacesRequest) (*ecsmodel.ListServerInterfacesResponse, error)
+	ListServerBlockDevices(*ecsmodel.ListServerBlockDevicesRequest) (*ecsmodel.ListServerBlockDevicesResponse, error)
+	NovaListAvailabilityZones(*ecsmodel.NovaListAvailabilityZonesRequest) (*ecsmodel.NovaListAvailabilityZonesResponse, error)
+}
+
+// ServerVolumeClient attaches the volumes to and detaches them from the ECS servers.
+type ServerVolumeClient interface {
+	ServerJobClient
+	AttachServerVolume(*ecsmodel.AttachServerVolumeRequest) (*ecsmodel.AttachServerVolumeResponse, error)
+	DetachServerVolume(*ecsmodel.DetachServerVolumeRequest) (*ecsmodel.DetachServerVolumeResponse, error)
+}
+
+// KeypairClient manages the SSH key pairs.
+type KeypairClient interface {
+	NovaCreateKeypair(*ecsmodel.NovaCreateKeypairRequest) (*ecsmodel.NovaCreateKeypairResponse, error)
+	NovaDeleteKeypair(*ecsmodel.NovaDeleteKeypairRequest) (*ecsmodel.NovaDeleteKeypairResponse, error)
+}
+
+// ServerQuotaClient queries the ECS quotas and the flavors.
+type ServerQuotaClient interface {
+	ShowServerLimits(*ecsmodel.ShowServerLimitsRequest) (*ecsmodel.ShowServerLimitsResponse, error)
+	ListFlavors(*ecsmodel.ListFlavorsRequest) (*ecsmodel.ListFlavorsResponse, error)
+}
+
+// ServerListClient lists the ECS servers.
+type ServerListClient interface {
+	ListServersDetails(*ecsmodel.ListServersDetailsRequest) (*ecsmodel.ListServersDetailsResponse, error)
+}
+
+// ConsoleClient queries the console log and the remote console of the ECS servers.
+type ConsoleClient interface {
+	// GetConsoleOutput returns the last lines of the console log, or the whole log if length is 0
+	GetConsoleOutput(serverID string, length int) (string, error)
+	ShowServerRemoteConsole(*ecsmodel.ShowServerRemoteConsoleRequest) (*ecsmodel.ShowServerRemoteConsoleResponse, error)
+}
+
+// ImageClient manages the IMS images and queries the image jobs.
+type ImageClient interface {
+	CreateImage(*imsmodel.CreateImageRequest) (*imsmodel.CreateImageResponse, error)
+	CreateWholeImage(*imsmodel.CreateWholeImageRequest) (*imsmodel.CreateWholeImageResponse, error)
+	ShowJob(*imsmodel.ShowJobRequest) (*imsmodel.ShowJobResponse, error)
+	ListImages(*imsmodel.ListImagesRequest) (*imsmodel.ListImagesResponse, error)
+	BatchAddMembers(*imsmodel.BatchAddMembersRequest) (*imsmodel.BatchAddMembersResponse, error)
+	BatchDeleteMembers(*imsmodel.BatchDeleteMembersRequest) (*imsmodel.BatchDeleteMembersResponse, error)
+	GlanceListImageMembers(*imsmodel.GlanceListImageMembersRequest) (*imsmodel.GlanceListImageMembersResponse, error)
+	GlanceDeleteImage(*imsmodel.GlanceDeleteImageRequest) (*imsmodel.GlanceDeleteImageResponse, error)
+}
+
+// ImageTagClient lists the IMS images and manages their tags.
+type ImageTagClient interface {
+	ListImages(*imsmodel.ListImagesRequest) (*imsmodel.ListImagesResponse, error)
+	AddImageTag(*imsmodel.AddImageTagRequest) (*imsmodel.AddImageTagResponse, error)
+	DeleteImageTag(*imsmodel.DeleteImageTagRequest) (*imsmodel.DeleteImageTagResponse, error)
+}
+
+// ImageQuotaClient queries the IMS quotas.
+type ImageQuotaClient interface {
+	ShowImageQuota(*imsmodel.ShowImageQuotaRequest) (*imsmodel.ShowImageQuotaResponse, error)
+}
+
+// NetworkClient manages the VPCs and subnets, and queries the security groups.
+type NetworkClient interface {
+	CreateVpc(*vpcmodel.CreateVpcRequest) (*vpcmodel.CreateVpcResponse, error)
+	ShowVpc(*vpcmodel.ShowVpcRequest) (*vpcmodel.ShowVpcResponse, error)
+	DeleteVpc(*vpcmodel.DeleteVpcRequest) (*vpcmodel.DeleteVpcResponse, error)
+	CreateSubnet(*vpcmodel.CreateSubnetRequest) (*vpcmodel.CreateSubnetResponse, error)
+	ShowSubnet(*vpcmodel.ShowSubnetRequest) (*vpcmodel.ShowSubnetResponse, error)
+	DeleteSubnet(*vpcmodel.DeleteSubnetRequest) (*vpcmodel.DeleteSubnetResponse, error)
+	ShowSecurityGroup(*vpcmodel.ShowSecurityGroupRequest) (*vpcmodel.ShowSecurityGroupResponse, error)
+}
+
+// NetworkQuotaClient queries the VPC quotas.
+type NetworkQuotaClient interface {
+	ShowQuota(*vpcmodel.ShowQuotaRequest) (*vpcmodel.ShowQuotaResponse, error)
+}
+
+// PublicIPClient manages the EIPs.
+type PublicIPClient interface {
+	CreatePublicip(*eipmodel.CreatePublicipRequest) (*eipmodel.CreatePublicipResponse, error)
+	ShowPublicip(*eipmodel.ShowPublicipRequest) (*eipmodel.ShowPublicipResponse, error)
+	DeletePublicip(*eipmodel.DeletePublicipRequest) (*eipmodel.DeletePublicipResponse, error)
+	ListPublicips(*eipmodel.ListPublicipsRequest) (*eipmodel.ListPublicipsResponse, error)
+}
+
+// PublicIPQuotaClient queries the EIP quotas.
+type PublicIPQuotaClient interface {
+	ListQuotas(*eipmodel.ListQuotasRequest) (*eipmodel.ListQuotasResponse, error)
+}
+
+// VolumeClient manages the EVS volumes and queries the snapshots.
+type VolumeClient interface {
+	CreateVolume(*evsmodel.CreateVolumeRequest) (*evsmodel.CreateVolumeResponse, error)
+	ShowJob(*evsmodel.ShowJobRequest) (*evsmodel.ShowJobResponse, error)
+	ListVolumes(*evsmodel.ListVolumesRequest) (*evsmodel.ListVolumesResponse, error)
+	ListSnapshots(*evsmodel.ListSnapshotsRequest) (*evsmodel.ListSnapshotsResponse, error)
+}
+
+// VolumeQuotaClient queries the EVS quotas.
+type VolumeQuotaClient interface {
+	CinderListQuotas(*evsmodel.CinderListQuotasRequest) (*evsmodel.CinderListQuotasResponse, error)
+}
+
+// KeyClient queries the KMS keys used to encrypt the volumes.
+type KeyClient interface {
+	ListKeyDetail(*kmsmodel.ListKeyDetailRequest) (*kmsmodel.ListKeyDetailResponse, error)
+}
+
+// VaultClient queries the CBR vaults used to create the full-ECS images.
+type VaultClient interface {
+	ShowVault(*cbrmodel.ShowVaultRequest) (*cbrmodel.ShowVaultResponse, error)
+}
+
+// The accessors below share the cached clients with the HcXxxClient methods, a fake registered
+// with setClient is returned as long as it implements the interface.
+
+func (c *AccessConfig) serverClient(region string) (ServerClient, error) {
+	return serviceClient(c, "ecs", region, func(hcClient *core.HcHttpClient) ServerClient {
+		return ecs.NewEcsClient(hcClient)
+	})
+}
+
+func (c *AccessConfig) serverVolumeClient(region string) (ServerVolumeClient, error) {
+	return serviceClient(c, "ecs", region, func(hcClient *core.HcHttpClient) ServerVolumeClient {
+		return ecs.NewEcsClient(hcClient)
+	})
+}
+
+func (c *AccessConfig) keypairClient(region string) (KeypairClient, error) {
+	return serviceClient(c, "ecs", region, func(hcClient *core.HcHttpClient) KeypairClient {
+		return ecs.NewEcsClient(hcClient)
+	})
+}
+
+func (c *AccessConfig) serverQuotaClient(region string) (ServerQuotaClient, error) {
+	return serviceClient(c, "ecs", region, func(hcClient *core.HcHttpClient) ServerQuotaClient {
+		return ecs.NewEcsClient(hcClient)
+	})
+}
+
+func (c *AccessConfig) consoleClient(region string) (ConsoleClient, error) {
+	client, err := serviceClient(c, "ecs", region, func(hcClient *core.HcHttpClient) interface{} {
+		return ecs.NewEcsClient(hcClient)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the SDK has no API to get the console log, see ecsConsoleClient
+	switch client := client.(type) {
+	case ConsoleClient:
+		return client, nil
+	case *ecs.EcsClient:
+		return &ecsConsoleClient{EcsClient: client}, nil
+	default:
+		return nil, fmt.Errorf("the cached ecs client in region %s is %T, not %T", region, client, (*ecs.EcsClient)(nil))
+	}
+}
+
+func (c *AccessConfig) imageClient(region string) (ImageClient, error) {
+	return serviceClient(c, "ims", region, func(hcClient *core.HcHttpClient) ImageClient {
+		return ims.NewImsClient(hcClient)
+	})
+}
+
+func (c *AccessConfig) imageQuotaClient(region string) (ImageQuotaClient, error) {
+	return serviceClient(c, "ims", region, func(hcClient *core.HcHttpClient) ImageQuotaClient {
+		return ims.NewImsClient(hcClient)
+	})
+}
+
+func (c *AccessConfig) networkClient(region string) (NetworkClient, error) {
+	return serviceClient(c, "vpc", region, func(hcClient *core.HcHttpClient) NetworkClient {
+		return vpc.NewVpcClient(hcClient)
+	})
+}
+
+func (c *AccessConfig) networkQuotaClient(region string) (NetworkQuotaClient, error) {
+	return serviceClient(c, "vpc", region, func(hcClient *core.HcHttpClient) NetworkQuotaClient {
+		return vpc.NewVpcClient(hcClient)
+	})
+}
+
+func (c *AccessConfig) publicIPClient(region string) (PublicIPClient, error) {
+	return serviceClient(c, "eip", region, func(hcClient *core.HcHttpClient) PublicIPClient {
+		return eip.NewEipClient(hcClient)
+	})
+}
+
+func (c *AccessConfig) publicIPQuotaClient(region string) (PublicIPQuotaClient, error) {
+	return serviceClient(c, "eip", region, func(hcClient *core.HcHttpClient) PublicIPQuotaClient {
+		return eip.NewEipClient(hcClient)
+	})
+}
+
+func (c *AccessConfig) volumeClient(region string) (VolumeClient, error) {
+	return serviceClient(c, "evs", region, func(hcClient *core.HcHttpClient) VolumeClient {
+		return evs.NewEvsClient(hcClient)
+	})
+}
+
+func (c *AccessConfig) volumeQuotaClient(region string) (VolumeQuotaClient, error) {
+	return serviceClient(c, "evs", region, func(hcClient *core.HcHttpClient) VolumeQuotaClient {
+		return evs.NewEvsClient(hcClient)
+	})
+}
+
+func (c *AccessConfig) keyClient(region string) (KeyClient, error) {
+	return serviceClient(c, "kms", region, func(hcClient *core.HcHttpClient) KeyClient {
+		return kms.NewKmsClient(hcClient)
+	})
+}
+
+func (c *AccessConfig) vaultClient(region string) (VaultClient, error) {
+	return serviceClient(c, "cbr", region, func(hcClient *core.HcHttpClient) VaultClient {
+		return cbr.NewCbrClient(hcClient)
+	})
+}
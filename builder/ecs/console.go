@@ -0,0 +1,73 @@
+package ecs
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/def"
+	ecs "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2"
+)
+
+// ecsConsoleClient adds the os-getConsoleOutput action of the ECS (Nova) API, which is missing
+// in the SDK, to the ECS client.
+type ecsConsoleClient struct {
+	*ecs.EcsClient
+}
+
+type consoleOutputRequest struct {
+	ServerId string                    `json:"server_id"`
+	Body     *consoleOutputRequestBody `json:"body,omitempty"`
+}
+
+type consoleOutputRequestBody struct {
+	GetConsoleOutput consoleOutputOption `json:"os-getConsoleOutput"`
+}
+
+type consoleOutputOption struct {
+	Length *int `json:"length,omitempty"`
+}
+
+type consoleOutputResponse struct {
+	Output         *string `json:"output,omitempty"`
+	HttpStatusCode int     `json:"-"`
+}
+
+func genReqDefForGetConsoleOutput() *def.HttpRequestDef {
+	reqDefBuilder := def.NewHttpRequestDefBuilder().
+		WithMethod(http.MethodPost).
+		WithPath("/v2.1/{project_id}/servers/{server_id}/action").
+		WithResponse(new(consoleOutputResponse)).
+		WithContentType("application/json;charset=UTF-8")
+
+	reqDefBuilder.WithRequestField(def.NewFieldDef().
+		WithName("ServerId").
+		WithJsonTag("server_id").
+		WithLocationType(def.Path))
+
+	reqDefBuilder.WithRequestField(def.NewFieldDef().
+		WithName("Body").
+		WithLocationType(def.Body))
+
+	return reqDefBuilder.Build()
+}
+
+func (c *ecsConsoleClient) GetConsoleOutput(serverID string, length int) (string, error) {
+	request := &consoleOutputRequest{
+		ServerId: serverID,
+		Body:     &consoleOutputRequestBody{},
+	}
+	if length > 0 {
+		request.Body.GetConsoleOutput.Length = &length
+	}
+
+	resp, err := c.HcClient.Sync(request, genReqDefForGetConsoleOutput())
+	if err != nil {
+		return "", err
+	}
+
+	response, ok := resp.(*consoleOutputResponse)
+	if !ok || response.Output == nil {
+		return "", fmt.Errorf("failed to obtain the console output")
+	}
+	return *response.Output, nil
+}
@@ -0,0 +1,78 @@
+package ecs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// credentialProcessTimeout is the maximum time to wait for the credential process.
+const credentialProcessTimeout = time.Minute
+
+// ProcessCredential is the JSON output of the credential process.
+type ProcessCredential struct {
+	AccessKey     string `json:"access_key"`
+	SecretKey     string `json:"secret_key"`
+	SecurityToken string `json:"security_token"`
+	// ExpiresAt is the expiration time in RFC 3339 format, the credential never expires if it's empty.
+	ExpiresAt string `json:"expires_at"`
+}
+
+// processProvider runs an external command to retrieve the credential.
+type processProvider struct {
+	command string
+}
+
+func (p *processProvider) Retrieve() (*Credential, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), credentialProcessTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd.exe", "/C", p.command)
+	} else {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", p.command)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	log.Printf("[DEBUG] running the credential process")
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("the credential process timed out after %s", credentialProcessTimeout)
+		}
+		return nil, fmt.Errorf("failed to run the credential process: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var output ProcessCredential
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		// the output may contain sensitive information, so don't return it
+		return nil, fmt.Errorf("failed to parse the output of the credential process: %s", err)
+	}
+	if output.AccessKey == "" || output.SecretKey == "" {
+		return nil, fmt.Errorf("access_key and secret_key must be included in the output of the credential process")
+	}
+
+	credential := Credential{
+		AccessKey:     output.AccessKey,
+		SecretKey:     output.SecretKey,
+		SecurityToken: output.SecurityToken,
+	}
+	if output.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339, output.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the expiration time of the credential process: %s", err)
+		}
+		credential.ExpiresAt = expiresAt
+	}
+
+	return &credential, nil
+}
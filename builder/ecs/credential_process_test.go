@@ -0,0 +1,46 @@
+package ecs
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestProcessProvider(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test command requires a POSIX shell")
+	}
+
+	output := `{"access_key": "process-ak", "secret_key": "process-sk", "security_token": "process-token", ` +
+		`"expires_at": "2030-01-01T00:00:00Z"}`
+	provider := processProvider{command: "cat " + writeTestFile(t, "credential.json", output)}
+
+	credential, err := provider.Retrieve()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if credential.AccessKey != "process-ak" || credential.SecretKey != "process-sk" ||
+		credential.SecurityToken != "process-token" {
+		t.Fatalf("bad credential: %#v", credential)
+	}
+	if credential.ExpiresAt.Year() != 2030 {
+		t.Fatalf("bad expiration: %s", credential.ExpiresAt)
+	}
+}
+
+func TestProcessProvider_Failed(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test command requires a POSIX shell")
+	}
+
+	for _, command := range []string{
+		"exit 1",
+		"echo not-json",
+		`echo '{"access_key": "process-ak"}'`,
+		`echo '{"access_key": "process-ak", "secret_key": "process-sk", "expires_at": "tomorrow"}'`,
+	} {
+		provider := processProvider{command: command}
+		if _, err := provider.Retrieve(); err == nil {
+			t.Errorf("the credential process %q should fail", command)
+		}
+	}
+}
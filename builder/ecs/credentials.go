@@ -0,0 +1,182 @@
+package ecs
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/basic"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth/global"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/impl"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/request"
+)
+
+// credentialRefreshWindow is the period before the expiration in which the temporary
+// credential will be refreshed.
+var credentialRefreshWindow = 5 * time.Minute
+
+const (
+	minCredentialDuration = 15 * time.Minute
+	maxCredentialDuration = 24 * time.Hour
+)
+
+// refreshableCredentialsType is the type name of refreshableCredentials which is checked by HcHttpClientBuilder.
+const refreshableCredentialsType = "ecs.refreshableCredentials"
+
+// Credential is the access key, secret key and security token used to sign the requests.
+type Credential struct {
+	AccessKey     string
+	SecretKey     string
+	SecurityToken string
+	// ExpiresAt is the expiration time of a temporary credential, it's zero for a permanent credential.
+	ExpiresAt time.Time
+}
+
+func (c *Credential) needRefresh() bool {
+	if c.ExpiresAt.IsZero() {
+		return false
+	}
+	return time.Until(c.ExpiresAt) < credentialRefreshWindow
+}
+
+// parseCredentialDuration parses the validity period of the temporary credentials,
+// an empty value means the default period of IAM.
+func parseCredentialDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %s", name, err)
+	}
+	if duration < minCredentialDuration || duration > maxCredentialDuration {
+		return 0, fmt.Errorf("%s must be between %s and %s, but got %s",
+			name, minCredentialDuration, maxCredentialDuration, value)
+	}
+	return duration, nil
+}
+
+// CredentialProvider retrieves a credential from a source, such as an IAM agency.
+type CredentialProvider interface {
+	Retrieve() (*Credential, error)
+}
+
+// cachedCredentialProvider caches the credential retrieved from the provider and retrieves
+// a new one only when the cached credential is about to expire.
+type cachedCredentialProvider struct {
+	provider   CredentialProvider
+	credential *Credential
+	lock       sync.Mutex
+}
+
+func newCachedCredentialProvider(provider CredentialProvider) *cachedCredentialProvider {
+	return &cachedCredentialProvider{
+		provider: provider,
+	}
+}
+
+func (p *cachedCredentialProvider) Retrieve() (*Credential, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.credential != nil && !p.credential.needRefresh() {
+		return p.credential, nil
+	}
+
+	credential, err := p.provider.Retrieve()
+	if err != nil {
+		return nil, err
+	}
+
+	if !credential.ExpiresAt.IsZero() {
+		log.Printf("[DEBUG] the temporary credential will expire at %s", credential.ExpiresAt.Format(time.RFC3339))
+	}
+	p.credential = credential
+	return credential, nil
+}
+
+// GetCredential returns the credential to sign the requests, the temporary credential
+// is refreshed automatically before it expires.
+func (c *AccessConfig) GetCredential() (*Credential, error) {
+	if c.credentialProvider == nil {
+		credential := Credential{
+			AccessKey:     c.AccessKey,
+			SecretKey:     c.SecretKey,
+			SecurityToken: c.SecurityToken,
+		}
+		return &credential, nil
+	}
+
+	return c.credentialProvider.Retrieve()
+}
+
+// buildCredentials returns the basic or global credentials used by the service clients.
+func (c *AccessConfig) buildCredentials(isGlobal bool, id string) auth.ICredential {
+	if c.credentialProvider != nil {
+		return &refreshableCredentials{
+			provider: c.credentialProvider,
+			isGlobal: isGlobal,
+			id:       id,
+		}
+	}
+
+	if isGlobal {
+		return &global.Credentials{
+			AK:            c.AccessKey,
+			SK:            c.SecretKey,
+			SecurityToken: c.SecurityToken,
+			DomainId:      id,
+			IamEndpoint:   c.IdentityEndpoint,
+		}
+	}
+	return &basic.Credentials{
+		AK:            c.AccessKey,
+		SK:            c.SecretKey,
+		SecurityToken: c.SecurityToken,
+		ProjectId:     id,
+		IamEndpoint:   c.IdentityEndpoint,
+	}
+}
+
+// refreshableCredentials implements auth.ICredential, it signs every request with the latest
+// credential of the provider, so a long-running build will not fail when the temporary
+// credential expires.
+type refreshableCredentials struct {
+	provider CredentialProvider
+	isGlobal bool
+	// id is the project ID for basic credentials or the domain ID for global credentials
+	id string
+}
+
+func (r *refreshableCredentials) ProcessAuthParams(_ *impl.DefaultHttpClient, _ string) auth.ICredential {
+	return r
+}
+
+func (r *refreshableCredentials) ProcessAuthRequest(client *impl.DefaultHttpClient,
+	req *request.DefaultHttpRequest) (*request.DefaultHttpRequest, error) {
+	credential, err := r.provider.Retrieve()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve the credential: %s", err)
+	}
+
+	if r.isGlobal {
+		globalCredentials := global.Credentials{
+			AK:            credential.AccessKey,
+			SK:            credential.SecretKey,
+			SecurityToken: credential.SecurityToken,
+			DomainId:      r.id,
+		}
+		return globalCredentials.ProcessAuthRequest(client, req)
+	}
+
+	basicCredentials := basic.Credentials{
+		AK:            credential.AccessKey,
+		SK:            credential.SecretKey,
+		SecurityToken: credential.SecurityToken,
+		ProjectId:     r.id,
+	}
+	return basicCredentials.ProcessAuthRequest(client, req)
+}
@@ -28,12 +28,21 @@ var serviceEndpoints = map[string]ServiceCatalog{
 	"evs": {
 		Name: "evs",
 	},
+	"kms": {
+		Name: "kms",
+	},
+	"cbr": {
+		Name: "cbr",
+	},
 	"obs": {
 		Name: "obs",
 	},
 }
 
-func buildDefaultIamEndpoint(region string) string {
+func buildDefaultIamEndpoint(cloud, region string) string {
+	if cloud != "" {
+		return fmt.Sprintf("https://iam.%s.%s", region, cloud)
+	}
 	if strings.HasPrefix(region, "eu-west-10") {
 		// In Europe site(e.g. eu-west-101), the default endpoint is "iam.eu-west-10x.myhuaweicloud.eu"
 		return fmt.Sprintf("https://iam.%s.myhuaweicloud.eu", region)
@@ -58,6 +67,38 @@ func GetServiceEndpoint(cloud, srv, region string) string {
 	return ep
 }
 
+// ServiceEndpoint returns the endpoint of the service, the endpoint in `endpoints`
+// takes precedence over the one derived from the region and cloud.
+func (c *AccessConfig) ServiceEndpoint(srv, region string) string {
+	if ep, ok := c.Endpoints[srv]; ok {
+		return ep
+	}
+	return GetServiceEndpoint(c.Cloud, srv, region)
+}
+
+// validateEndpoints checks the service names and URLs in `endpoints`, and appends a
+// trailing slash to the URLs to keep the same format as the derived endpoints.
+func (c *AccessConfig) validateEndpoints() []error {
+	var errs []error
+	for srv, ep := range c.Endpoints {
+		if _, ok := serviceEndpoints[srv]; !ok && srv != "iam" {
+			errs = append(errs, fmt.Errorf("the service %q in endpoints is not supported", srv))
+			continue
+		}
+
+		u, err := url.Parse(ep)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("the endpoint %q of %s service is invalid, "+
+				"it must be a URL like https://%s.example.com", ep, srv, srv))
+			continue
+		}
+		if !strings.HasSuffix(ep, "/") {
+			c.Endpoints[srv] = ep + "/"
+		}
+	}
+	return errs
+}
+
 func GetCloudFromAuth(auth string) (string, error) {
 	var cloud string
 
@@ -0,0 +1,56 @@
+package ecs
+
+import (
+	"testing"
+)
+
+func TestServiceEndpoint(t *testing.T) {
+	c := &AccessConfig{
+		Cloud: "myhuaweicloud.com",
+		Endpoints: map[string]string{
+			"ims": "https://ims.region-1.example.com",
+			"iam": "https://iam.example.com/",
+		},
+	}
+	if errs := c.validateEndpoints(); len(errs) > 0 {
+		t.Fatalf("bad endpoints: %v", errs)
+	}
+
+	cases := map[string]string{
+		"ims": "https://ims.region-1.example.com/",
+		"ecs": "https://ecs.cn-north-4.myhuaweicloud.com/",
+		"eip": "https://vpc.cn-north-4.myhuaweicloud.com/",
+	}
+	for srv, expected := range cases {
+		if ep := c.ServiceEndpoint(srv, "cn-north-4"); ep != expected {
+			t.Errorf("expected the endpoint of %s to be %s, but got %s", srv, expected, ep)
+		}
+	}
+}
+
+func TestValidateEndpoints(t *testing.T) {
+	c := &AccessConfig{
+		Endpoints: map[string]string{
+			"ecs":     "ecs.example.com",
+			"unknown": "https://unknown.example.com",
+		},
+	}
+	if errs := c.validateEndpoints(); len(errs) != 2 {
+		t.Fatalf("expected 2 errors, but got %v", errs)
+	}
+}
+
+func TestBuildDefaultIamEndpoint(t *testing.T) {
+	cases := []struct {
+		cloud, region, expected string
+	}{
+		{"", "cn-north-4", "https://iam.cn-north-4.myhuaweicloud.com"},
+		{"", "eu-west-101", "https://iam.eu-west-101.myhuaweicloud.eu"},
+		{"example.com", "region-1", "https://iam.region-1.example.com"},
+	}
+	for _, tc := range cases {
+		if ep := buildDefaultIamEndpoint(tc.cloud, tc.region); ep != tc.expected {
+			t.Errorf("expected %s, but got %s", tc.expected, ep)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package ecs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/sdkerr"
+	ecsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2/model"
+	eipmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/eip/v2/model"
+	imsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2/model"
+	vpcmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/vpc/v2/model"
+)
+
+// The fakes embed the interfaces they implement, so calling a method that a test does not expect
+// panics with a nil pointer dereference instead of silently succeeding.
+
+// fakeCalls records the calls of a fake client.
+type fakeCalls struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (f *fakeCalls) record(format string, args ...interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeCalls) called(call string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.calls {
+		if c == call {
+			return true
+		}
+	}
+	return false
+}
+
+func notFoundError() error {
+	return &sdkerr.ServiceResponseError{StatusCode: http.StatusNotFound, ErrorCode: "NotFound"}
+}
+
+type fakeServerClient struct {
+	ServerClient
+	fakeCalls
+
+	ServerID        string
+	InterfacesErr   error
+	BlockDevices    []ecsmodel.ServerBlockDevice
+	DetachErr       error
+	deleted         bool
+	deletedVolumes  bool
+	detachedVolumes []string
+}
+
+func (f *fakeServerClient) CreatePostPaidServers(req *ecsmodel.CreatePostPaidServersRequest) (*ecsmodel.CreatePostPaidServersResponse, error) {
+	f.record("CreatePostPaidServers %s", req.Body.Server.Name)
+	jobID := "server-job"
+	return &ecsmodel.CreatePostPaidServersResponse{JobId: &jobID}, nil
+}
+
+func (f *fakeServerClient) ShowJob(req *ecsmodel.ShowJobRequest) (*ecsmodel.ShowJobResponse, error) {
+	status := ecsmodel.GetShowJobResponseStatusEnum().SUCCESS
+	subJobs := []ecsmodel.SubJob{
+		{Entities: &ecsmodel.SubJobEntities{ServerId: &f.ServerID}},
+	}
+	return &ecsmodel.ShowJobResponse{
+		JobId:    &req.JobId,
+		Status:   &status,
+		Entities: &ecsmodel.JobEntities{SubJobs: &subJobs},
+	}, nil
+}
+
+func (f *fakeServerClient) ListServerInterfaces(req *ecsmodel.ListServerInterfacesRequest) (*ecsmodel.ListServerInterfacesResponse, error) {
+	if f.InterfacesErr != nil {
+		return nil, f.InterfacesErr
+	}
+	return &ecsmodel.ListServerInterfacesResponse{}, nil
+}
+
+func (f *fakeServerClient) ListServerBlockDevices(req *ecsmodel.ListServerBlockDevicesRequest) (*ecsmodel.ListServerBlockDevicesResponse, error) {
+	return &ecsmodel.ListServerBlockDevicesResponse{VolumeAttachments: &f.BlockDevices}, nil
+}
+
+func (f *fakeServerClient) DeleteServers(req *ecsmodel.DeleteServersRequest) (*ecsmodel.DeleteServersResponse, error) {
+	for _, server := range req.Body.Servers {
+		f.record("DeleteServers %s", server.Id)
+	}
+	f.deleted = true
+	f.deletedVolumes = req.Body.DeleteVolume != nil && *req.Body.DeleteVolume
+	return &ecsmodel.DeleteServersResponse{}, nil
+}
+
+func (f *fakeServerClient) ShowServer(req *ecsmodel.ShowServerRequest) (*ecsmodel.ShowServerResponse, error) {
+	if f.deleted {
+		return nil, notFoundError()
+	}
+	return &ecsmodel.ShowServerResponse{Server: &ecsmodel.ServerDetail{Id: req.ServerId, Status: "ACTIVE"}}, nil
+}
+
+func (f *fakeServerClient) DetachServerVolume(req *ecsmodel.DetachServerVolumeRequest) (*ecsmodel.DetachServerVolumeResponse, error) {
+	f.record("DetachServerVolume %s", req.VolumeId)
+	if f.DetachErr != nil {
+		return nil, f.DetachErr
+	}
+	f.detachedVolumes = append(f.detachedVolumes, req.VolumeId)
+	jobID := "detach-job"
+	return &ecsmodel.DetachServerVolumeResponse{JobId: &jobID}, nil
+}
+
+func (f *fakeServerClient) AttachServerVolume(req *ecsmodel.AttachServerVolumeRequest) (*ecsmodel.AttachServerVolumeResponse, error) {
+	f.record("AttachServerVolume %s", req.Body.VolumeAttachment.VolumeId)
+	jobID := "attach-job"
+	return &ecsmodel.AttachServerVolumeResponse{JobId: &jobID}, nil
+}
+
+type fakeImageClient struct {
+	ImageClient
+	fakeCalls
+
+	// FailedImages lists the names of the images whose jobs fail
+	FailedImages map[string]bool
+	// Images are the existing images sorted by ID, they're listed with the marker and limit
+	Images []imsmodel.ImageInfo
+	jobs   map[string]string
+}
+
+func (f *fakeImageClient) ListImages(req *imsmodel.ListImagesRequest) (*imsmodel.ListImagesResponse, error) {
+	var marker string
+	if req.Marker != nil {
+		marker = *req.Marker
+	}
+	f.record("ListImages %s", marker)
+
+	var images []imsmodel.ImageInfo
+	for _, image := range f.Images {
+		if image.Id <= marker || (req.Name != nil && image.Name != *req.Name) {
+			continue
+		}
+		if req.Limit != nil && len(images) == int(*req.Limit) {
+			break
+		}
+		images = append(images, image)
+	}
+	return &imsmodel.ListImagesResponse{Images: &images}, nil
+}
+
+func (f *fakeImageClient) CreateImage(req *imsmodel.CreateImageRequest) (*imsmodel.CreateImageResponse, error) {
+	name := req.Body.Name
+	if req.Body.DataImages != nil {
+		name = (*req.Body.DataImages)[0].Name
+	}
+	f.record("CreateImage %s", name)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.jobs == nil {
+		f.jobs = make(map[string]string)
+	}
+	jobID := fmt.Sprintf("image-job-%d", len(f.jobs)+1)
+	f.jobs[jobID] = name
+	return &imsmodel.CreateImageResponse{JobId: &jobID}, nil
+}
+
+func (f *fakeImageClient) ShowJob(req *imsmodel.ShowJobRequest) (*imsmodel.ShowJobResponse, error) {
+	f.mu.Lock()
+	name, ok := f.jobs[req.JobId]
+	f.mu.Unlock()
+	if !ok {
+		return nil, notFoundError()
+	}
+
+	if f.FailedImages[name] {
+		status := imsmodel.GetShowJobResponseStatusEnum().FAIL
+		reason := "the image quota is insufficient"
+		return &imsmodel.ShowJobResponse{JobId: &req.JobId, Status: &status, FailReason: &reason}, nil
+	}
+
+	status := imsmodel.GetShowJobResponseStatusEnum().SUCCESS
+	imageID := "image-" + name
+	return &imsmodel.ShowJobResponse{
+		JobId:    &req.JobId,
+		Status:   &status,
+		Entities: &imsmodel.JobEntities{ImageId: &imageID},
+	}, nil
+}
+
+type fakeNetworkClient struct {
+	NetworkClient
+	fakeCalls
+
+	CreateSubnetErr error
+}
+
+func (f *fakeNetworkClient) CreateVpc(req *vpcmodel.CreateVpcRequest) (*vpcmodel.CreateVpcResponse, error) {
+	f.record("CreateVpc")
+	return &vpcmodel.CreateVpcResponse{Vpc: &vpcmodel.Vpc{Id: "vpc-1"}}, nil
+}
+
+func (f *fakeNetworkClient) ShowVpc(req *vpcmodel.ShowVpcRequest) (*vpcmodel.ShowVpcResponse, error) {
+	return &vpcmodel.ShowVpcResponse{Vpc: &vpcmodel.Vpc{Id: req.VpcId, Status: vpcmodel.GetVpcStatusEnum().OK}}, nil
+}
+
+func (f *fakeNetworkClient) DeleteVpc(req *vpcmodel.DeleteVpcRequest) (*vpcmodel.DeleteVpcResponse, error) {
+	f.record("DeleteVpc %s", req.VpcId)
+	return &vpcmodel.DeleteVpcResponse{}, nil
+}
+
+func (f *fakeNetworkClient) CreateSubnet(req *vpcmodel.CreateSubnetRequest) (*vpcmodel.CreateSubnetResponse, error) {
+	f.record("CreateSubnet")
+	if f.CreateSubnetErr != nil {
+		return nil, f.CreateSubnetErr
+	}
+	return &vpcmodel.CreateSubnetResponse{Subnet: &vpcmodel.Subnet{Id: "subnet-1"}}, nil
+}
+
+func (f *fakeNetworkClient) ShowSubnet(req *vpcmodel.ShowSubnetRequest) (*vpcmodel.ShowSubnetResponse, error) {
+	return &vpcmodel.ShowSubnetResponse{
+		Subnet: &vpcmodel.Subnet{Id: req.SubnetId, Status: vpcmodel.GetSubnetStatusEnum().ACTIVE},
+	}, nil
+}
+
+func (f *fakeNetworkClient) DeleteSubnet(req *vpcmodel.DeleteSubnetRequest) (*vpcmodel.DeleteSubnetResponse, error) {
+	f.record("DeleteSubnet %s", req.SubnetId)
+	return &vpcmodel.DeleteSubnetResponse{}, nil
+}
+
+type fakePublicIPClient struct {
+	PublicIPClient
+	fakeCalls
+
+	PublicIPs []eipmodel.PublicipShowResp
+}
+
+func (f *fakePublicIPClient) ListPublicips(req *eipmodel.ListPublicipsRequest) (*eipmodel.ListPublicipsResponse, error) {
+	f.record("ListPublicips")
+
+	// the marker is the ID of the last public IP in the previous page
+	start := 0
+	if req.Marker != nil {
+		for i, ip := range f.PublicIPs {
+			if *ip.Id == *req.Marker {
+				start = i + 1
+			}
+		}
+	}
+	end := start + int(*req.Limit)
+	if end > len(f.PublicIPs) {
+		end = len(f.PublicIPs)
+	}
+
+	page := f.PublicIPs[start:end]
+	return &eipmodel.ListPublicipsResponse{Publicips: &page}, nil
+}
+
+func (f *fakePublicIPClient) CreatePublicip(req *eipmodel.CreatePublicipRequest) (*eipmodel.CreatePublicipResponse, error) {
+	f.record("CreatePublicip")
+	return nil, fmt.Errorf("unexpected call to CreatePublicip")
+}
+
+func (f *fakePublicIPClient) DeletePublicip(req *eipmodel.DeletePublicipRequest) (*eipmodel.DeletePublicipResponse, error) {
+	f.record("DeletePublicip %s", req.PublicipId)
+	return &eipmodel.DeletePublicipResponse{}, nil
+}
+
+// testStepState returns a state bag for the steps, with a config that polls the fake clients
+// without delays. The clients of the services are registered with setClient.
+func testStepState(t *testing.T, clients map[string]interface{}) (multistep.StateBag, *Config) {
+	t.Helper()
+
+	config := &Config{waitSleep: skipSleep}
+	config.Region = "cn-north-4"
+	config.ImageName = "packer-test"
+	if errs := config.Timeouts.Prepare(); len(errs) > 0 {
+		t.Fatalf("err: %v", errs)
+	}
+	for service, client := range clients {
+		config.setClient(service, config.Region, client)
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", config)
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	})
+	return state, config
+}
+
+// cleanupResources deletes the temporary resources tracked by the steps, and returns the resources
+// that are left behind.
+func cleanupResources(state multistep.StateBag) []*temporaryResource {
+	return resourceTrackerFrom(state).Cleanup(context.Background(), state.Get("ui").(packer.Ui))
+}
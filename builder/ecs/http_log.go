@@ -7,12 +7,13 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"os"
 	"sort"
 	"strings"
 )
 
 func logRequestHandler(request http.Request) {
-	log.Printf("[DEBUG] API Request URL: %s %s", request.Method, request.URL)
+	log.Printf("[DEBUG] API Request URL: %s %s", request.Method, defaultRedactor().RedactQuery(request.URL))
 	log.Printf("[DEBUG] API Request Headers:\n%s", FormatHeaders(request.Header, "\n"))
 	if request.Body != nil {
 		if err := logRequest(request.Body, request.Header.Get("Content-Type")); err != nil {
@@ -95,12 +96,11 @@ func FormatHeaders(headers http.Header, seperator string) string {
 
 // redactHeaders processes a headers object, returning a redacted list.
 func redactHeaders(headers http.Header) (processedHeaders []string) {
-	// sensitiveWords is a list of headers that need to be redacted.
-	var sensitiveWords = []string{"token", "authorization"}
+	redactor := defaultRedactor()
 
 	for name, header := range headers {
 		for _, v := range header {
-			if isSliceContainsStr(sensitiveWords, name) {
+			if isSensitiveHeader(redactor, name) {
 				processedHeaders = append(processedHeaders, fmt.Sprintf("%v: %v", name, "***"))
 			} else {
 				processedHeaders = append(processedHeaders, fmt.Sprintf("%v: %v", name, v))
@@ -110,10 +110,32 @@ func redactHeaders(headers http.Header) (processedHeaders []string) {
 	return
 }
 
+// redactHeaderMap processes a headers object, returning a redacted map,
+// the multiple values of a header are joined with commas.
+func redactHeaderMap(headers http.Header) map[string]string {
+	redactor := defaultRedactor()
+
+	processedHeaders := make(map[string]string, len(headers))
+	for name, header := range headers {
+		if isSensitiveHeader(redactor, name) {
+			processedHeaders[name] = redactedValue
+		} else {
+			processedHeaders[name] = strings.Join(header, ", ")
+		}
+	}
+	return processedHeaders
+}
+
+func isSensitiveHeader(redactor *Redactor, name string) bool {
+	// sensitiveWords is a list of headers that need to be redacted.
+	var sensitiveWords = []string{"token", "authorization"}
+	return isSliceContainsStr(sensitiveWords, name) || redactor.isSensitive([]string{name})
+}
+
 // formatJSON will try to pretty-format a JSON body.
-// It will also mask known fields which contain sensitive information.
+// It will also mask the fields which contain sensitive information, see Redactor.
 func formatJSON(raw []byte) string {
-	var data map[string]interface{}
+	var data interface{}
 
 	if len(raw) == 0 {
 		return ""
@@ -121,35 +143,27 @@ func formatJSON(raw []byte) string {
 
 	err := json.Unmarshal(raw, &data)
 	if err != nil {
+		// the body may contain sensitive information, so don't log it
 		log.Printf("[DEBUG] Unable to parse JSON: %s", err)
-		return string(raw)
-	}
-
-	// Mask known password fields
-	if v, ok := data["auth"].(map[string]interface{}); ok {
-		if v, ok := v["identity"].(map[string]interface{}); ok {
-			if v, ok := v["password"].(map[string]interface{}); ok {
-				if v, ok := v["user"].(map[string]interface{}); ok {
-					v["password"] = "***"
-				}
-			}
-		}
+		return "{ **unparsable** }"
 	}
 
 	// Ignore the catalog
-	if _, ok := data["catalog"].([]interface{}); ok {
-		return "{ **skipped** }"
-	}
-	if v, ok := data["token"].(map[string]interface{}); ok {
-		if _, ok := v["catalog"]; ok {
+	if v, ok := data.(map[string]interface{}); ok {
+		if _, ok := v["catalog"].([]interface{}); ok {
 			return "{ **skipped** }"
 		}
+		if v, ok := v["token"].(map[string]interface{}); ok {
+			if _, ok := v["catalog"]; ok {
+				return "{ **skipped** }"
+			}
+		}
 	}
 
-	pretty, err := json.MarshalIndent(data, "", "  ")
+	pretty, err := json.MarshalIndent(defaultRedactor().RedactJSON(data), "", "  ")
 	if err != nil {
 		log.Printf("[DEBUG] Unable to re-marshal JSON: %s", err)
-		return string(raw)
+		return "{ **unparsable** }"
 	}
 
 	return string(pretty)
@@ -177,3 +191,62 @@ func isSliceContainsStr(array []string, str string) bool {
 	}
 	return false
 }
+
+// LogRoundTripper is an http.RoundTripper which logs the requests and responses in the
+// same way as the HuaweiCloud SDK clients, it's used by the clients which are not built by
+// NewHcClient, e.g. the OBS client.
+type LogRoundTripper struct {
+	Rt http.RoundTripper
+}
+
+// RoundTrip performs a round-trip HTTP request and logs the request and response.
+// Only the JSON bodies are read and logged, so the uploaded files will not be buffered.
+func (lrt *LogRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	log.Printf("[DEBUG] API Request URL: %s %s", request.Method, defaultRedactor().RedactQuery(request.URL))
+	log.Printf("[DEBUG] API Request Headers:\n%s", FormatHeaders(request.Header, "\n"))
+
+	contentType := request.Header.Get("Content-Type")
+	if request.Body != nil && strings.HasPrefix(contentType, "application/json") {
+		body, err := io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		request.Body = io.NopCloser(bytes.NewReader(body))
+		if err := logRequest(io.NopCloser(bytes.NewReader(body)), contentType); err != nil {
+			log.Printf("[WARN] failed to get request body: %s", err)
+		}
+	}
+
+	response, err := lrt.Rt.RoundTrip(request)
+	if err != nil {
+		return response, err
+	}
+
+	log.Printf("[DEBUG] API Response Code: %d", response.StatusCode)
+	log.Printf("[DEBUG] API Response Headers:\n%s", FormatHeaders(response.Header, "\n"))
+
+	contentType = response.Header.Get("Content-Type")
+	if response.Body != nil && strings.HasPrefix(contentType, "application/json") {
+		body, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		response.Body = io.NopCloser(bytes.NewReader(body))
+		if err := logResponse(io.NopCloser(bytes.NewReader(body)), contentType); err != nil {
+			log.Printf("[WARN] failed to get response body: %s", err)
+		}
+	}
+
+	return response, nil
+}
+
+// LogEnabled returns whether the HTTP requests and responses should be logged,
+// it's controlled by the HW_DEBUG environment variable.
+func LogEnabled() bool {
+	debugEnv := os.Getenv("HW_DEBUG")
+	return debugEnv != "" && debugEnv != "0"
+}
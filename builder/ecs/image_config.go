@@ -31,14 +31,37 @@ type ImageConfig struct {
 	// decimal numbers, each with optional fraction and a unit suffix, such as "40m", "1.5h" or "2h30m".
 	// The default timeout is "30m" which means 30 minutes.
 	WaitImageReadyTimeout string `mapstructure:"wait_image_ready_timeout" required:"false"`
+	// What to do when a private image with the same name already exists, IMS allows duplicate
+	// names. Available values include:
+	//   -  `allow` - Create another image with the same name. This is the default.
+	//   -  `fail` - Fail the build in the preflight checks.
+	//   -  `replace` - Delete the existing images after the new image is active. The data disk images
+	//      named `<image_name>-<device>`, such as `my-image-vdb`, are deleted as well, and the members
+	//      of the existing images are removed before they are deleted.
+	ImageNameConflict string `mapstructure:"image_name_conflict" required:"false"`
 }
 
+const (
+	ImageNameConflictAllow   = "allow"
+	ImageNameConflictFail    = "fail"
+	ImageNameConflictReplace = "replace"
+)
+
 func (c *ImageConfig) Prepare(ctx *interpolate.Context) []error {
 	errs := make([]error, 0)
 	if c.ImageName == "" {
 		errs = append(errs, fmt.Errorf("image_name must be specified"))
 	}
 
+	switch c.ImageNameConflict {
+	case "":
+		c.ImageNameConflict = ImageNameConflictAllow
+	case ImageNameConflictAllow, ImageNameConflictFail, ImageNameConflictReplace:
+	default:
+		errs = append(errs, fmt.Errorf("image_name_conflict must be one of %s, %s and %s, got %q",
+			ImageNameConflictAllow, ImageNameConflictFail, ImageNameConflictReplace, c.ImageNameConflict))
+	}
+
 	if len(errs) > 0 {
 		return errs
 	}
@@ -21,3 +21,23 @@ func TestImageConfigPrepare_Region(t *testing.T) {
 		t.Fatal("should have error")
 	}
 }
+
+func TestImageConfigPrepare_ImageNameConflict(t *testing.T) {
+	c := testImageConfig()
+	if err := c.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+	if c.ImageNameConflict != ImageNameConflictAllow {
+		t.Fatalf("bad: %s", c.ImageNameConflict)
+	}
+
+	c.ImageNameConflict = ImageNameConflictReplace
+	if err := c.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %s", err)
+	}
+
+	c.ImageNameConflict = "overwrite"
+	if err := c.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+}
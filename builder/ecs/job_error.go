@@ -0,0 +1,267 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	ecsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2/model"
+	evsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/evs/v2/model"
+	imsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2/model"
+)
+
+// jobFailureHint is an actionable message for the job failures which match any of the keywords.
+type jobFailureHint struct {
+	keywords []string
+	message  string
+}
+
+// jobFailureHints maps the common error codes and failure reasons to actionable messages,
+// the keywords are matched against the lowercase error code and failure reason.
+var jobFailureHints = []jobFailureHint{
+	{
+		keywords: []string{"quota", "exceeded the limit", "ecs.0013", "ecs.0605", "ecs.0610", "evs.2024"},
+		message: "the quota is insufficient, please release the unused resources or " +
+			"apply for a higher quota in the console",
+	},
+	{
+		keywords: []string{"sold out", "soldout", "insufficient resource", "resources are insufficient",
+			"no valid host", "ecs.0204", "ecs.0302"},
+		message: "the flavor or volume type is sold out in the availability zone, please try another " +
+			"flavor or specify another availability_zone",
+	},
+	{
+		keywords: []string{"balance", "arrear", "frozen", "cbc."},
+		message:  "the account balance is insufficient or the account is frozen, please top up the account",
+	},
+	{
+		keywords: []string{"does not match the image", "does not match the flavor", "not support the flavor",
+			"not supported by the flavor", "flavor does not support", "ecs.0114", "ecs.0225", "ims.0143"},
+		message: "the image does not match the flavor, please check the architecture, boot mode and " +
+			"minimum disk size of the image against the flavor",
+	},
+}
+
+// JobError is the failure of an asynchronous ECS, EVS or IMS job and its sub-jobs.
+type JobError struct {
+	Service    string
+	JobID      string
+	JobType    string
+	ErrorCode  string
+	FailReason string
+	// RequestID is the ID of the request which submitted the job, it's empty if not recorded
+	RequestID string
+	SubJobs   []JobError
+}
+
+func (e *JobError) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "the %s job %s", e.Service, e.JobID)
+	if e.JobType != "" {
+		fmt.Fprintf(&b, " (%s)", e.JobType)
+	}
+	b.WriteString(" failed")
+	e.writeReason(&b)
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, ", request ID: %s", e.RequestID)
+	}
+
+	for _, sub := range e.SubJobs {
+		fmt.Fprintf(&b, "\n  sub-job %s", sub.JobID)
+		if sub.JobType != "" {
+			fmt.Fprintf(&b, " (%s)", sub.JobType)
+		}
+		b.WriteString(" failed")
+		sub.writeReason(&b)
+	}
+
+	if hint := e.Hint(); hint != "" {
+		fmt.Fprintf(&b, "\nhint: %s", hint)
+	}
+	return b.String()
+}
+
+func (e *JobError) writeReason(b *strings.Builder) {
+	if e.ErrorCode != "" {
+		fmt.Fprintf(b, ": [%s]", e.ErrorCode)
+	}
+	if e.FailReason != "" {
+		if e.ErrorCode == "" {
+			b.WriteString(":")
+		}
+		fmt.Fprintf(b, " %s", e.FailReason)
+	}
+}
+
+// Hint returns the actionable message of the job or its sub-jobs, or an empty string
+// if the failure is not recognized.
+func (e *JobError) Hint() string {
+	if hint := matchJobFailureHint(e.ErrorCode, e.FailReason); hint != "" {
+		return hint
+	}
+	for _, sub := range e.SubJobs {
+		if hint := sub.Hint(); hint != "" {
+			return hint
+		}
+	}
+	return ""
+}
+
+func matchJobFailureHint(code, reason string) string {
+	text := strings.ToLower(code + " " + reason)
+	for _, hint := range jobFailureHints {
+		for _, keyword := range hint.keywords {
+			if strings.Contains(text, keyword) {
+				return hint.message
+			}
+		}
+	}
+	return ""
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func newECSJobError(job *ecsmodel.ShowJobResponse) *JobError {
+	jobErr := JobError{
+		Service:    "ECS",
+		JobID:      derefString(job.JobId),
+		JobType:    derefString(job.JobType),
+		ErrorCode:  derefString(job.ErrorCode),
+		FailReason: derefString(job.FailReason),
+	}
+	jobErr.RequestID = JobRequestID(jobErr.JobID)
+
+	if job.Entities != nil && job.Entities.SubJobs != nil {
+		for _, sub := range *job.Entities.SubJobs {
+			if sub.Status == nil || sub.Status.Value() != "FAIL" {
+				continue
+			}
+
+			subErr := JobError{
+				JobID:      derefString(sub.JobId),
+				JobType:    derefString(sub.JobType),
+				ErrorCode:  derefString(sub.ErrorCode),
+				FailReason: derefString(sub.FailReason),
+			}
+			if sub.Entities != nil && subErr.FailReason == "" {
+				subErr.FailReason = derefString(sub.Entities.ErrorcodeMessage)
+			}
+			jobErr.SubJobs = append(jobErr.SubJobs, subErr)
+		}
+	}
+	return &jobErr
+}
+
+func newEVSJobError(job *evsmodel.ShowJobResponse) *JobError {
+	jobErr := JobError{
+		Service:    "EVS",
+		JobID:      derefString(job.JobId),
+		JobType:    derefString(job.JobType),
+		ErrorCode:  derefString(job.ErrorCode),
+		FailReason: derefString(job.FailReason),
+	}
+	jobErr.RequestID = JobRequestID(jobErr.JobID)
+
+	if job.Entities != nil && job.Entities.SubJobs != nil {
+		for _, sub := range *job.Entities.SubJobs {
+			if sub.Status.Value() != "FAIL" {
+				continue
+			}
+
+			jobErr.SubJobs = append(jobErr.SubJobs, JobError{
+				JobID:      sub.JobId,
+				JobType:    sub.JobType,
+				ErrorCode:  sub.ErrorCode,
+				FailReason: sub.FailReason,
+			})
+		}
+	}
+	return &jobErr
+}
+
+// NewIMSJobError returns the failure of an IMS job, including the failed sub-jobs.
+func NewIMSJobError(job *imsmodel.ShowJobResponse) *JobError {
+	jobErr := JobError{
+		Service:    "IMS",
+		JobID:      derefString(job.JobId),
+		JobType:    derefString(job.JobType),
+		ErrorCode:  derefString(job.ErrorCode),
+		FailReason: derefString(job.FailReason),
+	}
+	jobErr.RequestID = JobRequestID(jobErr.JobID)
+
+	if job.Entities != nil && job.Entities.SubJobsResult != nil {
+		for _, sub := range *job.Entities.SubJobsResult {
+			if sub.Status == nil || sub.Status.Value() != "FAIL" {
+				continue
+			}
+
+			jobErr.SubJobs = append(jobErr.SubJobs, JobError{
+				JobID:      derefString(sub.JobId),
+				JobType:    derefString(sub.JobType),
+				ErrorCode:  derefString(sub.ErrorCode),
+				FailReason: derefString(sub.FailReason),
+			})
+		}
+	}
+	return &jobErr
+}
+
+// jobRequestIDs records the request IDs of the requests which submitted the asynchronous jobs,
+// the SDK does not expose the response headers of the successful requests.
+var jobRequestIDs sync.Map
+
+// JobRequestID returns the ID of the request which submitted the job.
+func JobRequestID(jobID string) string {
+	if value, ok := jobRequestIDs.Load(jobID); ok {
+		return value.(string)
+	}
+	return ""
+}
+
+// requestIDRoundTripper records the request ID of the responses which contain a job ID.
+type requestIDRoundTripper struct {
+	rt http.RoundTripper
+}
+
+func (r *requestIDRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.rt.RoundTrip(req)
+	if err != nil || req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return resp, err
+	}
+	if resp.StatusCode >= 300 || !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		return resp, err
+	}
+
+	requestID := resp.Header.Get("X-Request-Id")
+	if requestID == "" {
+		return resp, err
+	}
+
+	body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	if readErr != nil {
+		return resp, err
+	}
+
+	var job struct {
+		JobID string `json:"job_id"`
+	}
+	if json.Unmarshal(body, &job) == nil && job.JobID != "" {
+		jobRequestIDs.Store(job.JobID, requestID)
+	}
+	return resp, err
+}
@@ -0,0 +1,116 @@
+package ecs
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ecsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2/model"
+	imsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2/model"
+)
+
+func TestJobError(t *testing.T) {
+	cases := []struct {
+		err      JobError
+		expected []string
+	}{
+		{
+			JobError{Service: "ECS", JobID: "job-1", JobType: "createServer",
+				ErrorCode: "Ecs.0013", FailReason: "insufficient EIP quota", RequestID: "request-1"},
+			[]string{"the ECS job job-1 (createServer) failed: [Ecs.0013] insufficient EIP quota",
+				"request ID: request-1", "hint: the quota is insufficient"},
+		},
+		{
+			JobError{Service: "ECS", JobID: "job-2", SubJobs: []JobError{
+				{JobID: "sub-1", JobType: "createSingleServer", FailReason: "the flavor is sold out"},
+			}},
+			[]string{"the ECS job job-2 failed", "sub-job sub-1 (createSingleServer) failed: the flavor is sold out",
+				"hint: the flavor or volume type is sold out"},
+		},
+		{
+			JobError{Service: "IMS", JobID: "job-3", FailReason: "the account is in arrears"},
+			[]string{"the IMS job job-3 failed: the account is in arrears", "hint: the account balance is insufficient"},
+		},
+		{
+			JobError{Service: "EVS", JobID: "job-4", ErrorCode: "EVS.9999"},
+			[]string{"the EVS job job-4 failed: [EVS.9999]"},
+		},
+	}
+
+	for _, tc := range cases {
+		message := tc.err.Error()
+		for _, expected := range tc.expected {
+			if !strings.Contains(message, expected) {
+				t.Errorf("expected the error to contain %q, but got %q", expected, message)
+			}
+		}
+	}
+
+	unknown := JobError{Service: "EVS", JobID: "job-5", FailReason: "internal error"}
+	if strings.Contains(unknown.Error(), "hint") {
+		t.Errorf("expected no hint for an unknown failure, but got %q", unknown.Error())
+	}
+}
+
+func TestNewJobError(t *testing.T) {
+	jobID := "job-1"
+	failed := ecsmodel.GetSubJobStatusEnum().FAIL
+	succeeded := ecsmodel.GetSubJobStatusEnum().SUCCESS
+	reason := "the image does not match the flavor"
+	subJobs := []ecsmodel.SubJob{
+		{JobId: &jobID, Status: &succeeded},
+		{JobId: &jobID, Status: &failed, Entities: &ecsmodel.SubJobEntities{ErrorcodeMessage: &reason}},
+	}
+	ecsErr := newECSJobError(&ecsmodel.ShowJobResponse{
+		JobId:    &jobID,
+		Entities: &ecsmodel.JobEntities{SubJobs: &subJobs},
+	})
+	if len(ecsErr.SubJobs) != 1 || ecsErr.SubJobs[0].FailReason != reason {
+		t.Fatalf("expected one failed sub-job, but got %+v", ecsErr.SubJobs)
+	}
+	if !strings.Contains(ecsErr.Hint(), "does not match the flavor") {
+		t.Errorf("expected the image and flavor mismatch hint, but got %q", ecsErr.Hint())
+	}
+
+	// no panic if the fail reason is absent
+	imsErr := NewIMSJobError(&imsmodel.ShowJobResponse{JobId: &jobID})
+	if imsErr.Error() != "the IMS job job-1 failed" {
+		t.Errorf("unexpected error: %s", imsErr.Error())
+	}
+}
+
+func TestRequestIDRoundTripper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "request-"+r.Method)
+		fmt.Fprint(w, `{"job_id": "job-`+r.Method+`"}`)
+	}))
+	defer server.Close()
+
+	c := &AccessConfig{}
+	client := http.Client{Transport: c.NewTransport()}
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		req, _ := http.NewRequest(method, server.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		// the body is still readable by the SDK
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(body), "job-"+method) {
+			t.Errorf("unexpected body: %s", body)
+		}
+	}
+
+	if id := JobRequestID("job-POST"); id != "request-POST" {
+		t.Errorf("expected request-POST, but got %q", id)
+	}
+	if id := JobRequestID("job-GET"); id != "" {
+		t.Errorf("expected no request ID of the GET request, but got %q", id)
+	}
+}
@@ -0,0 +1,111 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// metadataEndpoint is the OpenStack metadata service of ECS instances.
+var metadataEndpoint = "http://169.254.169.254/openstack/latest"
+
+// metadataTimeout is short enough to not delay the builds running outside HuaweiCloud.
+const metadataTimeout = 3 * time.Second
+
+// InstanceMetadata is the region and project of the ECS instance in meta_data.json.
+type InstanceMetadata struct {
+	RegionID         string `json:"region_id"`
+	ProjectID        string `json:"project_id"`
+	AvailabilityZone string `json:"availability_zone"`
+}
+
+type metadataSecurityKey struct {
+	Credential *struct {
+		ExpiresAt     string `json:"expires_at"`
+		Access        string `json:"access"`
+		Secret        string `json:"secret"`
+		Securitytoken string `json:"securitytoken"`
+	} `json:"credential"`
+}
+
+// metadataProvider retrieves the temporary credentials of the agency attached to the ECS instance.
+type metadataProvider struct{}
+
+func (p *metadataProvider) Retrieve() (*Credential, error) {
+	var securityKey metadataSecurityKey
+	if err := getMetadata("securitykey", &securityKey); err != nil {
+		return nil, fmt.Errorf("failed to get the temporary credential from the ECS metadata: %s", err)
+	}
+	if securityKey.Credential == nil {
+		return nil, fmt.Errorf("failed to get the temporary credential from the ECS metadata: the credential is empty")
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, securityKey.Credential.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the expiration time of the temporary credential: %s", err)
+	}
+
+	credential := Credential{
+		AccessKey:     securityKey.Credential.Access,
+		SecretKey:     securityKey.Credential.Secret,
+		SecurityToken: securityKey.Credential.Securitytoken,
+		ExpiresAt:     expiresAt,
+	}
+	return &credential, nil
+}
+
+// loadMetadata uses the temporary credentials of the ECS instance to sign the requests,
+// and fills the region and project ID if they are not specified.
+func (c *AccessConfig) loadMetadata() error {
+	provider := newCachedCredentialProvider(&metadataProvider{})
+	if _, err := provider.Retrieve(); err != nil {
+		return err
+	}
+
+	var metadata InstanceMetadata
+	if err := getMetadata("meta_data.json", &metadata); err != nil {
+		return fmt.Errorf("failed to get the ECS metadata: %s", err)
+	}
+
+	log.Printf("[DEBUG] using the temporary credentials of the ECS instance in region %s", metadata.RegionID)
+	c.credentialProvider = provider
+	if c.Region == "" {
+		c.Region = metadata.RegionID
+	}
+	if c.ProjectID == "" && metadata.RegionID == c.Region {
+		c.ProjectID = metadata.ProjectID
+	}
+
+	return nil
+}
+
+func getMetadata(path string, result interface{}) error {
+	// the metadata service must be accessed directly rather than through a proxy
+	var transport http.RoundTripper = &http.Transport{}
+	if LogEnabled() {
+		transport = &LogRoundTripper{Rt: transport}
+	}
+	client := http.Client{
+		Transport: transport,
+		Timeout:   metadataTimeout,
+	}
+
+	resp, err := client.Get(fmt.Sprintf("%s/%s", metadataEndpoint, path))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, body)
+	}
+
+	return json.Unmarshal(body, result)
+}
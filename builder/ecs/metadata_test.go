@@ -0,0 +1,58 @@
+package ecs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoadMetadata(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/securitykey", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"credential": {"access": "temp-ak", "secret": "temp-sk", "securitytoken": "temp-token", "expires_at": %q}}`,
+			expiresAt)
+	})
+	mux.HandleFunc("/meta_data.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"region_id": "cn-north-4", "project_id": "my-project", "availability_zone": "cn-north-4a"}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	defer func(endpoint string) { metadataEndpoint = endpoint }(metadataEndpoint)
+	metadataEndpoint = server.URL
+
+	c := &AccessConfig{}
+	if err := c.loadMetadata(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.Region != "cn-north-4" || c.ProjectID != "my-project" {
+		t.Fatalf("bad region or project: %s, %s", c.Region, c.ProjectID)
+	}
+
+	credential, err := c.GetCredential()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if credential.AccessKey != "temp-ak" || credential.SecretKey != "temp-sk" || credential.SecurityToken != "temp-token" {
+		t.Fatalf("bad credential: %#v", credential)
+	}
+}
+
+func TestLoadMetadata_Unavailable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	defer func(endpoint string) { metadataEndpoint = endpoint }(metadataEndpoint)
+	metadataEndpoint = server.URL
+
+	c := &AccessConfig{}
+	if err := c.loadMetadata(); err == nil {
+		t.Fatalf("loading the unavailable metadata should fail")
+	}
+	if c.credentialProvider != nil {
+		t.Fatalf("the credential provider should not be set")
+	}
+}
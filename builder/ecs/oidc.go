@@ -0,0 +1,84 @@
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type OIDCConfig
+
+package ecs
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/pathing"
+)
+
+// OIDCConfig is the configuration to exchange an OpenID Connect ID token, such as the token issued
+// to the GitHub Actions or GitLab CI jobs, for temporary credentials through an IAM identity
+// provider. The access key and secret key are not required in this mode, and the temporary
+// credentials are refreshed automatically if the build outlives them.
+type OIDCConfig struct {
+	// The ID of the IAM identity provider which trusts the OpenID Connect issuer.
+	IdpID string `mapstructure:"idp_id" required:"true"`
+	// The path to the file which contains the ID token, such as the token file projected by Kubernetes.
+	// The file is read again every time the temporary credentials are refreshed.
+	TokenFile string `mapstructure:"token_file" required:"false"`
+	// The name of the environment variable which contains the ID token.
+	// Either `token_file` or `token_env` must be specified.
+	TokenEnv string `mapstructure:"token_env" required:"false"`
+	// The validity period of the temporary credentials, such as `1h` or `90m`.
+	// The value ranges from `15m` to `24h`, defaults to `15m`.
+	Duration string `mapstructure:"duration" required:"false"`
+
+	duration time.Duration
+}
+
+func (c *OIDCConfig) Prepare() []error {
+	if c.IdpID == "" {
+		if c.TokenFile != "" || c.TokenEnv != "" || c.Duration != "" {
+			return []error{fmt.Errorf("oidc.idp_id must be specified")}
+		}
+		return nil
+	}
+
+	var errs []error
+	if c.TokenFile == "" && c.TokenEnv == "" {
+		errs = append(errs, fmt.Errorf("either oidc.token_file or oidc.token_env must be specified"))
+	}
+	if c.TokenFile != "" && c.TokenEnv != "" {
+		errs = append(errs, fmt.Errorf("only one of oidc.token_file and oidc.token_env can be specified"))
+	}
+
+	duration, err := parseCredentialDuration("oidc.duration", c.Duration)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	c.duration = duration
+
+	return errs
+}
+
+// readToken reads the ID token from the file or the environment variable.
+func (c *OIDCConfig) readToken() (string, error) {
+	if c.TokenEnv != "" {
+		token := strings.TrimSpace(os.Getenv(c.TokenEnv))
+		if token == "" {
+			return "", fmt.Errorf("the environment variable %s of the ID token is empty", c.TokenEnv)
+		}
+		return token, nil
+	}
+
+	filePath, err := pathing.ExpandUser(c.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to expand the path of oidc.token_file %s: %s", c.TokenFile, err)
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the ID token file %s: %s", filePath, err)
+	}
+
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", fmt.Errorf("the ID token file %s is empty", filePath)
+	}
+	return token, nil
+}
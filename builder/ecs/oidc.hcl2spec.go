@@ -0,0 +1,37 @@
+// Code generated by "packer-sdc mapstructure-to-hcl2"; DO NOT EDIT.
+
+package ecs
+
+import (
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FlatOIDCConfig is an auto-generated flat version of OIDCConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatOIDCConfig struct {
+	IdpID     *string `mapstructure:"idp_id" required:"true" cty:"idp_id" hcl:"idp_id"`
+	TokenFile *string `mapstructure:"token_file" required:"false" cty:"token_file" hcl:"token_file"`
+	TokenEnv  *string `mapstructure:"token_env" required:"false" cty:"token_env" hcl:"token_env"`
+	Duration  *string `mapstructure:"duration" required:"false" cty:"duration" hcl:"duration"`
+}
+
+// FlatMapstructure returns a new FlatOIDCConfig.
+// FlatOIDCConfig is an auto-generated flat version of OIDCConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*OIDCConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatOIDCConfig)
+}
+
+// HCL2Spec returns the hcl spec of a OIDCConfig.
+// This spec is used by HCL to read the fields of OIDCConfig.
+// The decoded values from this spec will then be applied to a FlatOIDCConfig.
+func (*FlatOIDCConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"idp_id":     &hcldec.AttrSpec{Name: "idp_id", Type: cty.String, Required: false},
+		"token_file": &hcldec.AttrSpec{Name: "token_file", Type: cty.String, Required: false},
+		"token_env":  &hcldec.AttrSpec{Name: "token_env", Type: cty.String, Required: false},
+		"duration":   &hcldec.AttrSpec{Name: "duration", Type: cty.String, Required: false},
+	}
+	return s
+}
@@ -0,0 +1,132 @@
+package ecs
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/auth"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/impl"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/request"
+	iam "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/iam/v3"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/iam/v3/model"
+)
+
+// tokenCredentialsType is the type name of tokenCredentials which is checked by HcHttpClientBuilder.
+const tokenCredentialsType = "ecs.tokenCredentials"
+
+// oidcProvider exchanges the ID token for an IAM token, and then the IAM token for
+// the temporary credentials.
+type oidcProvider struct {
+	config      *OIDCConfig
+	accessCfg   *AccessConfig
+	projectName string
+}
+
+func newOIDCProvider(c *AccessConfig) *oidcProvider {
+	return &oidcProvider{
+		config:      &c.OIDC,
+		accessCfg:   c,
+		projectName: c.ProjectName,
+	}
+}
+
+func (p *oidcProvider) Retrieve() (*Credential, error) {
+	idToken, err := p.config.readToken()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[DEBUG] exchanging the ID token through the identity provider %s", p.config.IdpID)
+	tokenRequest := model.CreateTokenWithIdTokenRequest{
+		XIdpId: p.config.IdpID,
+		Body: &model.GetIdTokenRequestBody{
+			Auth: &model.GetIdTokenAuthParams{
+				IdToken: &model.GetIdTokenIdTokenBody{
+					Id: idToken,
+				},
+				Scope: &model.GetIdTokenIdScopeBody{
+					Project: &model.GetIdTokenScopeDomainOrProjectBody{
+						Name: &p.projectName,
+					},
+				},
+			},
+		},
+	}
+	tokenResponse, err := p.newIamClient("").CreateTokenWithIdToken(&tokenRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the token with the ID token: %s", err)
+	}
+	if tokenResponse.XSubjectToken == nil || *tokenResponse.XSubjectToken == "" {
+		return nil, fmt.Errorf("failed to get the token with the ID token: the token is empty")
+	}
+
+	identityToken := model.IdentityToken{}
+	if p.config.duration > 0 {
+		seconds := int32(p.config.duration.Seconds())
+		identityToken.DurationSeconds = &seconds
+	}
+	credentialRequest := model.CreateTemporaryAccessKeyByTokenRequest{
+		Body: &model.CreateTemporaryAccessKeyByTokenRequestBody{
+			Auth: &model.TokenAuth{
+				Identity: &model.TokenAuthIdentity{
+					Methods: []model.TokenAuthIdentityMethods{
+						model.GetTokenAuthIdentityMethodsEnum().TOKEN,
+					},
+					Token: &identityToken,
+				},
+			},
+		},
+	}
+	credentialResponse, err := p.newIamClient(*tokenResponse.XSubjectToken).CreateTemporaryAccessKeyByToken(&credentialRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the temporary credential with the token: %s", err)
+	}
+	if credentialResponse.Credential == nil {
+		return nil, fmt.Errorf("failed to get the temporary credential with the token: the credential is empty")
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, credentialResponse.Credential.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the expiration time of the temporary credential: %s", err)
+	}
+
+	credential := Credential{
+		AccessKey:     credentialResponse.Credential.Access,
+		SecretKey:     credentialResponse.Credential.Secret,
+		SecurityToken: credentialResponse.Credential.Securitytoken,
+		ExpiresAt:     expiresAt,
+	}
+	return &credential, nil
+}
+
+// newIamClient returns an IAM client which authenticates the requests with the token,
+// or sends the requests without authentication if the token is empty.
+func (p *oidcProvider) newIamClient(token string) *iam.IamClient {
+	builder := core.NewHcHttpClientBuilder().WithEndpoint(p.accessCfg.IdentityEndpoint).
+		WithHttpConfig(buildHTTPConfig(p.accessCfg, "iam"))
+	builder.WithCredentialsType(tokenCredentialsType).WithCredential(&tokenCredentials{token: token})
+
+	headers := map[string]string{
+		"User-Agent": UserAgent,
+	}
+	return iam.NewIamClient(builder.Build().PreInvoke(headers))
+}
+
+// tokenCredentials implements auth.ICredential, it authenticates the requests with an IAM token.
+type tokenCredentials struct {
+	token string
+}
+
+func (t *tokenCredentials) ProcessAuthParams(_ *impl.DefaultHttpClient, _ string) auth.ICredential {
+	return t
+}
+
+func (t *tokenCredentials) ProcessAuthRequest(_ *impl.DefaultHttpClient,
+	req *request.DefaultHttpRequest) (*request.DefaultHttpRequest, error) {
+	if t.token != "" {
+		req.AddHeaderParam("X-Auth-Token", t.token)
+	}
+	return req, nil
+}
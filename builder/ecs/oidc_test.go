@@ -0,0 +1,80 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOIDCConfigPrepare(t *testing.T) {
+	cases := []struct {
+		config OIDCConfig
+		valid  bool
+	}{
+		{OIDCConfig{}, true},
+		{OIDCConfig{IdpID: "github", TokenEnv: "OIDC_TOKEN", Duration: "1h"}, true},
+		{OIDCConfig{IdpID: "github", TokenFile: "/var/run/token"}, true},
+		{OIDCConfig{TokenEnv: "OIDC_TOKEN"}, false},
+		{OIDCConfig{IdpID: "github"}, false},
+		{OIDCConfig{IdpID: "github", TokenFile: "/var/run/token", TokenEnv: "OIDC_TOKEN"}, false},
+		{OIDCConfig{IdpID: "github", TokenEnv: "OIDC_TOKEN", Duration: "5m"}, false},
+	}
+
+	for _, tc := range cases {
+		errs := tc.config.Prepare()
+		if tc.valid && len(errs) > 0 {
+			t.Errorf("%#v should be valid: %v", tc.config, errs)
+		}
+		if !tc.valid && len(errs) == 0 {
+			t.Errorf("%#v should be invalid", tc.config)
+		}
+	}
+}
+
+func TestOIDCProvider(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v3.0/OS-AUTH/id-token/tokens", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]map[string]map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode the request: %s", err)
+		}
+		if r.Header.Get("X-Idp-Id") != "github" || body["auth"]["id_token"]["id"] != "my-id-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-Subject-Token", "my-iam-token")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"token": {}}`)
+	})
+	mux.HandleFunc("/v3.0/OS-CREDENTIAL/securitytokens", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Auth-Token") != "my-iam-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"credential": {"access": "temp-ak", "secret": "temp-sk", "securitytoken": "temp-token", "expires_at": %q}}`,
+			expiresAt)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := &AccessConfig{
+		ProjectName:      "cn-north-4",
+		IdentityEndpoint: server.URL,
+		OIDC: OIDCConfig{
+			IdpID:     "github",
+			TokenFile: writeTestFile(t, "token", "my-id-token\n"),
+		},
+	}
+	credential, err := newOIDCProvider(c).Retrieve()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if credential.AccessKey != "temp-ak" || credential.SecretKey != "temp-sk" || credential.SecurityToken != "temp-token" {
+		t.Fatalf("bad credential: %#v", credential)
+	}
+}
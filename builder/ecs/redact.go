@@ -0,0 +1,171 @@
+package ecs
+
+import (
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+const redactedValue = "***"
+
+// defaultSensitiveFields is a list of field names which contain sensitive information in
+// the request and response bodies or the query strings, e.g. the password of IAM users,
+// the private key of key pairs, the adminPass and user_data of servers, the temporary
+// security credentials and the encrypted password of Windows servers.
+// A field is redacted if its normalized name ends with any of them.
+var defaultSensitiveFields = []string{
+	"password",
+	"adminpass",
+	"privatekey",
+	"userdata",
+	"secret",
+	"secretkey",
+	"securitytoken",
+	"idtoken",
+	"signature",
+	"authorization",
+}
+
+// defaultSensitivePaths is a list of full paths whose field names are too common to be
+// redacted everywhere, e.g. the ID token and the IAM token in the IAM requests.
+var defaultSensitivePaths = []string{
+	"auth.id_token.id",
+	"auth.identity.token.id",
+}
+
+// Redactor masks the sensitive fields in the HTTP request and response.
+type Redactor struct {
+	// fields is a list of the normalized field names
+	fields []string
+	// paths is a list of the normalized full paths, such as "server.metadata.secret_data"
+	paths []string
+}
+
+// NewRedactor returns a Redactor which masks the default sensitive fields and the extra fields.
+// An extra field is either a field name (e.g. "my_token") or a full path separated by dots
+// (e.g. "server.metadata.my_data"), the array indexes are omitted in the path.
+func NewRedactor(extra ...string) *Redactor {
+	r := Redactor{
+		fields: append([]string{}, defaultSensitiveFields...),
+	}
+	for _, path := range defaultSensitivePaths {
+		r.paths = append(r.paths, normalizeFieldPath(strings.Split(path, ".")))
+	}
+
+	for _, field := range extra {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		if strings.Contains(field, ".") {
+			r.paths = append(r.paths, normalizeFieldPath(strings.Split(field, ".")))
+		} else {
+			r.fields = append(r.fields, normalizeFieldName(field))
+		}
+	}
+
+	return &r
+}
+
+// defaultRedactor returns a Redactor with the extra fields specified by HW_DEBUG_REDACT_FIELDS,
+// the value is a comma-separated list of field names or paths.
+func defaultRedactor() *Redactor {
+	return NewRedactor(strings.Split(os.Getenv("HW_DEBUG_REDACT_FIELDS"), ",")...)
+}
+
+// RedactJSON masks the sensitive fields in the decoded JSON data recursively.
+// Only the values of strings, numbers and booleans are masked, objects and arrays
+// are walked through so that the other fields in them are still visible.
+func (r *Redactor) RedactJSON(data interface{}) interface{} {
+	return r.redactValue(nil, data)
+}
+
+func (r *Redactor) redactValue(path []string, data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = r.redactValue(append(path, key), value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = r.redactValue(path, value)
+		}
+		return v
+	case nil:
+		return nil
+	default:
+		if len(path) > 0 && r.isSensitive(path) {
+			return redactedValue
+		}
+		return v
+	}
+}
+
+// RedactQuery returns the URL string with the values of sensitive query parameters masked.
+func (r *Redactor) RedactQuery(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	if u.RawQuery == "" {
+		return u.String()
+	}
+
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	params := make([]string, 0, len(keys))
+	for _, key := range keys {
+		sensitive := r.isSensitive([]string{key})
+		for _, value := range query[key] {
+			if sensitive {
+				value = redactedValue
+			}
+			params = append(params, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+
+	redacted := *u
+	redacted.RawQuery = strings.Join(params, "&")
+	return redacted.String()
+}
+
+func (r *Redactor) isSensitive(path []string) bool {
+	name := normalizeFieldName(path[len(path)-1])
+	for _, field := range r.fields {
+		if strings.HasSuffix(name, field) {
+			return true
+		}
+	}
+
+	if len(r.paths) > 0 {
+		fullPath := normalizeFieldPath(path)
+		for _, p := range r.paths {
+			if fullPath == p {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// normalizeFieldName converts the field name to lower case and removes the separators,
+// so "adminPass", "admin_pass" and "X-Admin-Pass" are regarded as the same field.
+func normalizeFieldName(name string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+}
+
+func normalizeFieldPath(path []string) string {
+	normalized := make([]string, len(path))
+	for i, name := range path {
+		normalized[i] = normalizeFieldName(name)
+	}
+	return strings.Join(normalized, ".")
+}
@@ -0,0 +1,257 @@
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/sdkerr"
+)
+
+const (
+	// cleanupMaxAttempts is the number of attempts to delete a temporary resource
+	cleanupMaxAttempts = 3
+	resourceTrackerKey = "resource_tracker"
+)
+
+// cleanupRetryDelay is the delay before the second attempt to delete a resource, it doubles after
+// each attempt.
+var cleanupRetryDelay = 5 * time.Second
+
+// temporaryResource is a resource created by the build, it's deleted when the build ends.
+type temporaryResource struct {
+	Kind string // the kind of the resource in the messages, e.g. "server"
+	ID   string
+	Name string // optional
+	// DependsOn are the keys of the resources used by this resource, this resource is deleted
+	// before them. The keys which are not tracked are ignored, e.g. the existing VPC.
+	DependsOn []string
+	// NonBlocking means the resources it depends on are deleted even if this one is left behind
+	NonBlocking bool
+	// Delete deletes the resource, it's retried on error, so it must succeed if the resource
+	// has already been deleted
+	Delete func(ctx context.Context, ui packer.Ui) error
+	// Hint tells how to delete the resource manually if it's left behind
+	Hint string
+
+	createdAt time.Time
+	deletedAt time.Time
+	done      chan struct{}
+	err       error
+}
+
+func (r *temporaryResource) key() string {
+	return resourceKey(r.Kind, r.ID)
+}
+
+func (r *temporaryResource) String() string {
+	if r.Name != "" && r.Name != r.ID {
+		return fmt.Sprintf("%s %s (%s)", r.Kind, r.ID, r.Name)
+	}
+	return fmt.Sprintf("%s %s", r.Kind, r.ID)
+}
+
+// resourceKey returns the key of the resource, which is referenced by the DependsOn of the others.
+func resourceKey(kind, id string) string {
+	return kind + " " + id
+}
+
+// resourceTracker records the temporary resources created by the steps, and deletes them when the
+// build ends, see stepCleanupResources.
+//
+// A resource is deleted after all the resources depending on it, while the independent resources
+// are deleted concurrently. A failed deletion is retried with backoff, and the other resources are
+// still deleted, except those that the left behind resource depends on. The resources that are
+// left behind are listed with the hints to delete them manually.
+type resourceTracker struct {
+	mu        sync.Mutex
+	resources []*temporaryResource
+	cleanedUp bool
+	// sleep sleeps between the retries, see Config.waitSleep
+	sleep SleepFunc
+}
+
+// resourceTrackerFrom returns the resource tracker of the build, it's added to the state if missing.
+func resourceTrackerFrom(state multistep.StateBag) *resourceTracker {
+	if tracker, ok := state.GetOk(resourceTrackerKey); ok {
+		return tracker.(*resourceTracker)
+	}
+
+	tracker := &resourceTracker{sleep: sleepContext}
+	if config, ok := state.GetOk("config"); ok && config.(*Config).waitSleep != nil {
+		tracker.sleep = config.(*Config).waitSleep
+	}
+	state.Put(resourceTrackerKey, tracker)
+	return tracker
+}
+
+// Track records a temporary resource and returns its key. The dependencies must be tracked before,
+// so that there's no cycle.
+func (t *resourceTracker) Track(resource temporaryResource) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var dependsOn []string
+	for _, key := range resource.DependsOn {
+		if t.find(key) != nil {
+			dependsOn = append(dependsOn, key)
+		}
+	}
+	resource.DependsOn = dependsOn
+	resource.createdAt = time.Now()
+	resource.done = make(chan struct{})
+
+	log.Printf("[DEBUG] tracking the temporary %s", resource.String())
+	t.resources = append(t.resources, &resource)
+	return resource.key()
+}
+
+// Failed reports whether the resource is left behind, it's called by the Delete functions of the
+// resources it depends on, after the resource is deleted or left behind.
+func (t *resourceTracker) Failed(key string) bool {
+	t.mu.Lock()
+	resource := t.find(key)
+	t.mu.Unlock()
+
+	if resource == nil {
+		return false
+	}
+	<-resource.done
+	return resource.err != nil
+}
+
+// all returns the tracked resources.
+func (t *resourceTracker) all() []*temporaryResource {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*temporaryResource(nil), t.resources...)
+}
+
+// find returns the resource with the key, it's called with the lock held.
+func (t *resourceTracker) find(key string) *temporaryResource {
+	for _, resource := range t.resources {
+		if resource.key() == key {
+			return resource
+		}
+	}
+	return nil
+}
+
+// Cleanup deletes all the tracked resources, and returns those that are left behind.
+func (t *resourceTracker) Cleanup(ctx context.Context, ui packer.Ui) []*temporaryResource {
+	t.mu.Lock()
+	resources := t.resources
+	cleanedUp := t.cleanedUp
+	t.cleanedUp = true
+	t.mu.Unlock()
+
+	if cleanedUp || len(resources) == 0 {
+		return nil
+	}
+
+	// a resource waits for the resources depending on it
+	dependents := make(map[string][]*temporaryResource)
+	for _, resource := range resources {
+		for _, key := range resource.DependsOn {
+			dependents[key] = append(dependents[key], resource)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, resource := range resources {
+		wg.Add(1)
+		go func(resource *temporaryResource) {
+			defer wg.Done()
+			defer close(resource.done)
+
+			var blockers []string
+			for _, dependent := range dependents[resource.key()] {
+				<-dependent.done
+				if dependent.err != nil && !dependent.NonBlocking {
+					blockers = append(blockers, dependent.String())
+				}
+			}
+			if len(blockers) > 0 {
+				resource.err = fmt.Errorf("not deleted since it's still used by %s", strings.Join(blockers, ", "))
+				return
+			}
+
+			if resource.err = t.deleteWithRetry(ctx, ui, resource); resource.err == nil {
+				resource.deletedAt = time.Now()
+			}
+		}(resource)
+	}
+	wg.Wait()
+
+	var leftovers []*temporaryResource
+	for _, resource := range resources {
+		if resource.err != nil {
+			leftovers = append(leftovers, resource)
+		}
+	}
+	if len(leftovers) > 0 {
+		ui.Error(leftoverSummary(leftovers))
+	}
+	return leftovers
+}
+
+func (t *resourceTracker) deleteWithRetry(ctx context.Context, ui packer.Ui, resource *temporaryResource) error {
+	delay := cleanupRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := resource.Delete(ctx, ui)
+		if err == nil {
+			return nil
+		}
+		if attempt >= cleanupMaxAttempts || ctx.Err() != nil {
+			return err
+		}
+
+		ui.Message(fmt.Sprintf("Error deleting the temporary %s, retrying in %s (%d/%d): %s",
+			resource.String(), delay, attempt, cleanupMaxAttempts-1, err))
+		if err := t.sleep(ctx, delay); err != nil {
+			return err
+		}
+		delay *= 2
+	}
+}
+
+func leftoverSummary(leftovers []*temporaryResource) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d temporary resource(s) could not be deleted, please delete them manually:", len(leftovers))
+	for _, resource := range leftovers {
+		fmt.Fprintf(&b, "\n  - %s: %s", resource.String(), resource.err)
+		if resource.Hint != "" {
+			fmt.Fprintf(&b, "\n    %s", resource.Hint)
+		}
+	}
+	return b.String()
+}
+
+// isNotFoundError reports whether the API returns 404.
+func isNotFoundError(err error) bool {
+	responseErr, ok := err.(*sdkerr.ServiceResponseError)
+	return ok && responseErr.StatusCode == http.StatusNotFound
+}
+
+// stepCleanupResources is the first step of the build, it deletes the temporary resources tracked
+// by the other steps when the build ends, see resourceTracker.
+type stepCleanupResources struct{}
+
+func (s *stepCleanupResources) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	resourceTrackerFrom(state)
+	return multistep.ActionContinue
+}
+
+func (s *stepCleanupResources) Cleanup(state multistep.StateBag) {
+	ui := state.Get("ui").(packer.Ui)
+
+	// the cleanup runs after the build is cancelled, so it's not bound to the context of the steps
+	resourceTrackerFrom(state).Cleanup(context.Background(), ui)
+}
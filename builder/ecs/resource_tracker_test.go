@@ -0,0 +1,182 @@
+package ecs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// deletions records the order of the deletions.
+type deletions struct {
+	mu    sync.Mutex
+	order []string
+}
+
+func (d *deletions) deleteFunc(id string, err error) func(context.Context, packer.Ui) error {
+	return func(context.Context, packer.Ui) error {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.order = append(d.order, id)
+		return err
+	}
+}
+
+func (d *deletions) index(id string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, deleted := range d.order {
+		if deleted == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestResourceTracker_Order(t *testing.T) {
+	state, _ := testStepState(t, nil)
+	tracker := resourceTrackerFrom(state)
+	deleted := &deletions{}
+
+	// the subnet and the keypair are independent, so they're deleted concurrently
+	subnetStarted := make(chan struct{})
+	keypairStarted := make(chan struct{})
+	waitFor := func(started chan struct{}, other chan struct{}) func(context.Context, packer.Ui) error {
+		return func(ctx context.Context, ui packer.Ui) error {
+			close(started)
+			select {
+			case <-other:
+			case <-time.After(5 * time.Second):
+				return errors.New("the resources are not deleted concurrently")
+			}
+			return nil
+		}
+	}
+
+	vpc := tracker.Track(temporaryResource{Kind: "VPC", ID: "vpc-1", Delete: deleted.deleteFunc("vpc-1", nil)})
+	subnet := tracker.Track(temporaryResource{Kind: "subnet", ID: "subnet-1", DependsOn: []string{vpc},
+		Delete: func(ctx context.Context, ui packer.Ui) error {
+			if err := waitFor(subnetStarted, keypairStarted)(ctx, ui); err != nil {
+				return err
+			}
+			return deleted.deleteFunc("subnet-1", nil)(ctx, ui)
+		}})
+	keypair := tracker.Track(temporaryResource{Kind: "keypair", ID: "packer-key",
+		Delete: func(ctx context.Context, ui packer.Ui) error {
+			if err := waitFor(keypairStarted, subnetStarted)(ctx, ui); err != nil {
+				return err
+			}
+			return deleted.deleteFunc("packer-key", nil)(ctx, ui)
+		}})
+	tracker.Track(temporaryResource{Kind: "server", ID: "server-1",
+		DependsOn: []string{subnet, keypair, resourceKey("public IP", "existing")},
+		Delete:    deleted.deleteFunc("server-1", nil)})
+
+	if leftovers := tracker.Cleanup(context.Background(), state.Get("ui").(packer.Ui)); len(leftovers) > 0 {
+		t.Fatalf("unexpected leftovers: %v", leftovers)
+	}
+	if len(deleted.order) != 4 {
+		t.Fatalf("expected 4 resources to be deleted, got %v", deleted.order)
+	}
+	server := deleted.index("server-1")
+	if server > deleted.index("subnet-1") || server > deleted.index("packer-key") ||
+		deleted.index("subnet-1") > deleted.index("vpc-1") {
+		t.Fatalf("the resources are not deleted in the order of the dependencies: %v", deleted.order)
+	}
+
+	// the resources are deleted only once
+	if leftovers := tracker.Cleanup(context.Background(), state.Get("ui").(packer.Ui)); leftovers != nil || len(deleted.order) != 4 {
+		t.Fatalf("the resources are deleted again: %v", deleted.order)
+	}
+}
+
+func TestResourceTracker_RetryAndLeftovers(t *testing.T) {
+	state, _ := testStepState(t, nil)
+	tracker := resourceTrackerFrom(state)
+	deleted := &deletions{}
+
+	attempts := 0
+	tracker.Track(temporaryResource{Kind: "public IP", ID: "eip-1",
+		Delete: func(ctx context.Context, ui packer.Ui) error {
+			if attempts++; attempts < cleanupMaxAttempts {
+				return errors.New("the public IP is still bound")
+			}
+			return deleted.deleteFunc("eip-1", nil)(ctx, ui)
+		}})
+	vpc := tracker.Track(temporaryResource{Kind: "VPC", ID: "vpc-1", Delete: deleted.deleteFunc("vpc-1", nil),
+		Hint: "Delete it in the VPC console"})
+	tracker.Track(temporaryResource{Kind: "subnet", ID: "subnet-1", DependsOn: []string{vpc},
+		Delete: deleted.deleteFunc("subnet-1", errors.New("the subnet is in use")),
+		Hint:   "Delete it in the VPC console"})
+	tracker.Track(temporaryResource{Kind: "keypair", ID: "packer-key", Delete: deleted.deleteFunc("packer-key", nil)})
+
+	leftovers := tracker.Cleanup(context.Background(), state.Get("ui").(packer.Ui))
+	if len(leftovers) != 2 || leftovers[0].ID != "vpc-1" || leftovers[1].ID != "subnet-1" {
+		t.Fatalf("expected the subnet and the VPC to be left behind, got %v", leftovers)
+	}
+
+	// the failed deletion is retried, and the others are not stopped by the failure
+	if attempts != cleanupMaxAttempts || deleted.index("eip-1") < 0 || deleted.index("packer-key") < 0 {
+		t.Fatalf("expected the public IP and the keypair to be deleted, got %v", deleted.order)
+	}
+	// the VPC is not deleted since the subnet is left behind
+	if deleted.index("vpc-1") >= 0 {
+		t.Fatalf("expected the VPC to be kept, got %v", deleted.order)
+	}
+
+	output := state.Get("ui").(*packer.BasicUi).Writer.(*bytes.Buffer).String()
+	for _, expected := range []string{
+		"2 temporary resource(s) could not be deleted",
+		"subnet subnet-1: the subnet is in use",
+		"VPC vpc-1: not deleted since it's still used by subnet subnet-1",
+		"Delete it in the VPC console",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in the summary, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestStepAttachVolume_DetachFailure(t *testing.T) {
+	ecsClient := &fakeServerClient{ServerID: "server-1", DetachErr: errors.New("the server is locked")}
+	state, _ := testStepState(t, map[string]interface{}{
+		"ecs": ecsClient,
+		"evs": struct{ VolumeClient }{},
+	})
+	state.Put("flavor_id", "s6.large.2")
+	state.Put("source_image", "image-1")
+	state.Put("vpc_id", "vpc-1")
+	state.Put("subnets", []string{"subnet-1"})
+	state.Put("availability_zone", "cn-north-4a")
+	state.Put("data_disk_wraps", []DataVolumeWrap{
+		{DataVolume: DataVolume{VolumeId: "volume-1"}, dataType: VolumeId},
+	})
+
+	// the server fails after creating, the volume is attached in the test
+	ecsClient.InterfacesErr = errors.New("service unavailable")
+	if action := (&StepRunSourceServer{Name: "packer-test"}).Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected the step to halt, but got %v", action)
+	}
+	state.Put("server_id", "server-1")
+	if action := (&StepAttachVolume{PrefixName: "packer-test"}).Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %v, err: %v", action, state.Get("error"))
+	}
+
+	// the server is deleted although the volume can not be detached, but the volumes are kept
+	leftovers := cleanupResources(state)
+	if len(leftovers) != 1 || leftovers[0].key() != resourceKey("volume attachment", "volume-1") {
+		t.Fatalf("expected the volume attachment to be left behind, got %v", leftovers)
+	}
+	if !ecsClient.called("DeleteServers server-1") {
+		t.Fatalf("expected the server to be deleted, calls: %v", ecsClient.calls)
+	}
+	if ecsClient.deletedVolumes {
+		t.Fatalf("expected the volumes of the server to be kept")
+	}
+}
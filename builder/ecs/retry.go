@@ -0,0 +1,174 @@
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 5
+	retryMaxDelay     = 30 * time.Second
+	// maxErrorBodySize is the maximum size of the error body to be decoded for the error code
+	maxErrorBodySize = 64 * 1024
+)
+
+// retryBaseDelay is the base delay of the exponential backoff.
+var retryBaseDelay = 1 * time.Second
+
+// retryableErrorCodes is a list of error codes which indicate the request is throttled,
+// some services return them with a status code other than 429.
+var retryableErrorCodes = []string{
+	"APIGW.0308",
+}
+
+// retryRoundTripper retries the requests which are throttled or failed with transient errors,
+// it waits with exponential backoff and full jitter between the retries.
+//
+// The requests with idempotent methods are retried on 429, 5xx and connection errors,
+// while the POST and PATCH requests are retried only if they are not processed by the server,
+// i.e. on 429, 503 and the throttling error codes, so that no duplicate resource is created.
+type retryRoundTripper struct {
+	rt         http.RoundTripper
+	maxRetries int
+}
+
+func (r *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the request can not be retried if the body can not be rewound
+	if r.maxRetries <= 0 || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return r.rt.RoundTrip(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := r.rt.RoundTrip(req)
+		if attempt > r.maxRetries || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		delay := retryDelay(attempt, resp)
+		if err != nil {
+			log.Printf("[WARN] retrying %s %s in %s (%d/%d): %s",
+				req.Method, req.URL.Path, delay, attempt, r.maxRetries, err)
+		} else {
+			log.Printf("[WARN] retrying %s %s in %s (%d/%d): status code %d, request ID %s",
+				req.Method, req.URL.Path, delay, attempt, r.maxRetries, resp.StatusCode, getRequestID(resp))
+			// drain the body to reuse the connection
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	idempotent := req.Method != http.MethodPost && req.Method != http.MethodPatch
+
+	if err != nil {
+		if req.Context().Err() != nil {
+			return false
+		}
+		// the connection is refused before the request is sent
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return true
+		}
+		if !idempotent {
+			return false
+		}
+
+		var netErr net.Error
+		return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) ||
+			errors.Is(err, io.ErrUnexpectedEOF) || (errors.As(err, &netErr) && netErr.Timeout())
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return isRetryableErrorCode(resp)
+	}
+	return false
+}
+
+// isRetryableErrorCode checks the error code in the response body, the body is restored
+// so that it can be read by the SDK.
+func isRetryableErrorCode(resp *http.Response) bool {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	if err != nil {
+		return false
+	}
+
+	var errorBody struct {
+		ErrorCode string `json:"error_code"`
+		Code      string `json:"code"`
+	}
+	if json.Unmarshal(body, &errorBody) != nil {
+		return false
+	}
+
+	for _, code := range retryableErrorCodes {
+		if errorBody.ErrorCode == code || errorBody.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryDelay returns the delay before the next retry, the Retry-After header takes precedence
+// over the exponential backoff.
+func retryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			delay := time.Duration(seconds) * time.Second
+			if delay > retryMaxDelay {
+				delay = retryMaxDelay
+			}
+			return delay
+		}
+	}
+
+	backoff := retryBaseDelay << uint(attempt-1)
+	if backoff <= 0 || backoff > retryMaxDelay {
+		backoff = retryMaxDelay
+	}
+	// full jitter, but wait at least half of the base delay
+	// #nosec G404 -- the jitter does not require a secure random number
+	return retryBaseDelay/2 + time.Duration(rand.Int63n(int64(backoff)))
+}
+
+func getRequestID(resp *http.Response) string {
+	for _, key := range []string{"X-Request-Id", "X-Obs-Request-Id", "X-Openstack-Request-Id"} {
+		if id := resp.Header.Get(key); id != "" {
+			return id
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,60 @@
+package ecs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryRoundTripper(t *testing.T) {
+	defer func(delay time.Duration) { retryBaseDelay = delay }(retryBaseDelay)
+	retryBaseDelay = time.Millisecond
+
+	var count int
+	var status int
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.Header().Set("X-Request-Id", fmt.Sprintf("request-%d", count))
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	c := &AccessConfig{MaxRetries: 3}
+	client := http.Client{Transport: c.NewTransport()}
+
+	cases := []struct {
+		method string
+		status int
+		body   string
+		count  int
+	}{
+		{http.MethodGet, http.StatusOK, "", 1},
+		{http.MethodGet, http.StatusTooManyRequests, "", 4},
+		{http.MethodGet, http.StatusInternalServerError, "", 4},
+		{http.MethodGet, http.StatusNotFound, `{"error_code": "Ecs.0114"}`, 1},
+		{http.MethodGet, http.StatusForbidden, `{"error_code": "APIGW.0308"}`, 4},
+		{http.MethodPost, http.StatusTooManyRequests, "", 4},
+		{http.MethodPost, http.StatusServiceUnavailable, "", 4},
+		// the creation request may have been processed
+		{http.MethodPost, http.StatusInternalServerError, "", 1},
+	}
+
+	for _, tc := range cases {
+		count, status, body = 0, tc.status, tc.body
+		req, _ := http.NewRequest(tc.method, server.URL, strings.NewReader(`{"name": "test"}`))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.status || count != tc.count {
+			t.Errorf("%s with status %d: expected %d requests, but got %d", tc.method, tc.status, tc.count, count)
+		}
+	}
+}
@@ -180,6 +180,44 @@ type RunConfig struct {
 	// The ID of the vault to which the instance is to be added.
 	// This parameter is **mandatory** when creating a full-ECS image from the instance.
 	Vault string `mapstructure:"vault_id" required:"false"`
+	// The timeouts to wait for the resources to reach the expected states.
+	// Each timeout is a duration string such as `30s`, `15m` or `1h`.
+	// Usage example:
+	//
+	// ``` json {
+	//   "timeouts": {
+	//     "create_server": "20m",
+	//     "password": "30m"
+	//   },
+	//   ...
+	// }
+	// ```
+	//
+	// The timeouts allow for the following argument:
+	//   -  `create_server` (string) - The timeout to create the server, defaults to `10m`.
+	//   -  `delete_server` (string) - The timeout to delete the server, defaults to `10m`.
+	//   -  `stop_server` (string) - The timeout to stop the server, defaults to `3m`.
+	//   -  `attach_volume` (string) - The timeout to attach or detach a data disk, defaults to `10m`.
+	//   -  `create_volume` (string) - The timeout to create a data disk, defaults to `10m`.
+	//   -  `network` (string) - The timeout to create or delete the temporary VPC and subnet, defaults to `3m`.
+	//   -  `eip` (string) - The timeout to wait for the temporary EIP to be active, defaults to `5m`.
+	//   -  `password` (string) - The timeout to wait for the password of the Windows server, defaults to `10m`.
+	Timeouts Timeouts `mapstructure:"timeouts" required:"false"`
+	// The path of a JSON file to which the build report is written when the build ends, even if it
+	// fails. The report contains the region, availability zone and flavor used by the build, how the
+	// source image was resolved, the temporary resources with the times when they were created and
+	// deleted, the image jobs, and the duration of each step.
+	BuildReportFile string `mapstructure:"build_report_file" required:"false"`
+	// Stop the build after the preflight checks, without creating any resource. The preflight checks
+	// run at the start of every build, they check the quotas of ECS, EVS, VPC, EIP and IMS, and the
+	// resources referenced by the configuration, such as the VPC, subnets, security groups, KMS keys,
+	// vault, EIP and volumes, and report all the problems found at once. When the checks pass, the
+	// build succeeds without returning an artifact, so no post-processor runs.
+	ValidateOnly bool `mapstructure:"validate_only" required:"false"`
+	// Run the build up to the provisioners and stop the server, but don't create the image, which is
+	// useful to test the provisioners against a real server. The temporary resources are deleted as
+	// usual, and the build returns no artifact, so no post-processor runs. Defaults to false.
+	SkipCreateImage bool `mapstructure:"skip_create_image" required:"false"`
 
 	sourceImageOpts *model.ListImagesRequest
 }
@@ -326,6 +364,8 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 		c.sourceImageOpts = listOpts
 	}
 
+	errs = append(errs, c.Timeouts.Prepare()...)
+
 	// check user data file whether exists
 	if c.UserDataFile != "" {
 		_, fileErr := os.Stat(c.UserDataFile)
@@ -2,6 +2,7 @@ package ecs
 
 import (
 	"testing"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
 	"github.com/mitchellh/mapstructure"
@@ -82,6 +83,27 @@ func TestRunConfigPrepare_BlockStorage(t *testing.T) {
 	}
 }
 
+func TestRunConfigPrepare_Timeouts(t *testing.T) {
+	c := testRunConfig()
+	c.Timeouts.CreateServer = "20m"
+	if err := c.Prepare(nil); len(err) > 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if c.Timeouts.createServer != 20*time.Minute {
+		t.Fatalf("expected the create_server timeout to be 20m, but got %s", c.Timeouts.createServer)
+	}
+	if c.Timeouts.stopServer != 3*time.Minute {
+		t.Fatalf("expected the default stop_server timeout to be 3m, but got %s", c.Timeouts.stopServer)
+	}
+
+	c = testRunConfig()
+	c.Timeouts.Password = "ten minutes"
+	c.Timeouts.EIP = "-5m"
+	if err := c.Prepare(nil); len(err) != 2 {
+		t.Fatalf("expected 2 errors, but got: %s", err)
+	}
+}
+
 // This test case confirms that only allowed fields will be set to values
 // The checked values are non-nil for their target type
 func TestBuildImageFilter(t *testing.T) {
@@ -0,0 +1,158 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/pathing"
+	"gopkg.in/ini.v1"
+)
+
+const (
+	defaultSharedConfigFile      = "~/.hcloud/config.json"
+	defaultSharedCredentialsFile = "~/.huaweicloud/credentials"
+	defaultProfileName           = "default"
+)
+
+// SharedConfig is the configuration file of KooCLI (hcloud), it's located in ~/.hcloud/config.json by default.
+type SharedConfig struct {
+	Current  string          `json:"current"`
+	Profiles []SharedProfile `json:"profiles"`
+}
+
+// SharedProfile is a profile in the shared config file or the shared credentials file.
+type SharedProfile struct {
+	Name            string `json:"name"`
+	Mode            string `json:"mode"`
+	AccessKeyId     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	SecurityToken   string `json:"securityToken"`
+	Region          string `json:"region"`
+	ProjectId       string `json:"projectId"`
+	DomainId        string `json:"domainId"`
+}
+
+// loadSharedConfig loads the profile from the shared config file and fills the fields
+// which are not specified in the template or the environment variables.
+func (c *AccessConfig) loadSharedConfig() error {
+	path := c.SharedConfigFile
+	if path == "" {
+		path = defaultSharedConfigFile
+		if expanded, err := pathing.ExpandUser(path); err != nil || !isFileExist(expanded) {
+			path = defaultSharedCredentialsFile
+		}
+	}
+
+	filePath, err := pathing.ExpandUser(path)
+	if err != nil {
+		return fmt.Errorf("failed to expand the path of shared_config_file %s: %s", path, err)
+	}
+
+	profile, err := readSharedProfile(filePath, c.Profile)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] loading the credentials from profile %q in %s", profile.Name, filePath)
+	// the credentials in the profile are used only if neither access_key nor secret_key is specified
+	if c.AccessKey == "" && c.SecretKey == "" {
+		c.AccessKey = profile.AccessKeyId
+		c.SecretKey = profile.SecretAccessKey
+		if c.SecurityToken == "" {
+			c.SecurityToken = profile.SecurityToken
+		}
+	}
+	if c.Region == "" {
+		c.Region = profile.Region
+	}
+	if c.ProjectID == "" && profile.Region == c.Region {
+		c.ProjectID = profile.ProjectId
+	}
+	if c.DomainID == "" {
+		c.DomainID = profile.DomainId
+	}
+
+	return nil
+}
+
+// readSharedProfile reads the profile from a KooCLI config file in JSON format or
+// a credentials file in INI format.
+func readSharedProfile(filePath, name string) (*SharedProfile, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the shared config file %s: %s", filePath, err)
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(string(content)), "{") {
+		return readKooCLIProfile(filePath, content, name)
+	}
+	return readCredentialsProfile(filePath, content, name)
+}
+
+func readKooCLIProfile(filePath string, content []byte, name string) (*SharedProfile, error) {
+	var sharedConfig SharedConfig
+	if err := json.Unmarshal(content, &sharedConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse the shared config file %s: %s", filePath, err)
+	}
+
+	if name == "" {
+		name = sharedConfig.Current
+	}
+	if name == "" {
+		name = defaultProfileName
+	}
+
+	allNames := make([]string, 0, len(sharedConfig.Profiles))
+	for _, profile := range sharedConfig.Profiles {
+		if profile.Name != name {
+			allNames = append(allNames, profile.Name)
+			continue
+		}
+
+		if profile.Mode != "" && !strings.EqualFold(profile.Mode, "AKSK") {
+			return nil, fmt.Errorf("the mode of profile %q in %s is %s, only AKSK mode is supported",
+				name, filePath, profile.Mode)
+		}
+		return &profile, nil
+	}
+
+	return nil, fmt.Errorf("the profile %q does not exist in the shared config file %s, available profiles: %v",
+		name, filePath, allNames)
+}
+
+func readCredentialsProfile(filePath string, content []byte, name string) (*SharedProfile, error) {
+	file, err := ini.Load(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the shared credentials file %s: %s", filePath, err)
+	}
+
+	if name == "" {
+		name = defaultProfileName
+	}
+
+	section, err := file.GetSection(name)
+	if err != nil {
+		return nil, fmt.Errorf("the profile %q does not exist in the shared credentials file %s, available profiles: %v",
+			name, filePath, file.SectionStrings()[1:])
+	}
+
+	profile := SharedProfile{
+		Name:            name,
+		AccessKeyId:     section.Key("ak").String(),
+		SecretAccessKey: section.Key("sk").String(),
+		SecurityToken:   section.Key("security_token").String(),
+		Region:          section.Key("region").String(),
+		ProjectId:       section.Key("project_id").String(),
+		DomainId:        section.Key("domain_id").String(),
+	}
+	return &profile, nil
+}
+
+func isFileExist(path string) bool {
+	info, err := os.Stat(filepath.Clean(path))
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,121 @@
+package ecs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testKooCLIConfig = `{
+  "language": "en",
+  "current": "dev",
+  "profiles": [
+    {
+      "name": "default",
+      "mode": "AKSK",
+      "accessKeyId": "default-ak",
+      "secretAccessKey": "default-sk",
+      "region": "cn-north-1"
+    },
+    {
+      "name": "dev",
+      "mode": "AKSK",
+      "accessKeyId": "dev-ak",
+      "secretAccessKey": "dev-sk",
+      "securityToken": "dev-token",
+      "region": "cn-north-4",
+      "projectId": "dev-project",
+      "domainId": "dev-domain"
+    }
+  ]
+}`
+
+const testCredentialsFile = `[default]
+ak = default-ak
+sk = default-sk
+region = cn-north-1
+
+[prod]
+ak = prod-ak
+sk = prod-sk
+region = cn-north-4
+project_id = prod-project
+`
+
+func writeTestFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write %s: %s", path, err)
+	}
+	return path
+}
+
+func TestLoadSharedConfig_KooCLI(t *testing.T) {
+	c := &AccessConfig{
+		SharedConfigFile: writeTestFile(t, "config.json", testKooCLIConfig),
+	}
+	if err := c.loadSharedConfig(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// the current profile is used by default
+	if c.AccessKey != "dev-ak" || c.SecretKey != "dev-sk" || c.SecurityToken != "dev-token" {
+		t.Fatalf("bad credentials: %s, %s, %s", c.AccessKey, c.SecretKey, c.SecurityToken)
+	}
+	if c.Region != "cn-north-4" || c.ProjectID != "dev-project" || c.DomainID != "dev-domain" {
+		t.Fatalf("bad region, project or domain: %s, %s, %s", c.Region, c.ProjectID, c.DomainID)
+	}
+}
+
+func TestLoadSharedConfig_Precedence(t *testing.T) {
+	c := &AccessConfig{
+		AccessKey:        "my-ak",
+		SecretKey:        "my-sk",
+		Region:           "cn-east-3",
+		SharedConfigFile: writeTestFile(t, "config.json", testKooCLIConfig),
+		Profile:          "dev",
+	}
+	if err := c.loadSharedConfig(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if c.AccessKey != "my-ak" || c.SecretKey != "my-sk" || c.SecurityToken != "" {
+		t.Fatalf("the credentials should not be overridden: %s, %s, %s", c.AccessKey, c.SecretKey, c.SecurityToken)
+	}
+	// the project ID in the profile belongs to another region
+	if c.Region != "cn-east-3" || c.ProjectID != "" {
+		t.Fatalf("bad region or project: %s, %s", c.Region, c.ProjectID)
+	}
+}
+
+func TestLoadSharedConfig_Credentials(t *testing.T) {
+	c := &AccessConfig{
+		SharedConfigFile: writeTestFile(t, "credentials", testCredentialsFile),
+		Profile:          "prod",
+	}
+	if err := c.loadSharedConfig(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if c.AccessKey != "prod-ak" || c.SecretKey != "prod-sk" {
+		t.Fatalf("bad credentials: %s, %s", c.AccessKey, c.SecretKey)
+	}
+	if c.Region != "cn-north-4" || c.ProjectID != "prod-project" {
+		t.Fatalf("bad region or project: %s, %s", c.Region, c.ProjectID)
+	}
+}
+
+func TestLoadSharedConfig_ProfileNotFound(t *testing.T) {
+	for _, file := range []string{
+		writeTestFile(t, "config.json", testKooCLIConfig),
+		writeTestFile(t, "credentials", testCredentialsFile),
+	} {
+		c := &AccessConfig{
+			SharedConfigFile: file,
+			Profile:          "missing",
+		}
+		if err := c.loadSharedConfig(); err == nil {
+			t.Fatalf("loading the missing profile from %s should fail", file)
+		}
+	}
+}
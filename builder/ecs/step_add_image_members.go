@@ -21,7 +21,7 @@ func (s *stepAddImageMembers) Run(ctx context.Context, state multistep.StateBag)
 	}
 
 	region := config.Region
-	imsClient, err := config.HcImsClient(region)
+	imsClient, err := config.imageClient(region)
 	if err != nil {
 		err = fmt.Errorf("Error initializing image service client: %s", err)
 		state.Put("error", err)
@@ -8,9 +8,7 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/packer"
 
-	ecs "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2"
 	ecsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2/model"
-	evs "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/evs/v2"
 	evsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/evs/v2/model"
 )
 
@@ -28,13 +26,13 @@ func (s *StepAttachVolume) Run(ctx context.Context, state multistep.StateBag) mu
 	}
 
 	region := config.Region
-	ecsClient, err := config.HcEcsClient(region)
+	ecsClient, err := config.serverVolumeClient(region)
 	if err != nil {
 		err = fmt.Errorf("error initializing ECS client: %s", err)
 		state.Put("error", err)
 		return multistep.ActionHalt
 	}
-	evsClient, err := config.HcEvsClient(region)
+	evsClient, err := config.volumeClient(region)
 	if err != nil {
 		err = fmt.Errorf("error initializing EVS client: %s", err)
 		state.Put("error", err)
@@ -44,6 +42,9 @@ func (s *StepAttachVolume) Run(ctx context.Context, state multistep.StateBag) mu
 	attachVolumeIds := make([]string, 0)
 	serverId := state.Get("server_id").(string)
 	index := 1
+	tracker := resourceTrackerFrom(state)
+	// the volumes are detached one by one in the reverse order
+	var lastAttachment string
 
 	dataVolumeWraps := dataVolumes.([]DataVolumeWrap)
 	for _, dataVolumeWrap := range dataVolumeWraps {
@@ -53,7 +54,7 @@ func (s *StepAttachVolume) Run(ctx context.Context, state multistep.StateBag) mu
 			ui.Say(fmt.Sprintf("Attaching volume %s to ECS...", volumeId))
 
 			dataVolumeWrap.serverId = serverId
-			err = attachDataVolumes(ui, state, ecsClient, dataVolumeWrap)
+			err = attachDataVolumes(ctx, ui, state, ecsClient, dataVolumeWrap)
 			if err != nil {
 				state.Put("error", err)
 				return multistep.ActionHalt
@@ -61,13 +62,23 @@ func (s *StepAttachVolume) Run(ctx context.Context, state multistep.StateBag) mu
 
 			ui.Message(fmt.Sprintf("Attached volume %s to ECS", volumeId))
 			attachVolumeIds = append(attachVolumeIds, volumeId)
+			lastAttachment = tracker.Track(temporaryResource{
+				Kind:      "volume attachment",
+				ID:        volumeId,
+				DependsOn: []string{resourceKey("server", serverId), lastAttachment},
+				// the server is deleted even if the volume is left attached, but its volumes are kept
+				NonBlocking: true,
+				Delete:      detachVolumeFunc(state, ecsClient, serverId, volumeId),
+				Hint: fmt.Sprintf("The volume is kept when the server %s is deleted, detach it manually "+
+					"only if the server is left behind", serverId),
+			})
 		case Size, DataImageId, SnapshotId:
 			volumeName := s.generateVolumeName(index)
 			ui.Say(fmt.Sprintf("Creating and attaching %s...", volumeName))
 
 			dataVolumeWrap.serverId = serverId
 			dataVolumeWrap.volumeName = volumeName
-			err = createAndAttachVolume(ui, state, evsClient, dataVolumeWrap, index)
+			err = createAndAttachVolume(ctx, ui, state, evsClient, dataVolumeWrap, index)
 			if err != nil {
 				state.Put("error", err)
 				return multistep.ActionHalt
@@ -81,14 +92,15 @@ func (s *StepAttachVolume) Run(ctx context.Context, state multistep.StateBag) mu
 	return multistep.ActionContinue
 }
 
-func (s *StepAttachVolume) Cleanup(state multistep.StateBag) {
+func (s *StepAttachVolume) Cleanup(multistep.StateBag) {
+	// the volumes are detached by stepCleanupResources
 }
 
 func (s *StepAttachVolume) generateVolumeName(index int) string {
 	return fmt.Sprintf("%s-volume-%04d", s.PrefixName, index)
 }
 
-func attachDataVolumes(ui packer.Ui, state multistep.StateBag, ecsClient *ecs.EcsClient, disk DataVolumeWrap) error {
+func attachDataVolumes(ctx context.Context, ui packer.Ui, state multistep.StateBag, ecsClient ServerVolumeClient, disk DataVolumeWrap) error {
 	volumeId := disk.VolumeId
 	attachBody := &ecsmodel.AttachServerVolumeOption{
 		VolumeId: volumeId,
@@ -109,7 +121,7 @@ func attachDataVolumes(ui packer.Ui, state multistep.StateBag, ecsClient *ecs.Ec
 		jobID = *response.JobId
 	}
 
-	_, err = waitForAttachVolumeJobSuccess(ui, state, ecsClient, jobID)
+	_, err = waitForAttachVolumeJobSuccess(ctx, ui, state, ecsClient, jobID)
 	if err != nil {
 		return err
 	}
@@ -117,7 +129,30 @@ func attachDataVolumes(ui packer.Ui, state multistep.StateBag, ecsClient *ecs.Ec
 	return nil
 }
 
-func createAndAttachVolume(ui packer.Ui, state multistep.StateBag, evsClient *evs.EvsClient, disk DataVolumeWrap, index int) error {
+func detachVolumeFunc(state multistep.StateBag, client ServerVolumeClient, serverId, volumeId string) func(context.Context, packer.Ui) error {
+	return func(ctx context.Context, ui packer.Ui) error {
+		ui.Say(fmt.Sprintf("Detaching volume %s from ECS...", volumeId))
+		request := &ecsmodel.DetachServerVolumeRequest{
+			ServerId: serverId,
+			VolumeId: volumeId,
+		}
+		response, err := client.DetachServerVolume(request)
+		if err != nil {
+			return fmt.Errorf("error detach volume %s from ECS: %s", volumeId, err)
+		}
+
+		var jobID string
+		if response.JobId != nil {
+			jobID = *response.JobId
+		}
+		if _, err := WaitForDetachVolumeJobSuccess(ctx, ui, state, client, jobID); err != nil {
+			return fmt.Errorf("error detach volume %s from ECS: %s", volumeId, err)
+		}
+		return nil
+	}
+}
+
+func createAndAttachVolume(ctx context.Context, ui packer.Ui, state multistep.StateBag, evsClient VolumeClient, disk DataVolumeWrap, index int) error {
 	config := state.Get("config").(*Config)
 	availabilityZone := state.Get("availability_zone").(string)
 
@@ -168,51 +203,61 @@ func createAndAttachVolume(ui packer.Ui, state multistep.StateBag, evsClient *ev
 		jobID = *response.JobId
 	}
 
-	_, err = waitForCreateVolumeJobSuccess(ui, state, evsClient, jobID)
+	_, err = waitForCreateVolumeJobSuccess(ctx, ui, state, evsClient, jobID)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func waitForAttachVolumeJobSuccess(ui packer.Ui, state multistep.StateBag, client *ecs.EcsClient, jobID string) (*ecsmodel.ShowJobResponse, error) {
+func waitForAttachVolumeJobSuccess(ctx context.Context, ui packer.Ui, state multistep.StateBag, client ServerJobClient,
+	jobID string) (*ecsmodel.ShowJobResponse, error) {
+	config := state.Get("config").(*Config)
+
 	ui.Message("Waiting for attach volume to ECS success...")
-	stateChange := StateChangeConf{
-		Pending:      []string{"INIT", "RUNNING"},
-		Target:       []string{"SUCCESS"},
-		Refresh:      serverJobStateRefreshFunc(client, jobID),
-		Timeout:      10 * time.Minute,
-		Delay:        10 * time.Second,
-		PollInterval: 10 * time.Second,
-		StateBag:     state,
-	}
-	serverJob, err := stateChange.WaitForState()
+	waiter := Waiter[*ecsmodel.ShowJobResponse]{
+		Pending:     []string{"INIT", "RUNNING"},
+		Target:      []string{"SUCCESS"},
+		Refresh:     serverJobStateRefreshFunc(client, jobID),
+		Timeout:     config.Timeouts.attachVolume,
+		Sleep:       config.waitSleep,
+		Delay:       10 * time.Second,
+		MinInterval: 5 * time.Second,
+		MaxInterval: 10 * time.Second,
+		Progress:    UiProgress(ui, fmt.Sprintf("Volume job %s", jobID)),
+	}
+	serverJob, err := waiter.Wait(ctx)
 	if err != nil {
 		err = fmt.Errorf("error waiting for volume (%s) to become ready: %s", jobID, err)
 		ui.Error(err.Error())
 		return nil, err
 	}
 
-	return serverJob.(*ecsmodel.ShowJobResponse), nil
+	return serverJob, nil
 }
 
-func waitForCreateVolumeJobSuccess(ui packer.Ui, state multistep.StateBag, client *evs.EvsClient, jobID string) (*evsmodel.ShowJobResponse, error) {
+func waitForCreateVolumeJobSuccess(ctx context.Context, ui packer.Ui, state multistep.StateBag, client VolumeClient,
+	jobID string) (*evsmodel.ShowJobResponse, error) {
+	config := state.Get("config").(*Config)
+
 	ui.Message("Waiting for create volume success...")
-	stateChange := StateChangeConf{
-		Pending:      []string{"PENDING"},
-		Target:       []string{"SUCCESS"},
-		Refresh:      volumeJobStateRefreshFunc(client, jobID),
-		Timeout:      10 * time.Minute,
-		Delay:        10 * time.Second,
-		PollInterval: 10 * time.Second,
-		StateBag:     state,
-	}
-	serverJob, err := stateChange.WaitForState()
+	waiter := Waiter[*evsmodel.ShowJobResponse]{
+		Pending:     []string{"PENDING"},
+		Target:      []string{"SUCCESS"},
+		Refresh:     volumeJobStateRefreshFunc(client, jobID),
+		Timeout:     config.Timeouts.createVolume,
+		Sleep:       config.waitSleep,
+		Delay:       10 * time.Second,
+		MinInterval: 5 * time.Second,
+		MaxInterval: 10 * time.Second,
+		Progress:    UiProgress(ui, fmt.Sprintf("Volume job %s", jobID)),
+	}
+	serverJob, err := waiter.Wait(ctx)
 	if err != nil {
 		err = fmt.Errorf("error waiting for create volume (%s) to become ready: %s", jobID, err)
 		ui.Error(err.Error())
 		return nil, err
 	}
 
-	return serverJob.(*evsmodel.ShowJobResponse), nil
+	return serverJob, nil
 }
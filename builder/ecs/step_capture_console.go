@@ -0,0 +1,114 @@
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2/model"
+)
+
+// consoleTailLines is the number of the last lines of the console log printed on failure.
+const consoleTailLines = 20
+
+// stepCaptureConsole runs right before the communicator connects to the server. When the
+// communicator times out or a provisioner fails, it saves the console log of the server to LogPath,
+// and prints the tail of the log and the remote console URL, before the server is deleted.
+// The failures before it runs or after stepProvisioned are not captured.
+type stepCaptureConsole struct {
+	LogPath string
+
+	serverID string
+}
+
+func (s *stepCaptureConsole) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	s.serverID = state.Get("server_id").(string)
+	return multistep.ActionContinue
+}
+
+func (s *stepCaptureConsole) Cleanup(state multistep.StateBag) {
+	if _, ok := state.GetOk("error"); !ok || s.serverID == "" {
+		return
+	}
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, provisioned := state.GetOk("provisioned")
+	if cancelled || provisioned {
+		return
+	}
+	serverID := s.serverID
+
+	ui := state.Get("ui").(packer.Ui)
+	config := state.Get("config").(*Config)
+
+	client, err := config.consoleClient(config.Region)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error initializing compute client: %s", err))
+		return
+	}
+
+	ui.Say(fmt.Sprintf("Capturing the console of server %s for troubleshooting...", serverID))
+	s.saveConsoleOutput(ui, client, serverID)
+	showRemoteConsole(ui, client, serverID)
+}
+
+// stepProvisioned runs right after the provisioners. It ends the window of stepCaptureConsole, so
+// the console log is not captured for the failures after the provisioning.
+type stepProvisioned struct{}
+
+func (s *stepProvisioned) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	state.Put("provisioned", true)
+	return multistep.ActionContinue
+}
+
+func (s *stepProvisioned) Cleanup(multistep.StateBag) {}
+
+func (s *stepCaptureConsole) saveConsoleOutput(ui packer.Ui, client ConsoleClient, serverID string) {
+	output, err := client.GetConsoleOutput(serverID, 0)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error getting the console log of server %s: %s", serverID, err))
+		return
+	}
+
+	if err := os.WriteFile(s.LogPath, []byte(output), 0600); err != nil {
+		ui.Error(fmt.Sprintf("Error saving the console log: %s", err))
+	} else {
+		ui.Message(fmt.Sprintf("Saved the console log to %s", s.LogPath))
+	}
+
+	if tail := consoleTail(output, consoleTailLines); tail != "" {
+		ui.Message(fmt.Sprintf("The last lines of the console log:\n%s", tail))
+	}
+}
+
+func showRemoteConsole(ui packer.Ui, client ConsoleClient, serverID string) {
+	request := &model.ShowServerRemoteConsoleRequest{
+		ServerId: serverID,
+		Body: &model.ShowServerRemoteConsoleRequestBody{
+			RemoteConsole: &model.GetServerRemoteConsoleOption{
+				Protocol: model.GetGetServerRemoteConsoleOptionProtocolEnum().VNC,
+				Type:     model.GetGetServerRemoteConsoleOptionTypeEnum().NOVNC,
+			},
+		},
+	}
+	response, err := client.ShowServerRemoteConsole(request)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error getting the remote console of server %s: %s", serverID, err))
+		return
+	}
+	if response.RemoteConsole != nil {
+		ui.Message(fmt.Sprintf("Remote console (until the server is deleted): %s", response.RemoteConsole.Url))
+	}
+}
+
+// consoleTail returns the last n lines of the console log.
+func consoleTail(output string, n int) string {
+	lines := strings.Split(strings.TrimRight(output, "\r\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -9,9 +9,7 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/packer"
 
-	evs "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/evs/v2"
 	evsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/evs/v2/model"
-	ims "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2"
 	imsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2/model"
 )
 
@@ -60,11 +58,11 @@ func (s *StepCheckVolumes) Cleanup(state multistep.StateBag) {
 
 func checkAndWrapDataVolumes(config *Config, dataVolumes []DataVolume, targetAZ string) ([]DataVolumeWrap, error) {
 	region := config.Region
-	evsClient, err := config.HcEvsClient(region)
+	evsClient, err := config.volumeClient(region)
 	if err != nil {
 		return nil, fmt.Errorf("error initializing EVS client: %s", err)
 	}
-	imsClient, err := config.HcImsClient(region)
+	imsClient, err := config.imageClient(region)
 	if err != nil {
 		return nil, fmt.Errorf("error initializing IMS client: %s", err)
 	}
@@ -156,7 +154,7 @@ func checkAndWrapDataVolumes(config *Config, dataVolumes []DataVolume, targetAZ
 	return dataVolumeWraps, nil
 }
 
-func checkVolumeID(evsClient *evs.EvsClient, volumeID, targetAZ string) (int, error) {
+func checkVolumeID(evsClient VolumeClient, volumeID, targetAZ string) (int, error) {
 	var volumeSize int
 
 	request := &evsmodel.ListVolumesRequest{
@@ -178,7 +176,7 @@ func checkVolumeID(evsClient *evs.EvsClient, volumeID, targetAZ string) (int, er
 	return volumeSize, nil
 }
 
-func checkSnapshotID(evsClient *evs.EvsClient, snapshotID, targetAZ string) (int, error) {
+func checkSnapshotID(evsClient VolumeClient, snapshotID, targetAZ string) (int, error) {
 	var volumeSize int
 
 	request := &evsmodel.ListSnapshotsRequest{
@@ -200,7 +198,7 @@ func checkSnapshotID(evsClient *evs.EvsClient, snapshotID, targetAZ string) (int
 	return volumeSize, nil
 }
 
-func checkDataImage(imsClient *ims.ImsClient, imageID string) (int, error) {
+func checkDataImage(imsClient ImageClient, imageID string) (int, error) {
 	var volumeSize int
 
 	request := &imsmodel.ListImagesRequest{
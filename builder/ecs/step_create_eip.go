@@ -8,7 +8,6 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/packer"
 
-	eip "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/eip/v2"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/eip/v2/model"
 )
 
@@ -17,7 +16,6 @@ type StepCreatePublicipIP struct {
 	ReuseIPs         bool
 	EIPType          string
 	EIPBandwidthSize int
-	doCleanup        bool
 }
 
 type PublicipIP struct {
@@ -32,11 +30,11 @@ func (s *StepCreatePublicipIP) Run(ctx context.Context, state multistep.StateBag
 	var accessEIP PublicipIP
 
 	// This is here in case we error out before putting accessEIP into the
-	// statebag below, because it is requested by Cleanup()
+	// statebag below, because it is requested by the server dependencies
 	state.Put("access_eip", &accessEIP)
 
 	region := config.Region
-	eipClient, err := config.HcEipClient(region)
+	eipClient, err := config.publicIPClient(region)
 	if err != nil {
 		err = fmt.Errorf("Error initializing EIP client: %s", err)
 		state.Put("error", err)
@@ -60,7 +58,6 @@ func (s *StepCreatePublicipIP) Run(ctx context.Context, state multistep.StateBag
 
 		accessEIP = *freeFloatingIP
 		ui.Message(fmt.Sprintf("Selected public IP: '%s' (%s)", accessEIP.ID, accessEIP.Address))
-		s.doCleanup = false
 	} else if s.ReuseIPs {
 		// If ReuseIPs is set to true and we have a free public IP, use it rather
 		// than creating one.
@@ -75,65 +72,33 @@ func (s *StepCreatePublicipIP) Run(ctx context.Context, state multistep.StateBag
 
 		accessEIP = *freeFloatingIP
 		ui.Message(fmt.Sprintf("Selected public IP: '%s' (%s)", accessEIP.ID, accessEIP.Address))
-		s.doCleanup = false
 	} else if s.EIPBandwidthSize != 0 {
 		if s.EIPType == "" {
 			s.EIPType = "5_bgp"
 		}
 
-		accessEIP, err = s.createEIP(ui, config, state)
+		accessEIP, err = s.createEIP(ctx, ui, resourceTrackerFrom(state), config)
 		if err != nil {
 			state.Put("error", err)
 			return multistep.ActionHalt
 		}
-		s.doCleanup = true
 	}
 
 	state.Put("access_eip", &accessEIP)
 	return multistep.ActionContinue
 }
 
-func (s *StepCreatePublicipIP) Cleanup(state multistep.StateBag) {
-	if !s.doCleanup {
-		return
-	}
-
-	ui := state.Get("ui").(packer.Ui)
-	config := state.Get("config").(*Config)
-
-	accessEIP := state.Get("access_eip").(*PublicipIP)
-	if accessEIP.ID == "" || accessEIP.Address == "" {
-		return
-	}
-
-	region := config.Region
-	eipClient, err := config.HcEipClient(region)
-	if err != nil {
-		ui.Error(fmt.Sprintf(
-			"Error deleting temporary public IP '%s' (%s)", accessEIP.ID, accessEIP.Address))
-		return
-	}
-
-	if accessEIP.ID != "" {
-		request := &model.DeletePublicipRequest{
-			PublicipId: accessEIP.ID,
-		}
-		if _, err := eipClient.DeletePublicip(request); err != nil {
-			ui.Error(fmt.Sprintf(
-				"Error deleting temporary public IP '%s' (%s)", accessEIP.ID, accessEIP.Address))
-			return
-		}
-
-		ui.Say(fmt.Sprintf("Deleted temporary public IP '%s' (%s)", accessEIP.ID, accessEIP.Address))
-	}
+func (s *StepCreatePublicipIP) Cleanup(multistep.StateBag) {
+	// the temporary public IP is deleted by stepCleanupResources
 }
 
-func (s *StepCreatePublicipIP) createEIP(ui packer.Ui, config *Config, stateBag multistep.StateBag) (PublicipIP, error) {
+func (s *StepCreatePublicipIP) createEIP(ctx context.Context, ui packer.Ui, tracker *resourceTracker,
+	config *Config) (PublicipIP, error) {
 	result := PublicipIP{}
 	ui.Say(fmt.Sprintf("Creating EIP ..."))
 
 	region := config.Region
-	eipClient, err := config.HcEipClient(region)
+	eipClient, err := config.publicIPClient(region)
 	if err != nil {
 		err = fmt.Errorf("Error initializing EIP client: %s", err)
 		ui.Error(err.Error())
@@ -175,40 +140,62 @@ func (s *StepCreatePublicipIP) createEIP(ui packer.Ui, config *Config, stateBag
 	}
 
 	eipID := *response.Publicip.Id
-	ui.Message(fmt.Sprintf("Created EIP: '%s' (%s)", eipID, *response.Publicip.PublicIpAddress))
-
-	stateConf := &StateChangeConf{
-		Pending:    []string{"PENDING"},
-		Target:     []string{"ACTIVE"},
-		Refresh:    getEIPStatus(eipClient, eipID),
-		Timeout:    5 * time.Minute,
-		Delay:      5 * time.Second,
-		MinTimeout: 3 * time.Second,
-		StateBag:   stateBag,
+	eipAddress := *response.Publicip.PublicIpAddress
+	ui.Message(fmt.Sprintf("Created EIP: '%s' (%s)", eipID, eipAddress))
+	tracker.Track(temporaryResource{
+		Kind:   "public IP",
+		ID:     eipID,
+		Name:   eipAddress,
+		Delete: deletePublicIPFunc(eipClient, eipID, eipAddress),
+		Hint: fmt.Sprintf("Release it in the EIP console of %s, or with DELETE /v1/{project_id}/publicips/%s",
+			region, eipID),
+	})
+
+	waiter := Waiter[PublicipIP]{
+		Pending:     []string{"PENDING"},
+		Target:      []string{"ACTIVE"},
+		Refresh:     getEIPStatus(eipClient, eipID),
+		Timeout:     config.Timeouts.eip,
+		Sleep:       config.waitSleep,
+		Delay:       5 * time.Second,
+		MinInterval: 3 * time.Second,
 	}
-	state, err := stateConf.WaitForState()
+	result, err = waiter.Wait(ctx)
 	if err != nil {
 		err = fmt.Errorf("Error waiting eip to be active: %s", err)
 		ui.Error(err.Error())
 		return result, err
 	}
 
-	result = state.(PublicipIP)
 	return result, nil
 }
 
-func getEIPStatus(client *eip.EipClient, eipID string) StateRefreshFunc {
-	return func() (interface{}, string, error) {
+func deletePublicIPFunc(client PublicIPClient, eipID, address string) func(context.Context, packer.Ui) error {
+	return func(_ context.Context, ui packer.Ui) error {
+		request := &model.DeletePublicipRequest{
+			PublicipId: eipID,
+		}
+		if _, err := client.DeletePublicip(request); err != nil && !isNotFoundError(err) {
+			return err
+		}
+
+		ui.Say(fmt.Sprintf("Deleted temporary public IP '%s' (%s)", eipID, address))
+		return nil
+	}
+}
+
+func getEIPStatus(client PublicIPClient, eipID string) RefreshFunc[PublicipIP] {
+	return func(context.Context) (PublicipIP, string, error) {
 		request := &model.ShowPublicipRequest{
 			PublicipId: eipID,
 		}
 		response, err := client.ShowPublicip(request)
 		if err != nil {
-			return nil, "", err
+			return PublicipIP{}, "", err
 		}
 
 		if response.Publicip == nil {
-			return nil, "", nil
+			return PublicipIP{}, "", nil
 		}
 
 		object := response.Publicip
@@ -228,7 +215,7 @@ func getEIPStatus(client *eip.EipClient, eipID string) StateRefreshFunc {
 // checkPublicipIP gets a public IP by its ID and checks if it is already
 // associated with any internal interface.
 // It returns public IP if it can be used.
-func checkPublicIP(client *eip.EipClient, id string) (*PublicipIP, error) {
+func checkPublicIP(client PublicIPClient, id string) (*PublicipIP, error) {
 	request := &model.ShowPublicipRequest{
 		PublicipId: id,
 	}
@@ -258,7 +245,7 @@ var LimitCount int32 = 50
 
 // findFreePublicipIP returns free unassociated public IP.
 // It will return first public IP if there are many.
-func findFreePublicIP(client *eip.EipClient) (*PublicipIP, error) {
+func findFreePublicIP(client PublicIPClient) (*PublicipIP, error) {
 	var freePublicipIP *PublicipIP
 
 	var marker *string
@@ -0,0 +1,66 @@
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	eipmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/eip/v2/model"
+)
+
+func testPublicIP(id, portID string) eipmodel.PublicipShowResp {
+	address := fmt.Sprintf("192.0.2.%d", len(id))
+	ip := eipmodel.PublicipShowResp{Id: &id, PublicIpAddress: &address}
+	if portID != "" {
+		ip.PortId = &portID
+	}
+	return ip
+}
+
+func TestStepCreatePublicipIP_ReuseIPs(t *testing.T) {
+	limit := LimitCount
+	LimitCount = 2
+	defer func() { LimitCount = limit }()
+
+	// the free public IP is on the second page
+	eipClient := &fakePublicIPClient{
+		PublicIPs: []eipmodel.PublicipShowResp{
+			testPublicIP("eip-1", "port-1"),
+			testPublicIP("eip-2", "port-2"),
+			testPublicIP("eip-free", ""),
+		},
+	}
+	state, _ := testStepState(t, map[string]interface{}{"eip": eipClient})
+
+	step := &StepCreatePublicipIP{ReuseIPs: true, EIPBandwidthSize: 5}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %v, err: %v", action, state.Get("error"))
+	}
+
+	accessEIP := state.Get("access_eip").(*PublicipIP)
+	if accessEIP.ID != "eip-free" {
+		t.Fatalf("expected the free public IP to be reused, but got %s", accessEIP.ID)
+	}
+	if eipClient.called("CreatePublicip") {
+		t.Fatalf("expected no public IP to be created")
+	}
+
+	// the reused public IP is never deleted
+	cleanupResources(state)
+	if eipClient.called("DeletePublicip eip-free") {
+		t.Fatalf("expected the reused public IP to be kept")
+	}
+}
+
+func TestStepCreatePublicipIP_ReuseIPsNoneFree(t *testing.T) {
+	eipClient := &fakePublicIPClient{
+		PublicIPs: []eipmodel.PublicipShowResp{testPublicIP("eip-1", "port-1")},
+	}
+	state, _ := testStepState(t, map[string]interface{}{"eip": eipClient})
+
+	step := &StepCreatePublicipIP{ReuseIPs: true}
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected the step to halt, but got %v", action)
+	}
+}
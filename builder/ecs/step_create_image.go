@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"time"
 
@@ -11,7 +12,6 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/packer"
 
 	ecsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2/model"
-	ims "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2/model"
 )
 
@@ -24,7 +24,7 @@ func (s *stepCreateImage) Run(ctx context.Context, state multistep.StateBag) mul
 	config := state.Get("config").(*Config)
 
 	region := config.Region
-	imsClient, err := config.HcImsClient(region)
+	imsClient, err := config.imageClient(region)
 	if err != nil {
 		err = fmt.Errorf("Error initializing image service client: %s", err)
 		state.Put("error", err)
@@ -49,13 +49,13 @@ func (s *stepCreateImage) Run(ctx context.Context, state multistep.StateBag) mul
 	serverID := state.Get("server_id").(string)
 	switch config.ImageType {
 	case FullImageType:
-		imageID, err = createServerWholeImage(ui, config, waitTimeout, imsClient, serverID)
+		imageID, err = createServerWholeImage(ctx, ui, config, waitTimeout, imsClient, serverID)
 	case DataImageType:
-		imageID, err = createDataDiskImage(ui, config, waitTimeout, imsClient, serverID)
+		imageID, err = createDataDiskImage(ctx, ui, config, waitTimeout, imsClient, serverID)
 	case SystemDataImageType:
-		imageID, err = createSystemDataDiskImage(ui, config, waitTimeout, imsClient, serverID)
+		imageID, err = createSystemDataDiskImage(ctx, ui, config, waitTimeout, imsClient, serverID)
 	default:
-		imageID, err = createSystemImage(ui, config, waitTimeout, imsClient, serverID)
+		imageID, err = createSystemImage(ctx, ui, config, waitTimeout, imsClient, serverID)
 	}
 
 	if err != nil {
@@ -88,11 +88,13 @@ func buildImageTag(conf *Config) []model.TagKeyValue {
 		}
 		index++
 	}
+	// the tags are sent in the same order in each build
+	sort.Slice(taglist, func(i, j int) bool { return taglist[i].Key < taglist[j].Key })
 
 	return taglist
 }
 
-func createSystemImage(_ packer.Ui, conf *Config, timeout time.Duration, client *ims.ImsClient, serverID string) (string, error) {
+func createSystemImage(ctx context.Context, ui packer.Ui, conf *Config, timeout time.Duration, client ImageClient, serverID string) (string, error) {
 	requestBody := model.CreateImageRequestBody{
 		Name:        conf.ImageName,
 		Description: &conf.ImageDescription,
@@ -119,10 +121,10 @@ func createSystemImage(_ packer.Ui, conf *Config, timeout time.Duration, client
 	if response.JobId == nil {
 		return "", fmt.Errorf("can not get the job from API response")
 	}
-	return waitImageJobSuccess(client, timeout, *response.JobId)
+	return waitImageJobSuccess(ctx, ui, client, conf, timeout, *response.JobId)
 }
 
-func createServerWholeImage(_ packer.Ui, conf *Config, timeout time.Duration, client *ims.ImsClient, serverID string) (string, error) {
+func createServerWholeImage(ctx context.Context, ui packer.Ui, conf *Config, timeout time.Duration, client ImageClient, serverID string) (string, error) {
 	requestBody := model.CreateWholeImageRequestBody{
 		Name:        conf.ImageName,
 		Description: &conf.ImageDescription,
@@ -150,7 +152,7 @@ func createServerWholeImage(_ packer.Ui, conf *Config, timeout time.Duration, cl
 	if response.JobId == nil {
 		return "", fmt.Errorf("can not get the job from API response")
 	}
-	return waitImageJobSuccess(client, timeout, *response.JobId)
+	return waitImageJobSuccess(ctx, ui, client, conf, timeout, *response.JobId)
 }
 
 type BlockDevice struct {
@@ -158,9 +160,9 @@ type BlockDevice struct {
 	DeviceName string
 }
 
-func createDataDiskImage(ui packer.Ui, conf *Config, timeout time.Duration, client *ims.ImsClient, serverID string) (string, error) {
+func createDataDiskImage(ctx context.Context, ui packer.Ui, conf *Config, timeout time.Duration, client ImageClient, serverID string) (string, error) {
 	region := conf.Region
-	ecsClient, err := conf.HcEcsClient(region)
+	ecsClient, err := conf.serverClient(region)
 	if err != nil {
 		return "", fmt.Errorf("Error initializing compute client: %s", err)
 	}
@@ -193,6 +195,15 @@ func createDataDiskImage(ui packer.Ui, conf *Config, timeout time.Duration, clie
 		return "", fmt.Errorf("no data disks attachmented to the ECS %s", serverID)
 	}
 
+	// the block devices are listed in random order, create the images in the order
+	// of the device names (vdb, vdc, ..., vdz, vdaa) so that the image IDs are stable
+	sort.SliceStable(volumes, func(i, j int) bool {
+		if len(volumes[i].DeviceName) != len(volumes[j].DeviceName) {
+			return len(volumes[i].DeviceName) < len(volumes[j].DeviceName)
+		}
+		return volumes[i].DeviceName < volumes[j].DeviceName
+	})
+
 	var allImages string
 	for _, disk := range volumes {
 		imageName := fmt.Sprintf("%s-%s", conf.ImageName, disk.DeviceName)
@@ -232,8 +243,11 @@ func createDataDiskImage(ui packer.Ui, conf *Config, timeout time.Duration, clie
 			continue
 		}
 
-		imageID, err := waitImageJobSuccess(client, timeout, *response.JobId)
+		imageID, err := waitImageJobSuccess(ctx, ui, client, conf, timeout, *response.JobId)
 		if err != nil {
+			if ctx.Err() != nil {
+				return "", err
+			}
 			ui.Message(fmt.Sprintf("Error waiting for data disk image /dev/%s: %s", disk.DeviceName, err))
 			continue
 		} else {
@@ -248,15 +262,15 @@ func createDataDiskImage(ui packer.Ui, conf *Config, timeout time.Duration, clie
 	return allImages, fmt.Errorf("all jobs are failed to create data disk image")
 }
 
-func createSystemDataDiskImage(ui packer.Ui, conf *Config, timeout time.Duration, client *ims.ImsClient, serverID string) (string, error) {
+func createSystemDataDiskImage(ctx context.Context, ui packer.Ui, conf *Config, timeout time.Duration, client ImageClient, serverID string) (string, error) {
 	ui.Message(fmt.Sprintf("creating system image ..."))
-	sysImageID, err := createSystemImage(ui, conf, timeout, client, serverID)
+	sysImageID, err := createSystemImage(ctx, ui, conf, timeout, client, serverID)
 	if err != nil {
 		return "", fmt.Errorf("failed to create system image: %s", err)
 	}
 	ui.Message(fmt.Sprintf("system image: %s", sysImageID))
 
-	dataImageID, err := createDataDiskImage(ui, conf, timeout, client, serverID)
+	dataImageID, err := createDataDiskImage(ctx, ui, conf, timeout, client, serverID)
 	if err != nil {
 		return "", fmt.Errorf("failed to create data disk image: %s", err)
 	}
@@ -264,44 +278,47 @@ func createSystemDataDiskImage(ui packer.Ui, conf *Config, timeout time.Duration
 	return fmt.Sprintf("%s;%s", sysImageID, dataImageID), nil
 }
 
-func waitImageJobSuccess(client *ims.ImsClient, timeout time.Duration, jobID string) (string, error) {
-	stateConf := &StateChangeConf{
-		Pending:      []string{"INIT", "RUNNING"},
-		Target:       []string{"SUCCESS"},
-		Refresh:      getImsJobStatus(client, jobID),
-		Timeout:      timeout,
-		Delay:        60 * time.Second,
-		PollInterval: 10 * time.Second,
-	}
-
-	result, err := stateConf.WaitForState()
-	if err != nil {
-		return "", err
-	}
-
-	jobResult := result.(*model.ShowJobResponse)
-	imageID, err := getImageIDFromJobEntities(jobResult.Entities)
+func waitImageJobSuccess(ctx context.Context, ui packer.Ui, client ImageClient, conf *Config,
+	timeout time.Duration, jobID string) (imageID string, err error) {
+	startedAt := time.Now()
+	defer func() {
+		conf.report.addImageJob(jobID, imageID, startedAt, err)
+	}()
+
+	waiter := Waiter[*model.ShowJobResponse]{
+		Pending:     []string{"INIT", "RUNNING"},
+		Target:      []string{"SUCCESS"},
+		Refresh:     getImsJobStatus(client, jobID),
+		Timeout:     timeout,
+		Sleep:       conf.waitSleep,
+		Delay:       60 * time.Second,
+		MinInterval: 5 * time.Second,
+		MaxInterval: 10 * time.Second,
+		Progress:    UiProgress(ui, fmt.Sprintf("Image job %s", jobID)),
+	}
+
+	jobResult, err := waiter.Wait(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	return imageID, nil
+	return getImageIDFromJobEntities(jobResult.Entities)
 }
 
-func getImsJobStatus(client *ims.ImsClient, jobID string) StateRefreshFunc {
-	return func() (interface{}, string, error) {
+func getImsJobStatus(client ImageClient, jobID string) RefreshFunc[*model.ShowJobResponse] {
+	return func(context.Context) (*model.ShowJobResponse, string, error) {
 		jobRequest := &model.ShowJobRequest{
 			JobId: jobID,
 		}
 		jobResponse, err := client.ShowJob(jobRequest)
 		if err != nil {
-			return nil, "", nil
+			return nil, "", fmt.Errorf("error querying the IMS job %s: %s", jobID, err)
 		}
 
 		jobStatus := jobResponse.Status.Value()
 
 		if jobStatus == "FAIL" {
-			return jobResponse, jobStatus, fmt.Errorf("failed to create image: %s", *jobResponse.FailReason)
+			return jobResponse, jobStatus, NewIMSJobError(jobResponse)
 		}
 		return jobResponse, jobStatus, nil
 	}
@@ -0,0 +1,68 @@
+package ecs
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	ecsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2/model"
+)
+
+func testBlockDevice(volumeID, device string, bootIndex int32) ecsmodel.ServerBlockDevice {
+	return ecsmodel.ServerBlockDevice{VolumeId: &volumeID, Device: &device, BootIndex: &bootIndex}
+}
+
+func TestStepCreateImage_DataDiskOrder(t *testing.T) {
+	ecsClient := &fakeServerClient{
+		// the block devices are listed in random order
+		BlockDevices: []ecsmodel.ServerBlockDevice{
+			testBlockDevice("volume-aa", "/dev/vdaa", 27),
+			testBlockDevice("volume-c", "/dev/vdc", 2),
+			testBlockDevice("volume-a", "/dev/vda", 0),
+			testBlockDevice("volume-b", "/dev/vdb", 1),
+		},
+	}
+	imsClient := &fakeImageClient{}
+	state, config := testStepState(t, map[string]interface{}{"ecs": ecsClient, "ims": imsClient})
+	config.ImageType = DataImageType
+	state.Put("server_id", "server-1")
+
+	step := &stepCreateImage{}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %v, err: %v", action, state.Get("error"))
+	}
+
+	expected := []string{
+		"CreateImage packer-test-vdb",
+		"CreateImage packer-test-vdc",
+		"CreateImage packer-test-vdaa",
+	}
+	if !reflect.DeepEqual(imsClient.calls, expected) {
+		t.Fatalf("expected the calls %v, but got %v", expected, imsClient.calls)
+	}
+	if image := state.Get("image").(string); image != "image-packer-test-vdb;image-packer-test-vdc;image-packer-test-vdaa" {
+		t.Fatalf("bad image: %s", image)
+	}
+}
+
+func TestStepCreateImage_DataDiskPartialFailure(t *testing.T) {
+	ecsClient := &fakeServerClient{
+		BlockDevices: []ecsmodel.ServerBlockDevice{
+			testBlockDevice("volume-c", "/dev/vdc", 2),
+			testBlockDevice("volume-b", "/dev/vdb", 1),
+		},
+	}
+	imsClient := &fakeImageClient{FailedImages: map[string]bool{"packer-test-vdb": true}}
+	state, config := testStepState(t, map[string]interface{}{"ecs": ecsClient, "ims": imsClient})
+	config.ImageType = DataImageType
+	state.Put("server_id", "server-1")
+
+	step := &stepCreateImage{}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %v, err: %v", action, state.Get("error"))
+	}
+	if image := state.Get("image").(string); image != "image-packer-test-vdc" {
+		t.Fatalf("bad image: %s", image)
+	}
+}
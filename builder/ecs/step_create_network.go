@@ -12,7 +12,6 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/random"
 
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/sdkerr"
-	vpc "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/vpc/v2"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/vpc/v2/model"
 )
 
@@ -42,7 +41,6 @@ type StepCreateNetwork struct {
 	VpcID          string
 	Subnets        []string
 	SecurityGroups []string
-	doCleanup      bool
 }
 
 func (s *StepCreateNetwork) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -50,7 +48,7 @@ func (s *StepCreateNetwork) Run(ctx context.Context, state multistep.StateBag) m
 	config := state.Get("config").(*Config)
 
 	region := config.Region
-	vpcClient, err := config.HcVpcClient(region)
+	vpcClient, err := config.networkClient(region)
 	if err != nil {
 		err = fmt.Errorf("Error initializing VPC client: %s", err)
 		state.Put("error", err)
@@ -87,7 +85,8 @@ func (s *StepCreateNetwork) Run(ctx context.Context, state multistep.StateBag) m
 		}
 
 		ui.Say("Creating temporary VPC...")
-		vpcID, err := s.createVPC(vpcClient, config)
+		tracker := resourceTrackerFrom(state)
+		vpcID, err := s.createVPC(ctx, vpcClient, tracker, config)
 		if err != nil {
 			state.Put("error", err)
 			ui.Error(err.Error())
@@ -99,7 +98,7 @@ func (s *StepCreateNetwork) Run(ctx context.Context, state multistep.StateBag) m
 		state.Put("vpc_id", vpcID)
 
 		ui.Say("Creating temporary subnet...")
-		subnetID, err := s.createSubnet(vpcClient, vpcID, region)
+		subnetID, err := s.createSubnet(ctx, vpcClient, tracker, vpcID, config)
 		if err != nil {
 			state.Put("error", err)
 			ui.Error(err.Error())
@@ -121,60 +120,12 @@ func (s *StepCreateNetwork) Run(ctx context.Context, state multistep.StateBag) m
 	return multistep.ActionContinue
 }
 
-func (s *StepCreateNetwork) Cleanup(state multistep.StateBag) {
-	if !s.doCleanup {
-		return
-	}
-
-	ui := state.Get("ui").(packer.Ui)
-	config := state.Get("config").(*Config)
-
-	region := config.Region
-	vpcClient, err := config.HcVpcClient(region)
-	if err != nil {
-		ui.Error(fmt.Sprintf("Error initializing VPC client: %s", err))
-		return
-	}
-
-	if len(s.Subnets) > 0 {
-		subnetID := s.Subnets[0]
-		ui.Say(fmt.Sprintf("Deleting temporary subnet: %s...", subnetID))
-		// Wait for the subnet be DELETED
-		stateConf := StateChangeConf{
-			Pending:    []string{"ACTIVE"},
-			Target:     []string{"DELETED"},
-			Refresh:    waitForSubnetDelete(vpcClient, s.VpcID, subnetID),
-			Timeout:    3 * time.Minute,
-			Delay:      3 * time.Second,
-			MinTimeout: 5 * time.Second,
-			StateBag:   state,
-		}
-
-		if _, err := stateConf.WaitForState(); err != nil {
-			ui.Error(fmt.Sprintf(
-				"Error cleaning up subnet %s. Please delete it manually: %s", subnetID, err))
-		}
-	}
-
-	ui.Say(fmt.Sprintf("Deleting temporary VPC: %s...", s.VpcID))
-	// Wait for the VPC be DELETED
-	stateConf := StateChangeConf{
-		Pending:    []string{"ACTIVE"},
-		Target:     []string{"DELETED"},
-		Refresh:    waitForVpcDelete(vpcClient, s.VpcID),
-		Timeout:    3 * time.Minute,
-		Delay:      3 * time.Second,
-		MinTimeout: 5 * time.Second,
-		StateBag:   state,
-	}
-
-	if _, err := stateConf.WaitForState(); err != nil {
-		ui.Error(fmt.Sprintf(
-			"Error cleaning up VPC %s. Please delete it manually: %s", s.VpcID, err))
-	}
+func (s *StepCreateNetwork) Cleanup(multistep.StateBag) {
+	// the temporary VPC and subnet are deleted by stepCleanupResources
 }
 
-func (s *StepCreateNetwork) createVPC(client *vpc.VpcClient, conf *Config) (string, error) {
+func (s *StepCreateNetwork) createVPC(ctx context.Context, client NetworkClient, tracker *resourceTracker,
+	conf *Config) (string, error) {
 	vpcName := fmt.Sprintf("vpc-packer-%s", random.AlphaNumLower(6))
 	vpcCIDR := "172.16.0.0/16"
 
@@ -201,21 +152,29 @@ func (s *StepCreateNetwork) createVPC(client *vpc.VpcClient, conf *Config) (stri
 		return "", fmt.Errorf("failed to obtain the VPC response")
 	}
 
-	s.doCleanup = true
 	vpcID := response.Vpc.Id
+	// track the VPC before waiting, so that it's deleted if the wait fails
+	tracker.Track(temporaryResource{
+		Kind:   "VPC",
+		ID:     vpcID,
+		Name:   vpcName,
+		Delete: deleteVPCFunc(client, vpcID, conf),
+		Hint: fmt.Sprintf("Delete it in the VPC console of %s, or with DELETE /v1/{project_id}/vpcs/%s",
+			conf.Region, vpcID),
+	})
 
 	// Wait for VPC to become available.
-	stateConf := StateChangeConf{
-		Pending:    []string{"CREATING"},
-		Target:     []string{"OK"},
-		Refresh:    getVpcStatus(client, vpcID),
-		Timeout:    3 * time.Minute,
-		Delay:      5 * time.Second,
-		MinTimeout: 3 * time.Second,
+	waiter := Waiter[*model.Vpc]{
+		Pending:     []string{"CREATING"},
+		Target:      []string{"OK"},
+		Refresh:     getVpcStatus(client, vpcID),
+		Timeout:     conf.Timeouts.network,
+		Sleep:       conf.waitSleep,
+		Delay:       5 * time.Second,
+		MinInterval: 3 * time.Second,
 	}
 
-	if _, stateErr := stateConf.WaitForState(); stateErr != nil {
-		vpcName := fmt.Sprintf("vpc-packer-%s", random.AlphaNumLower(6))
+	if _, stateErr := waiter.Wait(ctx); stateErr != nil {
 		err := fmt.Errorf("Error waiting for VPC %s(%s): %s", vpcName, vpcID, stateErr)
 		return "", err
 	}
@@ -232,7 +191,9 @@ func buildDNSList(region string) []string {
 	return []string{"8.8.8.8", "114.114.114.114"}
 }
 
-func (s *StepCreateNetwork) createSubnet(client *vpc.VpcClient, vpcID, region string) (string, error) {
+func (s *StepCreateNetwork) createSubnet(ctx context.Context, client NetworkClient, tracker *resourceTracker,
+	vpcID string, conf *Config) (string, error) {
+	region := conf.Region
 	subnetName := fmt.Sprintf("subnet-packer-%s", random.AlphaNumLower(6))
 	dnsList := buildDNSList(region)
 
@@ -259,20 +220,29 @@ func (s *StepCreateNetwork) createSubnet(client *vpc.VpcClient, vpcID, region st
 		return "", fmt.Errorf("failed to obtain the subnet response")
 	}
 
-	s.doCleanup = true
 	subnetID := response.Subnet.Id
+	tracker.Track(temporaryResource{
+		Kind:      "subnet",
+		ID:        subnetID,
+		Name:      subnetName,
+		DependsOn: []string{resourceKey("VPC", vpcID)},
+		Delete:    deleteSubnetFunc(client, vpcID, subnetID, conf),
+		Hint: fmt.Sprintf("Delete it in the VPC console of %s, or with DELETE /v1/{project_id}/vpcs/%s/subnets/%s",
+			region, vpcID, subnetID),
+	})
 
 	// Wait for subnet to become available.
-	stateConf := StateChangeConf{
-		Pending:    []string{"UNKNOWN"},
-		Target:     []string{"ACTIVE"},
-		Refresh:    getSubnetStatus(client, subnetID),
-		Timeout:    3 * time.Minute,
-		Delay:      5 * time.Second,
-		MinTimeout: 3 * time.Second,
+	waiter := Waiter[*model.Subnet]{
+		Pending:     []string{"UNKNOWN"},
+		Target:      []string{"ACTIVE"},
+		Refresh:     getSubnetStatus(client, subnetID),
+		Timeout:     conf.Timeouts.network,
+		Sleep:       conf.waitSleep,
+		Delay:       5 * time.Second,
+		MinInterval: 3 * time.Second,
 	}
 
-	if _, stateErr := stateConf.WaitForState(); stateErr != nil {
+	if _, stateErr := waiter.Wait(ctx); stateErr != nil {
 		err := fmt.Errorf("Error waiting for subnet %s(%s): %s", subnetName, subnetID, stateErr)
 		return "", err
 	}
@@ -280,8 +250,8 @@ func (s *StepCreateNetwork) createSubnet(client *vpc.VpcClient, vpcID, region st
 	return subnetID, nil
 }
 
-func getVpcStatus(client *vpc.VpcClient, vpcID string) StateRefreshFunc {
-	return func() (interface{}, string, error) {
+func getVpcStatus(client NetworkClient, vpcID string) RefreshFunc[*model.Vpc] {
+	return func(context.Context) (*model.Vpc, string, error) {
 		request := &model.ShowVpcRequest{
 			VpcId: vpcID,
 		}
@@ -300,8 +270,8 @@ func getVpcStatus(client *vpc.VpcClient, vpcID string) StateRefreshFunc {
 	}
 }
 
-func getSubnetStatus(client *vpc.VpcClient, subnetID string) StateRefreshFunc {
-	return func() (interface{}, string, error) {
+func getSubnetStatus(client NetworkClient, subnetID string) RefreshFunc[*model.Subnet] {
+	return func(context.Context) (*model.Subnet, string, error) {
 		request := &model.ShowSubnetRequest{
 			SubnetId: subnetID,
 		}
@@ -320,67 +290,99 @@ func getSubnetStatus(client *vpc.VpcClient, subnetID string) StateRefreshFunc {
 	}
 }
 
-func waitForVpcDelete(client *vpc.VpcClient, vpcID string) StateRefreshFunc {
-	return func() (interface{}, string, error) {
+func deleteVPCFunc(client NetworkClient, vpcID string, conf *Config) func(context.Context, packer.Ui) error {
+	return func(ctx context.Context, ui packer.Ui) error {
+		ui.Say(fmt.Sprintf("Deleting temporary VPC: %s...", vpcID))
+		// Wait for the VPC be DELETED
+		waiter := Waiter[string]{
+			Pending:     []string{"ACTIVE"},
+			Target:      []string{"DELETED"},
+			Refresh:     waitForVpcDelete(client, vpcID),
+			Timeout:     conf.Timeouts.network,
+			Sleep:       conf.waitSleep,
+			Delay:       3 * time.Second,
+			MinInterval: 5 * time.Second,
+		}
+		_, err := waiter.Wait(ctx)
+		return err
+	}
+}
+
+func deleteSubnetFunc(client NetworkClient, vpcID, subnetID string, conf *Config) func(context.Context, packer.Ui) error {
+	return func(ctx context.Context, ui packer.Ui) error {
+		ui.Say(fmt.Sprintf("Deleting temporary subnet: %s...", subnetID))
+		// Wait for the subnet be DELETED
+		waiter := Waiter[string]{
+			Pending:     []string{"ACTIVE"},
+			Target:      []string{"DELETED"},
+			Refresh:     waitForSubnetDelete(client, vpcID, subnetID),
+			Timeout:     conf.Timeouts.network,
+			Sleep:       conf.waitSleep,
+			Delay:       3 * time.Second,
+			MinInterval: 5 * time.Second,
+		}
+		_, err := waiter.Wait(ctx)
+		return err
+	}
+}
+
+func waitForVpcDelete(client NetworkClient, vpcID string) RefreshFunc[string] {
+	return func(context.Context) (string, string, error) {
 		request := &model.DeleteVpcRequest{
 			VpcId: vpcID,
 		}
 
-		// the API response will be nil when got an error, but the wait do allow return with nil
-		response := model.DeleteSubnetResponse{}
 		if _, err := client.DeleteVpc(request); err != nil {
 			var statusCode int
 			if responseErr, ok := err.(*sdkerr.ServiceResponseError); ok {
 				statusCode = responseErr.StatusCode
 			} else {
-				return response, "ERROR", err
+				return vpcID, "ERROR", err
 			}
 
 			switch statusCode {
 			case http.StatusNotFound:
 				log.Printf("[INFO] successfully delete VPC %s", vpcID)
-				return response, "DELETED", nil
+				return vpcID, "DELETED", nil
 			case http.StatusConflict:
 				log.Printf("[INFO] the VPC %s is still active", vpcID)
-				return response, "ACTIVE", nil
+				return vpcID, "ACTIVE", nil
 			default:
-				return response, "ACTIVE", err
+				return vpcID, "ACTIVE", err
 			}
 		}
 
-		return response, "DELETED", nil
+		return vpcID, "DELETED", nil
 	}
 }
 
-func waitForSubnetDelete(client *vpc.VpcClient, vpcID, subnetID string) StateRefreshFunc {
-	return func() (interface{}, string, error) {
+func waitForSubnetDelete(client NetworkClient, vpcID, subnetID string) RefreshFunc[string] {
+	return func(context.Context) (string, string, error) {
 		request := &model.DeleteSubnetRequest{
 			VpcId:    vpcID,
 			SubnetId: subnetID,
 		}
 
-		// the API response will be nil when got an error, but the wait do allow return with nil
-		response := model.DeleteSubnetResponse{}
 		if _, err := client.DeleteSubnet(request); err != nil {
 			var statusCode int
 			if responseErr, ok := err.(*sdkerr.ServiceResponseError); ok {
 				statusCode = responseErr.StatusCode
 			} else {
-				return response, "ERROR", err
+				return subnetID, "ERROR", err
 			}
 
 			switch statusCode {
 			case http.StatusNotFound:
 				log.Printf("[INFO] successfully delete subnet %s", subnetID)
-				return response, "DELETED", nil
+				return subnetID, "DELETED", nil
 			case http.StatusConflict:
 				log.Printf("[INFO] the subnet %s is still active", subnetID)
-				return response, "ACTIVE", nil
+				return subnetID, "ACTIVE", nil
 			default:
-				return response, "ACTIVE", err
+				return subnetID, "ACTIVE", err
 			}
 		}
 
-		return response, "DELETED", nil
+		return subnetID, "DELETED", nil
 	}
 }
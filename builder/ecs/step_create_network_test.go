@@ -0,0 +1,46 @@
+package ecs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepCreateNetwork_CleanupOnFailure(t *testing.T) {
+	vpcClient := &fakeNetworkClient{CreateSubnetErr: errors.New("subnet quota exceeded")}
+	state, _ := testStepState(t, map[string]interface{}{"vpc": vpcClient})
+
+	step := &StepCreateNetwork{}
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected the step to halt, but got %v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatalf("expected an error in the state")
+	}
+
+	cleanupResources(state)
+	if !vpcClient.called("DeleteVpc vpc-1") {
+		t.Fatalf("expected the temporary VPC to be deleted, calls: %v", vpcClient.calls)
+	}
+	if vpcClient.called("DeleteSubnet subnet-1") {
+		t.Fatalf("expected no subnet to be deleted, calls: %v", vpcClient.calls)
+	}
+}
+
+func TestStepCreateNetwork_ExistingVPC(t *testing.T) {
+	vpcClient := &fakeNetworkClient{}
+	state, _ := testStepState(t, map[string]interface{}{"vpc": vpcClient})
+
+	step := &StepCreateNetwork{VpcID: "vpc-2", Subnets: []string{"subnet-2"}}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %v, err: %v", action, state.Get("error"))
+	}
+
+	// the existing VPC is never deleted
+	cleanupResources(state)
+	if len(vpcClient.calls) != 0 {
+		t.Fatalf("unexpected calls: %v", vpcClient.calls)
+	}
+}
@@ -13,7 +13,6 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/packer"
 	"golang.org/x/crypto/ssh"
 
-	ecs "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2/model"
 )
 
@@ -50,7 +49,7 @@ func (s *StepGetPassword) Run(ctx context.Context, state multistep.StateBag) mul
 	}
 
 	region := config.Region
-	ecsClient, err := config.HcEcsClient(region)
+	ecsClient, err := config.serverClient(region)
 	if err != nil {
 		err = fmt.Errorf("Error initializing compute client: %s", err)
 		state.Put("error", err)
@@ -58,17 +57,18 @@ func (s *StepGetPassword) Run(ctx context.Context, state multistep.StateBag) mul
 	}
 
 	serverID := state.Get("server_id").(string)
-	stateConf := &StateChangeConf{
-		Pending:      []string{"PENDING"},
-		Target:       []string{"SUCCESS"},
-		Refresh:      getencryptedPassword(ecsClient, serverID),
-		Timeout:      10 * time.Minute,
-		Delay:        30 * time.Second,
-		PollInterval: 10 * time.Second,
-		StateBag:     state,
+	waiter := Waiter[string]{
+		Pending:     []string{"PENDING"},
+		Target:      []string{"SUCCESS"},
+		Refresh:     getencryptedPassword(ecsClient, serverID),
+		Timeout:     config.Timeouts.password,
+		Sleep:       config.waitSleep,
+		Delay:       30 * time.Second,
+		MinInterval: 5 * time.Second,
+		MaxInterval: 10 * time.Second,
 	}
 
-	result, err := stateConf.WaitForState()
+	encryptedPassword, err := waiter.Wait(ctx)
 	if err != nil {
 		err = fmt.Errorf("Error getting the encrypted password: %s", err)
 		state.Put("error", err)
@@ -76,7 +76,6 @@ func (s *StepGetPassword) Run(ctx context.Context, state multistep.StateBag) mul
 		return multistep.ActionHalt
 	}
 
-	encryptedPassword := result.(string)
 	password, err := decryptPassword(encryptedPassword, privateKey.(*rsa.PrivateKey))
 	if err != nil {
 		state.Put("error", err)
@@ -99,8 +98,8 @@ func (s *StepGetPassword) Run(ctx context.Context, state multistep.StateBag) mul
 
 func (s *StepGetPassword) Cleanup(multistep.StateBag) {}
 
-func getencryptedPassword(client *ecs.EcsClient, serverID string) StateRefreshFunc {
-	return func() (interface{}, string, error) {
+func getencryptedPassword(client ServerClient, serverID string) RefreshFunc[string] {
+	return func(context.Context) (string, string, error) {
 		request := &model.ShowServerPasswordRequest{
 			ServerId: serverID,
 		}
@@ -21,8 +21,6 @@ type StepKeyPair struct {
 	Debug        bool
 	Comm         *communicator.Config
 	DebugKeyPath string
-
-	doCleanup bool
 }
 
 func (s *StepKeyPair) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -60,7 +58,7 @@ func (s *StepKeyPair) Run(ctx context.Context, state multistep.StateBag) multist
 
 	config := state.Get("config").(*Config)
 	region := config.Region
-	ecsClient, err := config.HcEcsClient(region)
+	ecsClient, err := config.keypairClient(region)
 	if err != nil {
 		err = fmt.Errorf("Error initializing compute client: %s", err)
 		state.Put("error", err)
@@ -91,6 +89,13 @@ func (s *StepKeyPair) Run(ctx context.Context, state multistep.StateBag) multist
 	}
 
 	ui.Say(fmt.Sprintf("Created temporary keypair: %s", kpName))
+	resourceTrackerFrom(state).Track(temporaryResource{
+		Kind:   "keypair",
+		ID:     kpName,
+		Delete: deleteKeypairFunc(ecsClient, kpName),
+		Hint: fmt.Sprintf("Delete it in the ECS console of %s, or with DELETE /v2.1/{project_id}/os-keypairs/%s",
+			region, kpName),
+	})
 
 	privateKey := string(berToDer([]byte(response.Keypair.PrivateKey), ui))
 
@@ -120,9 +125,6 @@ func (s *StepKeyPair) Run(ctx context.Context, state multistep.StateBag) multist
 		}
 	}
 
-	// we created a temporary key, so remember to clean it up
-	s.doCleanup = true
-
 	// Set some state data for use in future steps
 	s.Comm.SSHKeyPairName = kpName
 	s.Comm.SSHPrivateKey = []byte(privateKey)
@@ -174,31 +176,19 @@ func berToDer(ber []byte, ui packer.Ui) []byte {
 	return der
 }
 
-func (s *StepKeyPair) Cleanup(state multistep.StateBag) {
-	if !s.doCleanup {
-		return
-	}
-
-	config := state.Get("config").(*Config)
-	ui := state.Get("ui").(packer.Ui)
-
-	kpName := s.Comm.SSHTemporaryKeyPairName
-	region := config.Region
-	ecsClient, err := config.HcEcsClient(region)
-	if err != nil {
-		ui.Error(fmt.Sprintf(
-			"Error cleaning up keypair %s. Please delete the key manually: %s", kpName, err))
-		return
-	}
-
-	ui.Say(fmt.Sprintf("Deleting temporary keypair: %s ...", kpName))
-	request := &model.NovaDeleteKeypairRequest{
-		KeypairName: kpName,
-	}
+func (s *StepKeyPair) Cleanup(multistep.StateBag) {
+	// the temporary keypair is deleted by stepCleanupResources
+}
 
-	_, err = ecsClient.NovaDeleteKeypair(request)
-	if err != nil {
-		ui.Error(fmt.Sprintf(
-			"Error cleaning up keypair %s. Please delete the key manually: %s", kpName, err))
+func deleteKeypairFunc(client KeypairClient, kpName string) func(context.Context, packer.Ui) error {
+	return func(_ context.Context, ui packer.Ui) error {
+		ui.Say(fmt.Sprintf("Deleting temporary keypair: %s ...", kpName))
+		request := &model.NovaDeleteKeypairRequest{
+			KeypairName: kpName,
+		}
+		if _, err := client.NovaDeleteKeypair(request); err != nil && !isNotFoundError(err) {
+			return err
+		}
+		return nil
 	}
 }
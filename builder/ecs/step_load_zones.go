@@ -8,8 +8,6 @@ import (
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/packer"
-
-	ecs "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2"
 )
 
 type StepLoadAZ struct {
@@ -21,7 +19,7 @@ func (s *StepLoadAZ) Run(ctx context.Context, state multistep.StateBag) multiste
 	ui := state.Get("ui").(packer.Ui)
 
 	region := config.Region
-	client, err := config.HcEcsClient(region)
+	client, err := config.serverClient(region)
 	if err != nil {
 		err = fmt.Errorf("Error initializing compute client: %s", err)
 		state.Put("error", err)
@@ -68,7 +66,7 @@ func (s *StepLoadAZ) Run(ctx context.Context, state multistep.StateBag) multiste
 func (s *StepLoadAZ) Cleanup(state multistep.StateBag) {
 }
 
-func listZones(client *ecs.EcsClient) ([]string, error) {
+func listZones(client ServerClient) ([]string, error) {
 	response, err := client.NovaListAvailabilityZones(nil)
 	if err != nil {
 		return nil, fmt.Errorf("Error getting zones, err=%s", err)
@@ -0,0 +1,497 @@
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/core/sdkerr"
+	cbrmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/cbr/v1/model"
+	ecsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2/model"
+	eipmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/eip/v2/model"
+	evsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/evs/v2/model"
+	imsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2/model"
+	kmsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/kms/v2/model"
+	vpcmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/vpc/v2/model"
+)
+
+// kmsKeyStates are the states of the KMS keys which can not be used, the enabled state is "2".
+var kmsKeyStates = map[string]string{
+	"1": "pending activation",
+	"3": "disabled",
+	"4": "pending deletion",
+	"5": "pending import",
+}
+
+// stepPreflight checks the quotas and the resources referenced by the configuration before any
+// resource is created, and reports all the problems found at once. The checks which can not be done,
+// e.g. the query is denied by the policies, are reported as warnings and don't fail the build.
+type stepPreflight struct {
+	// ValidateOnly stops the build after the checks
+	ValidateOnly bool
+}
+
+// preflight collects the problems and warnings of the checks.
+type preflight struct {
+	config   *Config
+	problems []string
+	warnings []string
+}
+
+func (s *stepPreflight) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+	config := state.Get("config").(*Config)
+
+	ui.Say("Running the preflight checks...")
+	p := &preflight{config: config}
+
+	// the sizes of the existing snapshots and images are counted in the volume quota
+	newVolumeSizes := p.checkDataVolumes()
+	p.checkServerQuotas()
+	p.checkVolumeQuotas(newVolumeSizes)
+	p.checkNetwork()
+	p.checkPublicIP()
+	p.checkKeys()
+	p.checkVault()
+	if !config.SkipCreateImage {
+		p.checkImageQuota()
+		p.checkImageNameConflict()
+	}
+
+	for _, warning := range p.warnings {
+		ui.Message(fmt.Sprintf("Warning: %s", warning))
+	}
+	if len(p.problems) > 0 {
+		err := fmt.Errorf("the preflight checks found %d problem(s):\n  - %s",
+			len(p.problems), strings.Join(p.problems, "\n  - "))
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Message("The preflight checks passed")
+	if s.ValidateOnly {
+		ui.Say("Validation passed, stopping the build since validate_only is set. No artifact is returned")
+		return multistep.ActionHalt
+	}
+	return multistep.ActionContinue
+}
+
+func (s *stepPreflight) Cleanup(multistep.StateBag) {
+}
+
+func (p *preflight) problem(format string, args ...interface{}) {
+	p.problems = append(p.problems, fmt.Sprintf(format, args...))
+}
+
+func (p *preflight) warning(format string, args ...interface{}) {
+	p.warnings = append(p.warnings, fmt.Sprintf(format, args...))
+}
+
+// queryFailed reports the error of querying the resource, it's a problem if the resource does not
+// exist, otherwise the resource can not be checked.
+func (p *preflight) queryFailed(resource string, err error) {
+	if isNotFoundError(err) {
+		p.problem("the %s does not exist", resource)
+		return
+	}
+	p.warning("can not check the %s: %s", resource, err)
+}
+
+// checkQuota reports a problem if the remaining quota is less than the required amount, a negative
+// limit means the quota is unlimited.
+func (p *preflight) checkQuota(quota string, limit, used, required int) {
+	if required <= 0 || limit < 0 {
+		return
+	}
+	if limit-used < required {
+		p.problem("the %s quota is insufficient, %d required but %d of %d used", quota, required, used, limit)
+	}
+}
+
+func (p *preflight) checkServerQuotas() {
+	client, err := p.config.serverQuotaClient(p.config.Region)
+	if err != nil {
+		p.warning("can not check the ECS quotas: %s", err)
+		return
+	}
+
+	request := &ecsmodel.ListFlavorsRequest{}
+	if p.config.AvailabilityZone != "" {
+		request.AvailabilityZone = &p.config.AvailabilityZone
+	}
+	var flavor *ecsmodel.Flavor
+	if response, err := client.ListFlavors(request); err != nil {
+		p.warning("can not check the flavor %s: %s", p.config.Flavor, err)
+	} else if response.Flavors != nil {
+		for i, item := range *response.Flavors {
+			if item.Id == p.config.Flavor {
+				flavor = &(*response.Flavors)[i]
+				break
+			}
+		}
+		if flavor == nil {
+			p.problem("the flavor %s is not available", p.config.Flavor)
+		}
+	}
+
+	response, err := client.ShowServerLimits(&ecsmodel.ShowServerLimitsRequest{})
+	if err != nil {
+		p.warning("can not query the ECS quotas: %s", err)
+		return
+	}
+	if response.Absolute == nil {
+		return
+	}
+
+	limits := response.Absolute
+	p.checkQuota("ECS instance", int(limits.MaxTotalInstances), int(limits.TotalInstancesUsed), 1)
+	if flavor != nil {
+		vcpus, _ := strconv.Atoi(flavor.Vcpus)
+		p.checkQuota("ECS vCPU", int(limits.MaxTotalCores), int(limits.TotalCoresUsed), vcpus)
+		p.checkQuota("ECS memory (MB)", int(limits.MaxTotalRAMSize), int(limits.TotalRAMUsed), int(flavor.Ram))
+	}
+}
+
+// checkDataVolumes checks the existing volumes, snapshots and data disk images in data_disks, and
+// returns the sizes of the new volumes.
+func (p *preflight) checkDataVolumes() []int {
+	var sizes []int
+	if len(p.config.DataVolumes) == 0 {
+		return sizes
+	}
+
+	evsClient, err := p.config.volumeClient(p.config.Region)
+	if err != nil {
+		p.warning("can not check the data disks: %s", err)
+		return sizes
+	}
+	imsClient, err := p.config.imageClient(p.config.Region)
+	if err != nil {
+		p.warning("can not check the data disks: %s", err)
+		return sizes
+	}
+
+	var az *string
+	if p.config.AvailabilityZone != "" {
+		az = &p.config.AvailabilityZone
+	}
+	for i, dataVolume := range p.config.DataVolumes {
+		switch {
+		case dataVolume.VolumeId != "":
+			p.checkVolume(evsClient, i, dataVolume.VolumeId)
+		case dataVolume.SnapshotId != "":
+			response, err := evsClient.ListSnapshots(&evsmodel.ListSnapshotsRequest{Id: &dataVolume.SnapshotId, AvailabilityZone: az})
+			if err != nil {
+				p.queryFailed(fmt.Sprintf("snapshot %s of data_disks[%d]", dataVolume.SnapshotId, i), err)
+			} else if response.Snapshots == nil || len(*response.Snapshots) == 0 {
+				p.problem("data_disks[%d]: can not find the snapshot %s%s", i, dataVolume.SnapshotId, inZone(az))
+			} else {
+				sizes = append(sizes, int((*response.Snapshots)[0].Size))
+			}
+		case dataVolume.DataImageId != "":
+			if size, err := checkDataImage(imsClient, dataVolume.DataImageId); err != nil {
+				if _, ok := err.(*sdkerr.ServiceResponseError); ok {
+					p.queryFailed(fmt.Sprintf("image %s of data_disks[%d]", dataVolume.DataImageId, i), err)
+				} else {
+					p.problem("data_disks[%d]: %s", i, err)
+				}
+			} else {
+				sizes = append(sizes, size)
+			}
+		default:
+			sizes = append(sizes, dataVolume.Size)
+		}
+	}
+	return sizes
+}
+
+// checkVolume checks that the existing volume is available to be attached.
+func (p *preflight) checkVolume(client VolumeClient, index int, volumeID string) {
+	response, err := client.ListVolumes(&evsmodel.ListVolumesRequest{Id: &volumeID})
+	if err != nil {
+		p.queryFailed(fmt.Sprintf("volume %s of data_disks[%d]", volumeID, index), err)
+		return
+	}
+	if response.Volumes == nil || len(*response.Volumes) == 0 {
+		p.problem("data_disks[%d]: can not find the volume %s", index, volumeID)
+		return
+	}
+
+	volume := (*response.Volumes)[0]
+	if volume.Status != "available" {
+		p.problem("data_disks[%d]: the volume %s is %s, it must be available", index, volumeID, volume.Status)
+	}
+	if az := p.config.AvailabilityZone; az != "" && volume.AvailabilityZone != az {
+		p.problem("data_disks[%d]: the volume %s is in %s, not in the availability_zone %s",
+			index, volumeID, volume.AvailabilityZone, az)
+	}
+}
+
+func inZone(az *string) string {
+	if az == nil {
+		return ""
+	}
+	return " in " + *az
+}
+
+func (p *preflight) checkVolumeQuotas(newVolumeSizes []int) {
+	client, err := p.config.volumeQuotaClient(p.config.Region)
+	if err != nil {
+		p.warning("can not check the EVS quotas: %s", err)
+		return
+	}
+
+	response, err := client.CinderListQuotas(&evsmodel.CinderListQuotasRequest{
+		TargetProjectId: p.config.ProjectID,
+		Usage:           evsmodel.GetCinderListQuotasRequestUsageEnum().TRUE,
+	})
+	if err != nil {
+		p.warning("can not query the EVS quotas: %s", err)
+		return
+	}
+	if response.QuotaSet == nil {
+		return
+	}
+
+	// the size of the system disk is unknown until the source image is resolved if it's not specified
+	gigabytes := p.config.VolumeSize
+	for _, size := range newVolumeSizes {
+		gigabytes += size
+	}
+
+	quotas := response.QuotaSet
+	if volumes := quotas.Volumes; volumes != nil {
+		p.checkQuota("EVS volume", int(volumes.Limit), int(volumes.InUse+volumes.Reserved), 1+len(newVolumeSizes))
+	}
+	if capacity := quotas.Gigabytes; capacity != nil {
+		p.checkQuota("EVS capacity (GB)", int(capacity.Limit), int(capacity.InUse+capacity.Reserved), gigabytes)
+	}
+}
+
+func (p *preflight) checkNetwork() {
+	if p.config.VpcID == "" {
+		client, err := p.config.networkQuotaClient(p.config.Region)
+		if err != nil {
+			p.warning("can not check the VPC quotas: %s", err)
+			return
+		}
+		response, err := client.ShowQuota(&vpcmodel.ShowQuotaRequest{})
+		if err != nil {
+			p.warning("can not query the VPC quotas: %s", err)
+			return
+		}
+		if response.Quotas == nil {
+			return
+		}
+		for _, quota := range response.Quotas.Resources {
+			switch quota.Type.Value() {
+			case "vpc":
+				p.checkQuota("VPC", int(quota.Quota), int(quota.Used), 1)
+			case "subnet":
+				p.checkQuota("subnet", int(quota.Quota), int(quota.Used), 1)
+			}
+		}
+		return
+	}
+
+	client, err := p.config.networkClient(p.config.Region)
+	if err != nil {
+		p.warning("can not check the VPC: %s", err)
+		return
+	}
+	if _, err := client.ShowVpc(&vpcmodel.ShowVpcRequest{VpcId: p.config.VpcID}); err != nil {
+		p.queryFailed(fmt.Sprintf("VPC %s", p.config.VpcID), err)
+	}
+	for _, subnetID := range p.config.Subnets {
+		response, err := client.ShowSubnet(&vpcmodel.ShowSubnetRequest{SubnetId: subnetID})
+		if err != nil {
+			p.queryFailed(fmt.Sprintf("subnet %s", subnetID), err)
+		} else if response.Subnet != nil && response.Subnet.VpcId != p.config.VpcID {
+			p.problem("the subnet %s belongs to the VPC %s, not the vpc_id %s", subnetID, response.Subnet.VpcId, p.config.VpcID)
+		}
+	}
+	for _, secgroupID := range p.config.SecurityGroups {
+		// the default security group is skipped as StepRunSourceServer does
+		if strings.Contains(secgroupID, "default") {
+			continue
+		}
+		if _, err := client.ShowSecurityGroup(&vpcmodel.ShowSecurityGroupRequest{SecurityGroupId: secgroupID}); err != nil {
+			p.queryFailed(fmt.Sprintf("security group %s", secgroupID), err)
+		}
+	}
+}
+
+func (p *preflight) checkPublicIP() {
+	config := p.config
+	if config.AssociatePublicIpAddress != nil && !*config.AssociatePublicIpAddress {
+		return
+	}
+
+	if config.FloatingIP != "" {
+		client, err := config.publicIPClient(config.Region)
+		if err != nil {
+			p.warning("can not check the public IP %s: %s", config.FloatingIP, err)
+			return
+		}
+		if _, err := checkPublicIP(client, config.FloatingIP); err != nil {
+			if _, ok := err.(*sdkerr.ServiceResponseError); ok {
+				p.queryFailed(fmt.Sprintf("public IP %s", config.FloatingIP), err)
+			} else {
+				p.problem("%s", err)
+			}
+		}
+		return
+	}
+
+	// a free public IP is reused if there's one, so the quota is not checked
+	if config.ReuseIPs || config.EIPBandwidthSize == 0 {
+		return
+	}
+	client, err := config.publicIPQuotaClient(config.Region)
+	if err != nil {
+		p.warning("can not check the EIP quotas: %s", err)
+		return
+	}
+	publicIPType := "publicIp"
+	response, err := client.ListQuotas(&eipmodel.ListQuotasRequest{Type: &publicIPType})
+	if err != nil {
+		p.warning("can not query the EIP quotas: %s", err)
+		return
+	}
+	if response.Quotas == nil {
+		return
+	}
+	for _, quota := range response.Quotas.Resources {
+		if quota.Type != nil && *quota.Type == publicIPType && quota.Quota != nil && quota.Used != nil {
+			p.checkQuota("EIP", int(*quota.Quota), int(*quota.Used), 1)
+		}
+	}
+}
+
+// checkKeys checks that the KMS keys to encrypt the volumes are enabled.
+func (p *preflight) checkKeys() {
+	var keyIDs []string
+	for _, keyID := range append([]string{p.config.KmsKeyID}, dataVolumeKeys(p.config.DataVolumes)...) {
+		if keyID != "" && !isStringInSlice(keyID, keyIDs) {
+			keyIDs = append(keyIDs, keyID)
+		}
+	}
+	if len(keyIDs) == 0 {
+		return
+	}
+
+	client, err := p.config.keyClient(p.config.Region)
+	if err != nil {
+		p.warning("can not check the KMS keys: %s", err)
+		return
+	}
+	for _, keyID := range keyIDs {
+		response, err := client.ListKeyDetail(&kmsmodel.ListKeyDetailRequest{
+			Body: &kmsmodel.OperateKeyRequestBody{KeyId: keyID},
+		})
+		if err != nil {
+			p.queryFailed(fmt.Sprintf("KMS key %s", keyID), err)
+			continue
+		}
+		if response.KeyInfo == nil || response.KeyInfo.KeyState == nil {
+			continue
+		}
+		if state, ok := kmsKeyStates[*response.KeyInfo.KeyState]; ok {
+			p.problem("the KMS key %s is %s, it must be enabled", keyID, state)
+		}
+	}
+}
+
+func dataVolumeKeys(dataVolumes []DataVolume) []string {
+	keyIDs := make([]string, 0, len(dataVolumes))
+	for _, dataVolume := range dataVolumes {
+		keyIDs = append(keyIDs, dataVolume.KmsKeyID)
+	}
+	return keyIDs
+}
+
+func (p *preflight) checkVault() {
+	if p.config.Vault == "" {
+		return
+	}
+
+	client, err := p.config.vaultClient(p.config.Region)
+	if err != nil {
+		p.warning("can not check the vault %s: %s", p.config.Vault, err)
+		return
+	}
+	response, err := client.ShowVault(&cbrmodel.ShowVaultRequest{VaultId: p.config.Vault})
+	if err != nil {
+		p.queryFailed(fmt.Sprintf("vault %s", p.config.Vault), err)
+		return
+	}
+	if response.Vault == nil || response.Vault.Billing == nil {
+		return
+	}
+
+	billing := response.Vault.Billing
+	if status := billing.Status.Value(); status != "" && status != "available" {
+		p.problem("the vault %s is %s, it must be available", p.config.Vault, status)
+	}
+	if billing.ObjectType != nil && billing.ObjectType.Value() != "server" {
+		p.problem("the vault %s is a %s backup vault, it must be a server backup vault",
+			p.config.Vault, billing.ObjectType.Value())
+	}
+}
+
+// checkImageQuota checks the quota of the images to be created.
+func (p *preflight) checkImageQuota() {
+	images := 1
+	switch p.config.ImageType {
+	case DataImageType:
+		images = len(p.config.DataVolumes)
+	case SystemDataImageType:
+		images = 1 + len(p.config.DataVolumes)
+	}
+
+	client, err := p.config.imageQuotaClient(p.config.Region)
+	if err != nil {
+		p.warning("can not check the IMS quotas: %s", err)
+		return
+	}
+	response, err := client.ShowImageQuota(&imsmodel.ShowImageQuotaRequest{})
+	if err != nil {
+		p.warning("can not query the IMS quotas: %s", err)
+		return
+	}
+	if response.Quotas == nil {
+		return
+	}
+	for _, quota := range response.Quotas.Resources {
+		if quota.Type == "image" {
+			p.checkQuota("image", int(quota.Quota), int(quota.Used), images)
+		}
+	}
+}
+
+// checkImageNameConflict reports a problem if a private image with the same name as one of the
+// images to be created exists when image_name_conflict is "fail".
+func (p *preflight) checkImageNameConflict() {
+	if p.config.ImageNameConflict != ImageNameConflictFail {
+		return
+	}
+
+	client, err := p.config.imageClient(p.config.Region)
+	if err != nil {
+		p.warning("can not check the existing images named %s: %s", p.config.ImageName, err)
+		return
+	}
+	images, err := listConflictingImages(client, p.config, nil)
+	if err != nil {
+		p.warning("can not query the existing images named %s: %s", p.config.ImageName, err)
+		return
+	}
+	for _, image := range images {
+		p.problem("the private image %s (%s) already exists", image.Name, image.Id)
+	}
+}
@@ -0,0 +1,90 @@
+package ecs
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/huaweicloud/packer-builder-huaweicloud/internal/fakecloud"
+)
+
+func TestBuilder_Run_FakeCloudPreflightProblems(t *testing.T) {
+	cloud := fakecloud.New(t)
+	cloud.Quotas[fakecloud.QuotaInstances] = 0
+	cloud.Quotas[fakecloud.QuotaPublicIPs] = 0
+	vpcID, subnetID := cloud.AddNetwork()
+	keyID := cloud.AddKey(fakecloud.Key{State: "3"})
+	volumeID := cloud.AddVolume(fakecloud.Volume{Size: 10, ServerID: "server-in-use"})
+	b := testFakeCloudBuilder(t, cloud, map[string]interface{}{
+		"vpc_id":          vpcID,
+		"subnets":         []string{subnetID},
+		"security_groups": []string{"secgroup-missing", "default"},
+		"kms_key_id":      keyID,
+		"data_disks":      []map[string]interface{}{{"volume_id": volumeID}},
+	})
+
+	_, err := b.Run(context.Background(), packer.TestUi(t), &packer.MockHook{})
+	if err == nil {
+		t.Fatal("the build should fail")
+	}
+	for _, expected := range []string{
+		"5 problem(s)",
+		"the ECS instance quota is insufficient, 1 required but 0 of 0 used",
+		"the EIP quota is insufficient",
+		"the security group secgroup-missing does not exist",
+		"the KMS key " + keyID + " is disabled",
+		"the volume " + volumeID + " is in-use",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in the error, got:\n%s", expected, err)
+		}
+	}
+
+	// the build stops before creating any resource
+	if n := cloud.CountRequests("ecs", http.MethodPost, ""); n > 0 {
+		t.Fatalf("expected no ECS resource to be created, got %d requests", n)
+	}
+	if leftovers := cloud.Leftovers(); len(leftovers) > 0 {
+		t.Fatalf("the temporary resources are not deleted: %v", leftovers)
+	}
+}
+
+func TestBuilder_Run_FakeCloudValidateOnly(t *testing.T) {
+	cloud := fakecloud.New(t)
+	// the quotas which can not be queried don't fail the checks
+	cloud.AddRule(fakecloud.Rule{
+		Service: "ecs",
+		Method:  http.MethodGet,
+		Path:    `/cloudservers/limits$`,
+		Status:  http.StatusForbidden,
+		Code:    "Ecs.0002",
+		Message: "Policy doesn't allow ecs:cloudServers:showServerLimits to be performed.",
+	})
+	b := testFakeCloudBuilder(t, cloud, map[string]interface{}{"validate_only": true})
+
+	output := new(bytes.Buffer)
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: output, ErrorWriter: output}
+	artifact, err := b.Run(context.Background(), ui, &packer.MockHook{})
+	if err != nil || artifact != nil {
+		t.Fatalf("expected no artifact and no error, got %v, %v", artifact, err)
+	}
+	for _, expected := range []string{
+		"Warning: can not query the ECS quotas",
+		"The preflight checks passed",
+		"Validation passed, stopping the build since validate_only is set. No artifact is returned",
+	} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("expected %q in the output, got:\n%s", expected, output.String())
+		}
+	}
+
+	for _, service := range []string{"ecs", "vpc", "eip", "evs", "ims"} {
+		if n := cloud.CountRequests(service, http.MethodPost, ""); n > 0 {
+			t.Fatalf("expected no %s resource to be created, got %d requests", service, n)
+		}
+	}
+}
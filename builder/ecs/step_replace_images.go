@@ -0,0 +1,179 @@
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2/model"
+)
+
+const (
+	// the timeout to remove the members of an existing image before deleting it
+	removeImageMembersTimeout = 10 * time.Minute
+	// the number of images in a page when listing the existing images
+	imagePageSize = 100
+)
+
+// stepReplaceImages deletes the existing private images with the same name as the new images when
+// image_name_conflict is "replace", it runs after the new images are active.
+type stepReplaceImages struct{}
+
+func (s *stepReplaceImages) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+	config := state.Get("config").(*Config)
+
+	if config.ImageNameConflict != ImageNameConflictReplace {
+		return multistep.ActionContinue
+	}
+
+	imsClient, err := config.imageClient(config.Region)
+	if err != nil {
+		err = fmt.Errorf("Error initializing image service client: %s", err)
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	newImages := strings.Split(state.Get("image").(string), ";")
+	images, err := listConflictingImages(imsClient, config, newImages)
+	if err != nil {
+		ui.Message(fmt.Sprintf("WARN: failed to query the existing images named %s: %s", config.ImageName, err))
+		ui.Message("WARN: please delete the existing images manually!\n")
+		return multistep.ActionContinue
+	}
+	if len(images) == 0 {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Replacing %d existing image(s) named %s ...", len(images), config.ImageName))
+	for _, image := range images {
+		if err := deleteImageWithMembers(ctx, imsClient, config, image.Id); err != nil {
+			if ctx.Err() != nil {
+				state.Put("error", err)
+				return multistep.ActionHalt
+			}
+			// the new images are created, keep them even if the existing images can not be deleted
+			ui.Message(fmt.Sprintf("WARN: failed to delete the existing image %s (%s): %s", image.Name, image.Id, err))
+			ui.Message("WARN: please delete the image manually!\n")
+			continue
+		}
+		ui.Message(fmt.Sprintf("Deleted the existing image %s (%s)", image.Name, image.Id))
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepReplaceImages) Cleanup(multistep.StateBag) {
+	// No cleanup...
+}
+
+// listConflictingImages lists the private images except the excluded ones, which have the same
+// name as one of the images to be created, that is the image_name for the system and full-ECS
+// images, and "<image_name>-<device>" for the data disk images.
+func listConflictingImages(client ImageClient, config *Config, exclude []string) ([]model.ImageInfo, error) {
+	imageType := model.GetListImagesRequestImagetypeEnum().PRIVATE
+	limit := int32(imagePageSize)
+	request := &model.ListImagesRequest{
+		Imagetype: &imageType,
+		Limit:     &limit,
+	}
+	// the data disk images can not be filtered by the name, all the private images are listed
+	if config.ImageType != DataImageType && config.ImageType != SystemDataImageType {
+		request.Name = &config.ImageName
+	}
+
+	excluded := make(map[string]bool, len(exclude))
+	for _, id := range exclude {
+		excluded[id] = true
+	}
+	dataImageName := regexp.MustCompile("^" + regexp.QuoteMeta(config.ImageName) + "-(vd|sd|xvd)[a-z]+$")
+	var images []model.ImageInfo
+	for {
+		response, err := client.ListImages(request)
+		if err != nil {
+			return nil, err
+		}
+		if response.Images == nil || len(*response.Images) == 0 {
+			break
+		}
+
+		page := *response.Images
+		for _, image := range page {
+			if excluded[image.Id] {
+				continue
+			}
+
+			var conflicted bool
+			switch config.ImageType {
+			case DataImageType:
+				conflicted = dataImageName.MatchString(image.Name)
+			case SystemDataImageType:
+				conflicted = image.Name == config.ImageName || dataImageName.MatchString(image.Name)
+			default:
+				conflicted = image.Name == config.ImageName
+			}
+			if conflicted {
+				images = append(images, image)
+			}
+		}
+		if len(page) < imagePageSize {
+			break
+		}
+		request.Marker = &page[len(page)-1].Id
+	}
+	return images, nil
+}
+
+// deleteImageWithMembers removes the members of the image and then deletes it.
+func deleteImageWithMembers(ctx context.Context, client ImageClient, config *Config, imageID string) error {
+	response, err := client.GlanceListImageMembers(&model.GlanceListImageMembersRequest{ImageId: imageID})
+	if err != nil {
+		return fmt.Errorf("error listing the members: %s", err)
+	}
+
+	var members []string
+	if response.Members != nil {
+		for _, member := range *response.Members {
+			members = append(members, member.MemberId)
+		}
+	}
+	if len(members) > 0 {
+		log.Printf("[DEBUG] Removing the members %v of the image %s", members, imageID)
+		request := &model.BatchDeleteMembersRequest{
+			Body: &model.BatchAddMembersRequestBody{
+				Images:   []string{imageID},
+				Projects: members,
+			},
+		}
+		deleteResponse, err := client.BatchDeleteMembers(request)
+		if err != nil {
+			return fmt.Errorf("error removing the members: %s", err)
+		}
+		if deleteResponse.JobId != nil {
+			waiter := Waiter[*model.ShowJobResponse]{
+				Pending:     []string{"INIT", "RUNNING"},
+				Target:      []string{"SUCCESS"},
+				Refresh:     getImsJobStatus(client, *deleteResponse.JobId),
+				Timeout:     removeImageMembersTimeout,
+				Sleep:       config.waitSleep,
+				Delay:       5 * time.Second,
+				MinInterval: 5 * time.Second,
+				MaxInterval: 10 * time.Second,
+			}
+			if _, err := waiter.Wait(ctx); err != nil {
+				return fmt.Errorf("error removing the members: %s", err)
+			}
+		}
+	}
+
+	if _, err := client.GlanceDeleteImage(&model.GlanceDeleteImageRequest{ImageId: imageID}); err != nil && !isNotFoundError(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+	imsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2/model"
+
+	"github.com/huaweicloud/packer-builder-huaweicloud/internal/fakecloud"
+)
+
+func TestBuilder_Run_FakeCloudImageNameConflictReplace(t *testing.T) {
+	cloud := fakecloud.New(t)
+	oldImage := cloud.AddImage(fakecloud.Image{
+		Name:      "packer-fake",
+		ImageType: "private",
+		Members:   []string{"0970dd7a1300f5672ff2c003c60ae116"},
+	})
+	oldDataImage := cloud.AddImage(fakecloud.Image{Name: "packer-fake-vdb", ImageType: "private"})
+	otherImage := cloud.AddImage(fakecloud.Image{Name: "packer-fake-prod", ImageType: "private"})
+	b := testFakeCloudBuilder(t, cloud, map[string]interface{}{
+		"data_disks":          []map[string]interface{}{{"volume_size": 20, "volume_type": "SSD"}},
+		"image_type":          SystemDataImageType,
+		"image_name_conflict": ImageNameConflictReplace,
+	})
+
+	artifact, err := b.Run(context.Background(), packer.TestUi(t), &packer.MockHook{})
+	if err != nil {
+		t.Fatalf("build failed: %s", err)
+	}
+
+	for _, id := range strings.Split(artifact.Id(), ";") {
+		if _, ok := cloud.Image(id); !ok {
+			t.Fatalf("the new image %s does not exist", id)
+		}
+	}
+	for _, id := range []string{oldImage, oldDataImage} {
+		if _, ok := cloud.Image(id); ok {
+			t.Fatalf("the existing image %s should be deleted", id)
+		}
+	}
+	if _, ok := cloud.Image(otherImage); !ok {
+		t.Fatalf("the image %s with another name should be kept", otherImage)
+	}
+	// the members are removed before the image is deleted
+	if n := cloud.CountRequests("ims", http.MethodDelete, `/v1/cloudimages/members$`); n != 1 {
+		t.Fatalf("expected the members to be removed once, got %d requests", n)
+	}
+}
+
+func TestBuilder_Run_FakeCloudImageNameConflictFail(t *testing.T) {
+	cloud := fakecloud.New(t)
+	oldImage := cloud.AddImage(fakecloud.Image{Name: "packer-fake", ImageType: "private"})
+	// the public images with the same name don't conflict
+	cloud.AddImage(fakecloud.Image{Name: "packer-fake"})
+	b := testFakeCloudBuilder(t, cloud, map[string]interface{}{
+		"image_name_conflict": ImageNameConflictFail,
+	})
+
+	_, err := b.Run(context.Background(), packer.TestUi(t), &packer.MockHook{})
+	if err == nil {
+		t.Fatal("the build should fail")
+	}
+	expected := "1 problem(s):\n  - the private image packer-fake (" + oldImage + ") already exists"
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected %q in the error, got:\n%s", expected, err)
+	}
+	if n := cloud.CountRequests("ecs", http.MethodPost, ""); n > 0 {
+		t.Fatalf("expected no ECS resource to be created, got %d requests", n)
+	}
+}
+
+func TestListConflictingImages_Paging(t *testing.T) {
+	// all the private images are listed for the data disk images
+	cases := []struct {
+		imageType string
+		conflict  string
+	}{
+		{DataImageType, "packer-test-vdb"},
+		{SystemDataImageType, "packer-test-vdc"},
+	}
+
+	for _, tc := range cases {
+		client := &fakeImageClient{}
+		// the conflicting image is on the second page
+		for i := 0; i < imagePageSize+10; i++ {
+			name := fmt.Sprintf("other-%d", i)
+			if i == imagePageSize+5 {
+				name = tc.conflict
+			}
+			client.Images = append(client.Images, imsmodel.ImageInfo{Id: fmt.Sprintf("image-%04d", i), Name: name})
+		}
+		config := &Config{}
+		config.ImageName = "packer-test"
+		config.ImageType = tc.imageType
+
+		images, err := listConflictingImages(client, config, nil)
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.conflict, err)
+		}
+		if len(images) != 1 || images[0].Name != tc.conflict {
+			t.Fatalf("%s: expected the conflicting image on the second page, got %v", tc.conflict, images)
+		}
+		if !client.called(fmt.Sprintf("ListImages image-%04d", imagePageSize-1)) {
+			t.Fatalf("%s: expected the second page to be listed after the last image, got %v", tc.conflict, client.calls)
+		}
+	}
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/packer"
 
-	ecs "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2"
 	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2/model"
 )
 
@@ -27,7 +26,6 @@ type StepRunSourceServer struct {
 	UserData         string
 	UserDataFile     string
 	InstanceMetadata map[string]string
-	serverID         string
 }
 
 func (s *StepRunSourceServer) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -37,7 +35,7 @@ func (s *StepRunSourceServer) Run(ctx context.Context, state multistep.StateBag)
 	sourceImage := state.Get("source_image").(string)
 
 	region := config.Region
-	ecsClient, err := config.HcEcsClient(region)
+	ecsClient, err := config.serverClient(region)
 	if err != nil {
 		err = fmt.Errorf("Error initializing compute client: %s", err)
 		state.Put("error", err)
@@ -129,7 +127,7 @@ func (s *StepRunSourceServer) Run(ctx context.Context, state multistep.StateBag)
 		jobID = *response.JobId
 	}
 
-	serverJob, err := WaitForServerJobSuccess(ui, state, ecsClient, jobID)
+	serverJob, err := WaitForServerJobSuccess(ctx, ui, state, ecsClient, jobID)
 	if err != nil {
 		state.Put("error", err)
 		return multistep.ActionHalt
@@ -142,24 +140,50 @@ func (s *StepRunSourceServer) Run(ctx context.Context, state multistep.StateBag)
 		}
 	}
 
+	ui.Message(fmt.Sprintf("Server ID: %s", serverID))
+	// track the server before any further call, so that it's deleted if the step fails
+	resourceTrackerFrom(state).Track(temporaryResource{
+		Kind:      "server",
+		ID:        serverID,
+		Name:      s.Name,
+		DependsOn: serverDependencies(state, config),
+		Delete:    s.deleteServerFunc(state, ecsClient, serverID),
+		Hint: fmt.Sprintf("Delete it in the ECS console of %s, or with POST /v1/{project_id}/cloudservers/delete",
+			region),
+	})
+
 	accessPrivateIP, err := getAccessPrivateIP(ecsClient, serverID)
 	if err != nil {
 		state.Put("error", err)
 		return multistep.ActionHalt
 	}
 
-	ui.Message(fmt.Sprintf("Server ID: %s", serverID))
-	s.serverID = serverID
-
 	state.Put("server_id", serverID)
 	state.Put("access_private_ip", accessPrivateIP)
 
 	return multistep.ActionContinue
 }
 
+// serverDependencies returns the keys of the temporary resources used by the server.
+func serverDependencies(state multistep.StateBag, config *Config) []string {
+	var keys []string
+	if subnets, ok := state.GetOk("subnets"); ok {
+		for _, subnetID := range subnets.([]string) {
+			keys = append(keys, resourceKey("subnet", subnetID))
+		}
+	}
+	if accessEIP, ok := state.GetOk("access_eip"); ok {
+		keys = append(keys, resourceKey("public IP", accessEIP.(*PublicipIP).ID))
+	}
+	if config.Comm.SSHKeyPairName != "" {
+		keys = append(keys, resourceKey("keypair", config.Comm.SSHKeyPairName))
+	}
+	return keys
+}
+
 // getAccessPrivateIP returns the first internal port of the instance that can be used for
 // the association of a public IP.
-func getAccessPrivateIP(client *ecs.EcsClient, serverID string) (string, error) {
+func getAccessPrivateIP(client ServerClient, serverID string) (string, error) {
 	var primaryIP string
 	request := &model.ListServerInterfacesRequest{
 		ServerId: serverID,
@@ -192,129 +216,113 @@ func getAccessPrivateIP(client *ecs.EcsClient, serverID string) (string, error)
 	return primaryIP, nil
 }
 
-func (s *StepRunSourceServer) Cleanup(state multistep.StateBag) {
-	if s.serverID == "" {
-		return
-	}
-
-	ui := state.Get("ui").(packer.Ui)
-	config := state.Get("config").(*Config)
-	detachVolumeIds := state.Get("attach_volume_ids")
-
-	region := config.Region
-	ecsClient, err := config.HcEcsClient(region)
-	if err != nil {
-		ui.Error(fmt.Sprintf("Error terminating server, may still be around: %s", err))
-		return
-	}
-
-	serverID := s.serverID
-	err = detachServerVolume(ui, state, ecsClient, serverID, detachVolumeIds)
-	if err != nil {
-		ui.Error(fmt.Sprintf("Error detaching volume from server: %s", err))
-		return
-	}
-
-	ui.Say(fmt.Sprintf("Terminating the source server: %s...", serverID))
-
-	serversbody := []model.ServerId{
-		{
-			Id: serverID,
-		},
-	}
-	cleanup := true
-	request := &model.DeleteServersRequest{
-		Body: &model.DeleteServersRequestBody{
-			Servers:      serversbody,
-			DeleteVolume: &cleanup,
-		},
-	}
-	_, err = ecsClient.DeleteServers(request)
-	if err != nil {
-		ui.Error(fmt.Sprintf("Error terminating server, may still be around: %s", err))
-		return
-	}
-
-	stateChange := StateChangeConf{
-		Pending:      []string{"ACTIVE", "BUILD", "REBUILD", "SUSPENDED", "SHUTOFF", "STOPPED"},
-		Target:       []string{"DELETED"},
-		Refresh:      serverStateRefreshFunc(ecsClient, serverID),
-		Timeout:      10 * time.Minute,
-		Delay:        10 * time.Second,
-		PollInterval: 10 * time.Second,
-	}
-
-	stateChange.WaitForState()
+func (s *StepRunSourceServer) Cleanup(multistep.StateBag) {
+	// the server is deleted by stepCleanupResources
 }
 
-func detachServerVolume(ui packer.Ui, state multistep.StateBag, ecsClient *ecs.EcsClient, serverId string, detachVolumeIds interface{}) error {
-	ui.Say(fmt.Sprintf("Detacheing the volume..."))
-	if detachVolumeIds == nil {
-		return nil
-	}
-	detachIds := detachVolumeIds.([]string)
-	request := &model.DetachServerVolumeRequest{
-		ServerId: serverId,
-	}
-	for _, detachVolumeId := range detachIds {
-		request.VolumeId = detachVolumeId
-		response, err := ecsClient.DetachServerVolume(request)
-		if err != nil {
-			return fmt.Errorf("error detach volume %s from ECS: %s", detachVolumeId, err)
+// deleteServerFunc returns the function to delete the server and its volumes. The volumes attached
+// by StepAttachVolume are detached before, and if any of them is left attached, the volumes are
+// kept so that the existing volume is not deleted with the server.
+func (s *StepRunSourceServer) deleteServerFunc(state multistep.StateBag, client ServerClient, serverID string) func(context.Context, packer.Ui) error {
+	return func(ctx context.Context, ui packer.Ui) error {
+		config := state.Get("config").(*Config)
+		tracker := resourceTrackerFrom(state)
+
+		deleteVolume := true
+		if attachVolumeIds, ok := state.GetOk("attach_volume_ids"); ok {
+			for _, volumeID := range attachVolumeIds.([]string) {
+				if tracker.Failed(resourceKey("volume attachment", volumeID)) {
+					ui.Error(fmt.Sprintf("The volume %s is still attached, so the data volumes of server %s are kept, "+
+						"please delete the temporary volumes %s-volume-* manually", volumeID, serverID, s.Name))
+					deleteVolume = false
+					break
+				}
+			}
 		}
 
-		var jobID string
-		if response.JobId != nil {
-			jobID = *response.JobId
+		ui.Say(fmt.Sprintf("Terminating the source server: %s...", serverID))
+		request := &model.DeleteServersRequest{
+			Body: &model.DeleteServersRequestBody{
+				Servers: []model.ServerId{
+					{
+						Id: serverID,
+					},
+				},
+				DeleteVolume: &deleteVolume,
+			},
 		}
-		_, err = WaitForDetachVolumeJobSuccess(ui, state, ecsClient, jobID)
-		if err != nil {
-			return fmt.Errorf("error detach volume %s from ECS: %s", detachVolumeId, err)
+		if _, err := client.DeleteServers(request); err != nil && !isNotFoundError(err) {
+			return err
+		}
+
+		waiter := Waiter[*model.ServerDetail]{
+			Pending:     []string{"ACTIVE", "BUILD", "REBUILD", "SUSPENDED", "SHUTOFF", "STOPPED"},
+			Target:      []string{"DELETED"},
+			Refresh:     serverStateRefreshFunc(client, serverID),
+			Timeout:     config.Timeouts.deleteServer,
+			Sleep:       config.waitSleep,
+			Delay:       10 * time.Second,
+			MinInterval: 5 * time.Second,
+			MaxInterval: 10 * time.Second,
+			Progress:    UiProgress(ui, fmt.Sprintf("Server %s", serverID)),
+		}
+		if _, err := waiter.Wait(ctx); err != nil {
+			return fmt.Errorf("error waiting for server (%s) to be deleted: %s", serverID, err)
 		}
+		return nil
 	}
-	return nil
 }
 
-func WaitForServerJobSuccess(ui packer.Ui, state multistep.StateBag, client *ecs.EcsClient, jobID string) (*model.ShowJobResponse, error) {
+func WaitForServerJobSuccess(ctx context.Context, ui packer.Ui, state multistep.StateBag, client ServerJobClient,
+	jobID string) (*model.ShowJobResponse, error) {
+	config := state.Get("config").(*Config)
+
 	ui.Message("Waiting for server to become ready...")
-	stateChange := StateChangeConf{
-		Pending:      []string{"INIT", "RUNNING"},
-		Target:       []string{"SUCCESS"},
-		Refresh:      serverJobStateRefreshFunc(client, jobID),
-		Timeout:      10 * time.Minute,
-		Delay:        10 * time.Second,
-		PollInterval: 10 * time.Second,
-		StateBag:     state,
-	}
-	serverJob, err := stateChange.WaitForState()
+	waiter := Waiter[*model.ShowJobResponse]{
+		Pending:     []string{"INIT", "RUNNING"},
+		Target:      []string{"SUCCESS"},
+		Refresh:     serverJobStateRefreshFunc(client, jobID),
+		Timeout:     config.Timeouts.createServer,
+		Sleep:       config.waitSleep,
+		Delay:       10 * time.Second,
+		MinInterval: 5 * time.Second,
+		MaxInterval: 10 * time.Second,
+		Progress:    UiProgress(ui, fmt.Sprintf("Server job %s", jobID)),
+	}
+	serverJob, err := waiter.Wait(ctx)
 	if err != nil {
 		err = fmt.Errorf("Error waiting for server (%s) to become ready: %s", jobID, err)
 		ui.Error(err.Error())
 		return nil, err
 	}
 
-	return serverJob.(*model.ShowJobResponse), nil
+	return serverJob, nil
 }
 
-func WaitForDetachVolumeJobSuccess(ui packer.Ui, state multistep.StateBag, client *ecs.EcsClient, jobID string) (*model.ShowJobResponse, error) {
+func WaitForDetachVolumeJobSuccess(ctx context.Context, ui packer.Ui, state multistep.StateBag, client ServerJobClient,
+	jobID string) (*model.ShowJobResponse, error) {
+	config := state.Get("config").(*Config)
+
 	ui.Message("Waiting for detach volume from ECS success...")
-	stateChange := StateChangeConf{
-		Pending:      []string{"RUNNING"},
-		Target:       []string{"SUCCESS", "NOTFOUND"},
-		Refresh:      serverJobStateRefreshFunc(client, jobID),
-		Timeout:      10 * time.Minute,
-		Delay:        10 * time.Second,
-		PollInterval: 10 * time.Second,
-		StateBag:     state,
-	}
-	serverJob, err := stateChange.WaitForState()
+	waiter := Waiter[*model.ShowJobResponse]{
+		Pending:     []string{"INIT", "RUNNING"},
+		Target:      []string{"SUCCESS", "NOTFOUND"},
+		Refresh:     serverJobStateRefreshFunc(client, jobID),
+		Timeout:     config.Timeouts.attachVolume,
+		Sleep:       config.waitSleep,
+		Delay:       10 * time.Second,
+		MinInterval: 5 * time.Second,
+		MaxInterval: 10 * time.Second,
+		Progress:    UiProgress(ui, fmt.Sprintf("Volume job %s", jobID)),
+	}
+	serverJob, err := waiter.Wait(ctx)
 	if err != nil {
 		err = fmt.Errorf("error waiting for volume (%s) to become ready: %s", jobID, err)
 		ui.Error(err.Error())
 		return nil, err
 	}
 
-	return serverJob.(*model.ShowJobResponse), nil
+	return serverJob, nil
 }
 
 func (s *StepRunSourceServer) buildNetworks(state multistep.StateBag) []model.PostPaidServerNic {
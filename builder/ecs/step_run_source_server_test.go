@@ -0,0 +1,38 @@
+package ecs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+func TestStepRunSourceServer_CleanupOnFailure(t *testing.T) {
+	ecsClient := &fakeServerClient{
+		ServerID:      "server-1",
+		InterfacesErr: errors.New("service unavailable"),
+	}
+	state, _ := testStepState(t, map[string]interface{}{"ecs": ecsClient})
+	state.Put("flavor_id", "s6.large.2")
+	state.Put("source_image", "image-1")
+	state.Put("vpc_id", "vpc-1")
+	state.Put("subnets", []string{"subnet-1"})
+	state.Put("availability_zone", "cn-north-4a")
+
+	step := &StepRunSourceServer{Name: "packer-test"}
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("expected the step to halt, but got %v", action)
+	}
+
+	// the server has been created, so it's deleted even though the step failed
+	if leftovers := cleanupResources(state); len(leftovers) > 0 {
+		t.Fatalf("unexpected leftovers: %v", leftovers)
+	}
+	if !ecsClient.called("DeleteServers server-1") {
+		t.Fatalf("expected the server to be deleted, calls: %v", ecsClient.calls)
+	}
+	if !ecsClient.deletedVolumes {
+		t.Fatalf("expected the volumes to be deleted with the server")
+	}
+}
@@ -23,12 +23,13 @@ func (s *StepSourceImageInfo) Run(ctx context.Context, state multistep.StateBag)
 	ui := state.Get("ui").(packer.Ui)
 
 	if s.SourceImage != "" {
+		config.report.setSourceImage(sourceImageReport{ID: s.SourceImage, ResolvedBy: sourceImageByID})
 		state.Put("source_image", s.SourceImage)
 		return multistep.ActionContinue
 	}
 
 	region := config.Region
-	client, err := config.HcImsClient(region)
+	client, err := config.imageClient(region)
 	if err != nil {
 		err := fmt.Errorf("error creating image client: %s", err)
 		state.Put("error", err)
@@ -77,6 +78,19 @@ func (s *StepSourceImageInfo) Run(ctx context.Context, state multistep.StateBag)
 	imageID := images[0].Id
 	ui.Message(fmt.Sprintf("Found Image ID: %s", imageID))
 
+	resolvedBy := sourceImageByFilter
+	if s.SourceImageName != "" {
+		resolvedBy = sourceImageByName
+	}
+	config.report.setSourceImage(sourceImageReport{
+		ID:         imageID,
+		Name:       images[0].Name,
+		ResolvedBy: resolvedBy,
+		Filters:    s.SourceImageOpts,
+		MostRecent: s.SourceMostRecent,
+		Matches:    len(images),
+	})
+
 	state.Put("source_image", imageID)
 	return multistep.ActionContinue
 }
@@ -19,7 +19,7 @@ func (s *StepStopServer) Run(ctx context.Context, state multistep.StateBag) mult
 	config := state.Get("config").(*Config)
 
 	region := config.Region
-	client, err := config.HcEcsClient(region)
+	client, err := config.serverClient(region)
 	if err != nil {
 		err = fmt.Errorf("Error initializing compute client: %s", err)
 		state.Put("error", err)
@@ -50,16 +50,21 @@ func (s *StepStopServer) Run(ctx context.Context, state multistep.StateBag) mult
 	}
 
 	ui.Message(fmt.Sprintf("Waiting for server to stop: %s ...", serverID))
-	stateChange := StateChangeConf{
-		Pending:      []string{"ACTIVE"},
-		Target:       []string{"SHUTOFF", "STOPPED"},
-		Refresh:      serverStateRefreshFunc(client, serverID),
-		Timeout:      3 * time.Minute,
-		Delay:        5 * time.Second,
-		PollInterval: 5 * time.Second,
-		StateBag:     state,
+	waiter := Waiter[*model.ServerDetail]{
+		Pending:     []string{"ACTIVE"},
+		Target:      []string{"SHUTOFF", "STOPPED"},
+		Refresh:     serverStateRefreshFunc(client, serverID),
+		Timeout:     config.Timeouts.stopServer,
+		Sleep:       config.waitSleep,
+		Delay:       5 * time.Second,
+		MinInterval: 5 * time.Second,
+		MaxInterval: 5 * time.Second,
 	}
-	if _, err := stateChange.WaitForState(); err != nil {
+	if _, err := waiter.Wait(ctx); err != nil {
+		if ctx.Err() != nil {
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
 		log.Printf("[WARN] error waiting for server (%s) to stop: %s", serverID, err)
 	}
 
@@ -0,0 +1,77 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Timeouts
+
+package ecs
+
+import (
+	"fmt"
+	"time"
+)
+
+// Timeouts is the configuration of the timeouts to wait for the resources to reach the expected states.
+type Timeouts struct {
+	// The timeout to wait for the server to be created, defaults to `10m`.
+	CreateServer string `mapstructure:"create_server" required:"false"`
+	// The timeout to wait for the server to be deleted, defaults to `10m`.
+	DeleteServer string `mapstructure:"delete_server" required:"false"`
+	// The timeout to wait for the server to be stopped, defaults to `3m`.
+	StopServer string `mapstructure:"stop_server" required:"false"`
+	// The timeout to wait for a data disk to be attached or detached, defaults to `10m`.
+	AttachVolume string `mapstructure:"attach_volume" required:"false"`
+	// The timeout to wait for a data disk to be created, defaults to `10m`.
+	CreateVolume string `mapstructure:"create_volume" required:"false"`
+	// The timeout to wait for the temporary VPC and subnet to be created or deleted, defaults to `3m`.
+	Network string `mapstructure:"network" required:"false"`
+	// The timeout to wait for the temporary EIP to be active, defaults to `5m`.
+	EIP string `mapstructure:"eip" required:"false"`
+	// The timeout to wait for the password of the Windows server, defaults to `10m`.
+	Password string `mapstructure:"password" required:"false"`
+
+	createServer time.Duration
+	deleteServer time.Duration
+	stopServer   time.Duration
+	attachVolume time.Duration
+	createVolume time.Duration
+	network      time.Duration
+	eip          time.Duration
+	password     time.Duration
+}
+
+func (t *Timeouts) Prepare() []error {
+	var errs []error
+
+	timeouts := []struct {
+		name         string
+		value        string
+		defaultValue time.Duration
+		target       *time.Duration
+	}{
+		{"create_server", t.CreateServer, 10 * time.Minute, &t.createServer},
+		{"delete_server", t.DeleteServer, 10 * time.Minute, &t.deleteServer},
+		{"stop_server", t.StopServer, 3 * time.Minute, &t.stopServer},
+		{"attach_volume", t.AttachVolume, 10 * time.Minute, &t.attachVolume},
+		{"create_volume", t.CreateVolume, 10 * time.Minute, &t.createVolume},
+		{"network", t.Network, 3 * time.Minute, &t.network},
+		{"eip", t.EIP, 5 * time.Minute, &t.eip},
+		{"password", t.Password, 10 * time.Minute, &t.password},
+	}
+
+	for _, timeout := range timeouts {
+		if timeout.value == "" {
+			*timeout.target = timeout.defaultValue
+			continue
+		}
+
+		duration, err := time.ParseDuration(timeout.value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid timeouts.%s %q: %s", timeout.name, timeout.value, err))
+			continue
+		}
+		if duration <= 0 {
+			errs = append(errs, fmt.Errorf("timeouts.%s must be a positive duration, got %s", timeout.name, timeout.value))
+			continue
+		}
+		*timeout.target = duration
+	}
+
+	return errs
+}
@@ -0,0 +1,45 @@
+// Code generated by "packer-sdc mapstructure-to-hcl2"; DO NOT EDIT.
+
+package ecs
+
+import (
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FlatTimeouts is an auto-generated flat version of Timeouts.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatTimeouts struct {
+	CreateServer *string `mapstructure:"create_server" required:"false" cty:"create_server" hcl:"create_server"`
+	DeleteServer *string `mapstructure:"delete_server" required:"false" cty:"delete_server" hcl:"delete_server"`
+	StopServer   *string `mapstructure:"stop_server" required:"false" cty:"stop_server" hcl:"stop_server"`
+	AttachVolume *string `mapstructure:"attach_volume" required:"false" cty:"attach_volume" hcl:"attach_volume"`
+	CreateVolume *string `mapstructure:"create_volume" required:"false" cty:"create_volume" hcl:"create_volume"`
+	Network      *string `mapstructure:"network" required:"false" cty:"network" hcl:"network"`
+	EIP          *string `mapstructure:"eip" required:"false" cty:"eip" hcl:"eip"`
+	Password     *string `mapstructure:"password" required:"false" cty:"password" hcl:"password"`
+}
+
+// FlatMapstructure returns a new FlatTimeouts.
+// FlatTimeouts is an auto-generated flat version of Timeouts.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Timeouts) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatTimeouts)
+}
+
+// HCL2Spec returns the hcl spec of a Timeouts.
+// This spec is used by HCL to read the fields of Timeouts.
+// The decoded values from this spec will then be applied to a FlatTimeouts.
+func (*FlatTimeouts) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"create_server": &hcldec.AttrSpec{Name: "create_server", Type: cty.String, Required: false},
+		"delete_server": &hcldec.AttrSpec{Name: "delete_server", Type: cty.String, Required: false},
+		"stop_server":   &hcldec.AttrSpec{Name: "stop_server", Type: cty.String, Required: false},
+		"attach_volume": &hcldec.AttrSpec{Name: "attach_volume", Type: cty.String, Required: false},
+		"create_volume": &hcldec.AttrSpec{Name: "create_volume", Type: cty.String, Required: false},
+		"network":       &hcldec.AttrSpec{Name: "network", Type: cty.String, Required: false},
+		"eip":           &hcldec.AttrSpec{Name: "eip", Type: cty.String, Required: false},
+		"password":      &hcldec.AttrSpec{Name: "password", Type: cty.String, Required: false},
+	}
+	return s
+}
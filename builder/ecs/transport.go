@@ -0,0 +1,105 @@
+package ecs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/pathing"
+)
+
+// prepareTLSConfig loads the CA bundle and the client certificate, the TLS configuration
+// is shared by all the service clients and the OBS client.
+func (c *AccessConfig) prepareTLSConfig() error {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.Insecure,
+	}
+
+	if c.CACertFile != "" {
+		filePath, err := pathing.ExpandUser(c.CACertFile)
+		if err != nil {
+			return fmt.Errorf("failed to expand the path of cacert_file %s: %s", c.CACertFile, err)
+		}
+		caCert, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read cacert_file %s: %s", filePath, err)
+		}
+
+		// the custom CA bundle is appended to the system pool, so the public endpoints are still trusted
+		caPool, err := x509.SystemCertPool()
+		if err != nil || caPool == nil {
+			caPool = x509.NewCertPool()
+		}
+		if !caPool.AppendCertsFromPEM(caCert) {
+			return fmt.Errorf("no valid PEM certificate is found in cacert_file %s", filePath)
+		}
+		tlsConfig.RootCAs = caPool
+	}
+
+	if c.ClientCertFile != "" || c.ClientKeyFile != "" {
+		if c.ClientCertFile == "" || c.ClientKeyFile == "" {
+			return fmt.Errorf("both cert and key must be specified for mutual TLS")
+		}
+
+		certPath, err := pathing.ExpandUser(c.ClientCertFile)
+		if err != nil {
+			return fmt.Errorf("failed to expand the path of cert %s: %s", c.ClientCertFile, err)
+		}
+		keyPath, err := pathing.ExpandUser(c.ClientKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to expand the path of key %s: %s", c.ClientKeyFile, err)
+		}
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return fmt.Errorf("failed to load the client certificate %s and key %s: %s", certPath, keyPath, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	c.tlsConfig = tlsConfig
+	return nil
+}
+
+// NewTransport returns an HTTP transport with the TLS settings of AccessConfig, the proxy is
+// taken from the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables (or the lowercase
+// versions thereof). The throttled and transient failed requests are retried up to MaxRetries times,
+// and the request IDs of the submitted jobs are recorded for the error messages.
+func (c *AccessConfig) NewTransport() *http.Transport {
+	transport := c.newBaseTransport()
+
+	// the alternate protocols take over the requests of the transport, it's the only way to
+	// wrap the transport of the SDK clients which must be an *http.Transport
+	apiTransport := &requestIDRoundTripper{
+		rt: &retryRoundTripper{
+			rt:         c.newBaseTransport(),
+			maxRetries: c.MaxRetries,
+		},
+	}
+	transport.RegisterProtocol("https", apiTransport)
+	transport.RegisterProtocol("http", apiTransport)
+
+	return transport
+}
+
+func (c *AccessConfig) newBaseTransport() *http.Transport {
+	tlsConfig := c.tlsConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{InsecureSkipVerify: c.Insecure}
+	}
+
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:       tlsConfig.Clone(),
+		TLSHandshakeTimeout:   10 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
@@ -0,0 +1,51 @@
+package ecs
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewTransport_CACertFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// the self-signed certificate of the test server is not trusted by default
+	c := &AccessConfig{}
+	if err := c.prepareTLSConfig(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	client := http.Client{Transport: c.NewTransport()}
+	if _, err := client.Get(server.URL); err == nil {
+		t.Fatalf("the request should fail without the CA certificate")
+	}
+
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	c = &AccessConfig{
+		CACertFile: writeTestFile(t, "ca.pem", string(caCert)),
+	}
+	if err := c.prepareTLSConfig(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	client = http.Client{Transport: c.NewTransport()}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("the request should succeed with the CA certificate: %s", err)
+	}
+	resp.Body.Close()
+}
+
+func TestPrepareTLSConfig_Invalid(t *testing.T) {
+	cases := []AccessConfig{
+		{CACertFile: writeTestFile(t, "ca.pem", "not a certificate")},
+		{ClientCertFile: writeTestFile(t, "cert.pem", "not a certificate")},
+		{ClientCertFile: writeTestFile(t, "cert.pem", "bad"), ClientKeyFile: writeTestFile(t, "key.pem", "bad")},
+	}
+
+	for _, c := range cases {
+		if err := c.prepareTLSConfig(); err == nil {
+			t.Errorf("%#v should be invalid", c)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package fakecloud
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func (s *Server) cbrRoutes() []route {
+	return []route{
+		{http.MethodGet, "/v3/{project_id}/vaults/{vault_id}", s.showVault},
+	}
+}
+
+func (s *Server) showVault(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	vault, ok := s.vaults[params["vault_id"]]
+	if !ok {
+		writeError(w, "cbr", http.StatusNotFound, "BackupService.6001", fmt.Sprintf("The vault %s does not exist.", params["vault_id"]))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"vault": map[string]interface{}{
+			"id":         vault.ID,
+			"name":       vault.Name,
+			"project_id": s.ProjectID,
+			"resources":  []interface{}{},
+			"billing": map[string]interface{}{
+				"status":       vault.Status,
+				"object_type":  vault.ObjectType,
+				"protect_type": "backup",
+			},
+		},
+	})
+}
@@ -0,0 +1,623 @@
+package fakecloud
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// WindowsPassword is the administrator password of the Windows servers.
+const WindowsPassword = "Packer@Fake1"
+
+func (s *Server) ecsRoutes() []route {
+	return []route{
+		{http.MethodGet, "/v2.1/{project_id}/os-availability-zone", s.listZones},
+		{http.MethodPost, "/v2.1/{project_id}/os-keypairs", s.createKeypair},
+		{http.MethodDelete, "/v2.1/{project_id}/os-keypairs/{name}", s.deleteKeypair},
+		{http.MethodPost, "/v1/{project_id}/cloudservers", s.createServer},
+		{http.MethodPost, "/v1/{project_id}/cloudservers/delete", s.deleteServers},
+		{http.MethodPost, "/v1/{project_id}/cloudservers/action", s.serverAction},
+		{http.MethodGet, "/v1/{project_id}/cloudservers/limits", s.showServerLimits},
+		{http.MethodGet, "/v1/{project_id}/cloudservers/flavors", s.listFlavors},
+		{http.MethodGet, "/v1/{project_id}/cloudservers/detail", s.listServers},
+		{http.MethodGet, "/v1/{project_id}/cloudservers/{server_id}", s.showServer},
+		{http.MethodGet, "/v1/{project_id}/cloudservers/{server_id}/os-server-password", s.showServerPassword},
+		{http.MethodGet, "/v1/{project_id}/cloudservers/{server_id}/os-interface", s.listServerInterfaces},
+		{http.MethodGet, "/v1/{project_id}/cloudservers/{server_id}/block_device", s.listServerBlockDevices},
+		{http.MethodPost, "/v1/{project_id}/cloudservers/{server_id}/attachvolume", s.attachVolume},
+		{http.MethodDelete, "/v1/{project_id}/cloudservers/{server_id}/detachvolume/{volume_id}", s.detachVolume},
+		{http.MethodPost, "/v1/{project_id}/cloudservers/{server_id}/remote_console", s.showRemoteConsole},
+		{http.MethodPost, "/v2.1/{project_id}/servers/{server_id}/action", s.novaServerAction},
+		{http.MethodGet, "/v1/{project_id}/jobs/{job_id}", s.showJob("ecs")},
+	}
+}
+
+func (s *Server) listZones(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	zones := make([]interface{}, len(s.Zones))
+	for i, zone := range s.Zones {
+		zones[i] = map[string]interface{}{
+			"zoneName":  zone,
+			"zoneState": map[string]interface{}{"available": true},
+			"hosts":     nil,
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"availabilityZoneInfo": zones})
+}
+
+func (s *Server) createKeypair(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body struct {
+		Keypair struct {
+			Name string `json:"name"`
+		} `json:"keypair"`
+	}
+	if !readJSON(w, r, "ecs", &body) {
+		return
+	}
+
+	name := body.Keypair.Name
+	if _, ok := s.keypairs[name]; ok {
+		writeError(w, "ecs", http.StatusConflict, "Ecs.0315", fmt.Sprintf("Key pair '%s' already exists.", name))
+		return
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		writeError(w, "ecs", http.StatusInternalServerError, "Ecs.0000", err.Error())
+		return
+	}
+	publicKey, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		writeError(w, "ecs", http.StatusInternalServerError, "Ecs.0000", err.Error())
+		return
+	}
+
+	kp := &Keypair{
+		Name:      name,
+		PublicKey: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey))),
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		})),
+		key: key,
+	}
+	s.keypairs[name] = kp
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keypair": map[string]interface{}{
+			"name":        kp.Name,
+			"public_key":  kp.PublicKey,
+			"private_key": kp.PrivateKey,
+			"fingerprint": ssh.FingerprintLegacyMD5(publicKey),
+			"user_id":     "fake-user",
+		},
+	})
+}
+
+func (s *Server) deleteKeypair(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	name := params["name"]
+	if _, ok := s.keypairs[name]; !ok {
+		writeError(w, "ecs", http.StatusNotFound, "Ecs.0314", fmt.Sprintf("Keypair %s not found.", name))
+		return
+	}
+
+	delete(s.keypairs, name)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) createServer(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body struct {
+		Server struct {
+			Name             string            `json:"name"`
+			ImageRef         string            `json:"imageRef"`
+			FlavorRef        string            `json:"flavorRef"`
+			VpcID            string            `json:"vpcid"`
+			AvailabilityZone string            `json:"availability_zone"`
+			KeyName          string            `json:"key_name"`
+			Metadata         map[string]string `json:"metadata"`
+			Nics             []struct {
+				SubnetID string `json:"subnet_id"`
+			} `json:"nics"`
+			RootVolume struct {
+				VolumeType string `json:"volumetype"`
+				Size       int    `json:"size"`
+				Metadata   struct {
+					Encrypted string `json:"__system__encrypted"`
+				} `json:"metadata"`
+			} `json:"root_volume"`
+			PublicIP *struct {
+				ID string `json:"id"`
+			} `json:"publicip"`
+		} `json:"server"`
+	}
+	if !readJSON(w, r, "ecs", &body) {
+		return
+	}
+	opts := body.Server
+
+	image, ok := s.images[opts.ImageRef]
+	if !ok || image.Status != "active" {
+		writeError(w, "ecs", http.StatusBadRequest, "Ecs.0023", fmt.Sprintf("The image %s does not exist.", opts.ImageRef))
+		return
+	}
+	if !containsString(s.Zones, opts.AvailabilityZone) {
+		writeError(w, "ecs", http.StatusBadRequest, "Ecs.0036",
+			fmt.Sprintf("The availability zone %s is not available.", opts.AvailabilityZone))
+		return
+	}
+	if _, ok := s.vpcs[opts.VpcID]; !ok {
+		writeError(w, "ecs", http.StatusBadRequest, "Ecs.0026", fmt.Sprintf("The VPC %s does not exist.", opts.VpcID))
+		return
+	}
+	subnetIDs := make([]string, 0, len(opts.Nics))
+	for _, nic := range opts.Nics {
+		subnet, ok := s.subnets[nic.SubnetID]
+		if !ok || subnet.VpcID != opts.VpcID {
+			writeError(w, "ecs", http.StatusBadRequest, "Ecs.0026",
+				fmt.Sprintf("The subnet %s does not exist in VPC %s.", nic.SubnetID, opts.VpcID))
+			return
+		}
+		subnetIDs = append(subnetIDs, nic.SubnetID)
+	}
+	if len(subnetIDs) == 0 {
+		writeError(w, "ecs", http.StatusBadRequest, "Ecs.0005", "The nics must be specified.")
+		return
+	}
+	if opts.KeyName != "" {
+		if _, ok := s.keypairs[opts.KeyName]; !ok {
+			writeError(w, "ecs", http.StatusBadRequest, "Ecs.0314", fmt.Sprintf("Keypair %s not found.", opts.KeyName))
+			return
+		}
+	}
+	var eipID string
+	if opts.PublicIP != nil && opts.PublicIP.ID != "" {
+		eip, ok := s.eips[opts.PublicIP.ID]
+		if !ok || eip.PortID != "" {
+			writeError(w, "ecs", http.StatusBadRequest, "Ecs.0039",
+				fmt.Sprintf("The public IP %s does not exist or is in use.", opts.PublicIP.ID))
+			return
+		}
+		eipID = eip.ID
+	}
+
+	serverID := s.newID("server")
+	j := s.newJob("ecs", JobCreateServer, func() map[string]interface{} {
+		instance := &Instance{
+			ID:               serverID,
+			Name:             opts.Name,
+			Status:           "ACTIVE",
+			ImageRef:         opts.ImageRef,
+			FlavorRef:        opts.FlavorRef,
+			AvailabilityZone: opts.AvailabilityZone,
+			VpcID:            opts.VpcID,
+			SubnetIDs:        subnetIDs,
+			PrivateIP:        fmt.Sprintf("172.16.0.%d", len(s.instances)+10),
+			KeyName:          opts.KeyName,
+			Metadata:         opts.Metadata,
+			CreatedAt:        time.Now(),
+		}
+		if eip, ok := s.eips[eipID]; ok && eip.PortID == "" {
+			eip.PortID = "port-" + serverID
+			eip.Status = "ACTIVE"
+			instance.PublicIPID = eipID
+		}
+		if kp, ok := s.keypairs[opts.KeyName]; ok && image.OsType == "Windows" {
+			encrypted, err := rsa.EncryptPKCS1v15(rand.Reader, &kp.key.PublicKey, []byte(WindowsPassword))
+			if err == nil {
+				instance.EncryptedPassword = base64.StdEncoding.EncodeToString(encrypted)
+			}
+		}
+		s.instances[serverID] = instance
+
+		size := opts.RootVolume.Size
+		if size == 0 {
+			size = image.MinDisk
+		}
+		if size == 0 {
+			size = 40
+		}
+		rootVolume := &Volume{
+			ID:               s.newID("volume"),
+			Name:             opts.Name + "-system",
+			VolumeType:       opts.RootVolume.VolumeType,
+			Size:             size,
+			AvailabilityZone: opts.AvailabilityZone,
+			ServerID:         serverID,
+			Device:           "/dev/vda",
+			Bootable:         true,
+			ImageRef:         opts.ImageRef,
+			Encrypted:        opts.RootVolume.Metadata.Encrypted == "1",
+		}
+		s.volumes[rootVolume.ID] = rootVolume
+
+		return map[string]interface{}{
+			"sub_jobs_total": 1,
+			"sub_jobs": []interface{}{
+				map[string]interface{}{
+					"status":   "SUCCESS",
+					"job_id":   s.newID("job"),
+					"job_type": "createSingleServer",
+					"entities": map[string]interface{}{"server_id": serverID},
+				},
+			},
+		}
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": j.id, "serverIds": []string{serverID}})
+}
+
+func (s *Server) deleteServers(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body struct {
+		Servers []struct {
+			ID string `json:"id"`
+		} `json:"servers"`
+		DeletePublicIP bool `json:"delete_publicip"`
+		DeleteVolume   bool `json:"delete_volume"`
+	}
+	if !readJSON(w, r, "ecs", &body) {
+		return
+	}
+
+	for _, server := range body.Servers {
+		if _, ok := s.instances[server.ID]; !ok {
+			writeError(w, "ecs", http.StatusNotFound, "Ecs.0114", fmt.Sprintf("Instance[%s] could not be found.", server.ID))
+			return
+		}
+	}
+
+	j := s.newJob("ecs", JobDeleteServer, func() map[string]interface{} {
+		for _, server := range body.Servers {
+			instance, ok := s.instances[server.ID]
+			if !ok {
+				continue
+			}
+			if eip, ok := s.eips[instance.PublicIPID]; ok {
+				if body.DeletePublicIP {
+					delete(s.eips, eip.ID)
+				} else {
+					eip.PortID = ""
+					eip.Status = "DOWN"
+				}
+			}
+			for id, volume := range s.volumes {
+				if volume.ServerID != instance.ID {
+					continue
+				}
+				if volume.Bootable || body.DeleteVolume {
+					delete(s.volumes, id)
+				} else {
+					volume.ServerID = ""
+					volume.Device = ""
+				}
+			}
+			delete(s.instances, instance.ID)
+		}
+		return map[string]interface{}{}
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": j.id})
+}
+
+func (s *Server) serverAction(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body struct {
+		Stop *struct {
+			Servers []struct {
+				ID string `json:"id"`
+			} `json:"servers"`
+		} `json:"os-stop"`
+	}
+	if !readJSON(w, r, "ecs", &body) {
+		return
+	}
+	if body.Stop == nil {
+		writeError(w, "ecs", http.StatusBadRequest, "Ecs.0005", "Only the os-stop action is supported.")
+		return
+	}
+
+	j := s.newJob("ecs", JobStopServer, func() map[string]interface{} {
+		for _, server := range body.Stop.Servers {
+			if instance, ok := s.instances[server.ID]; ok {
+				instance.Status = "SHUTOFF"
+			}
+		}
+		return map[string]interface{}{}
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": j.id})
+}
+
+// instance returns the server of the request, it answers the request with an error if the
+// server does not exist.
+func (s *Server) instance(w http.ResponseWriter, params map[string]string) (*Instance, bool) {
+	serverID := params["server_id"]
+	instance, ok := s.instances[serverID]
+	if !ok {
+		writeError(w, "ecs", http.StatusNotFound, "Ecs.0114", fmt.Sprintf("Instance[%s] could not be found.", serverID))
+	}
+	return instance, ok
+}
+
+func (s *Server) showServer(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	// the deletion and stop are observed through the status of the server
+	s.advanceJobs("ecs", JobDeleteServer)
+	s.advanceJobs("ecs", JobStopServer)
+
+	instance, ok := s.instance(w, params)
+	if !ok {
+		return
+	}
+
+	addresses := make([]interface{}, 0, 2)
+	addresses = append(addresses, map[string]interface{}{
+		"addr": instance.PrivateIP, "version": "4", "OS-EXT-IPS:type": "fixed",
+	})
+	if eip, ok := s.eips[instance.PublicIPID]; ok {
+		addresses = append(addresses, map[string]interface{}{
+			"addr": eip.Address, "version": "4", "OS-EXT-IPS:type": "floating",
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"server": map[string]interface{}{
+			"id":                          instance.ID,
+			"name":                        instance.Name,
+			"status":                      instance.Status,
+			"key_name":                    instance.KeyName,
+			"metadata":                    instance.Metadata,
+			"OS-EXT-AZ:availability_zone": instance.AvailabilityZone,
+			"flavor":                      map[string]interface{}{"id": instance.FlavorRef},
+			"image":                       map[string]interface{}{"id": instance.ImageRef},
+			"addresses":                   map[string]interface{}{instance.VpcID: addresses},
+			"created":                     instance.CreatedAt.UTC().Format(time.RFC3339),
+		},
+	})
+}
+
+// listServers lists the servers in pages, the offset is the page number starting from 1.
+func (s *Server) listServers(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	instances := make([]*Instance, 0, len(s.instances))
+	for _, instance := range s.instances {
+		instances = append(instances, instance)
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+
+	count := len(instances)
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 25
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset <= 0 {
+		offset = 1
+	}
+	start := (offset - 1) * limit
+	if start > len(instances) {
+		start = len(instances)
+	}
+	end := start + limit
+	if end > len(instances) {
+		end = len(instances)
+	}
+
+	servers := make([]interface{}, 0, end-start)
+	for _, instance := range instances[start:end] {
+		servers = append(servers, map[string]interface{}{
+			"id":      instance.ID,
+			"name":    instance.Name,
+			"status":  instance.Status,
+			"image":   map[string]interface{}{"id": instance.ImageRef},
+			"flavor":  map[string]interface{}{"id": instance.FlavorRef},
+			"created": instance.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"count": count, "servers": servers})
+}
+
+func (s *Server) showServerPassword(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	instance, ok := s.instance(w, params)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"password": instance.EncryptedPassword})
+}
+
+func (s *Server) showRemoteConsole(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	instance, ok := s.instance(w, params)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"remote_console": map[string]interface{}{
+			"protocol": "vnc",
+			"type":     "novnc",
+			"url":      fmt.Sprintf("https://%s/vnc_auto.html?instance_id=%s&token=fake-token", r.Host, instance.ID),
+		},
+	})
+}
+
+// novaServerAction answers the os-getConsoleOutput action with the last lines of ConsoleOutput.
+func (s *Server) novaServerAction(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body struct {
+		GetConsoleOutput *struct {
+			Length int `json:"length"`
+		} `json:"os-getConsoleOutput"`
+	}
+	if !readJSON(w, r, "ecs", &body) {
+		return
+	}
+	if body.GetConsoleOutput == nil {
+		writeError(w, "ecs", http.StatusBadRequest, "Ecs.0005", "Only the os-getConsoleOutput action is supported.")
+		return
+	}
+	if _, ok := s.instance(w, params); !ok {
+		return
+	}
+
+	lines := strings.SplitAfter(s.ConsoleOutput, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if length := body.GetConsoleOutput.Length; length > 0 && length < len(lines) {
+		lines = lines[len(lines)-length:]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"output": strings.Join(lines, "")})
+}
+
+func (s *Server) listServerInterfaces(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	instance, ok := s.instance(w, params)
+	if !ok {
+		return
+	}
+
+	interfaces := make([]interface{}, len(instance.SubnetIDs))
+	for i, subnetID := range instance.SubnetIDs {
+		ip := instance.PrivateIP
+		if i > 0 {
+			ip = fmt.Sprintf("172.16.%d.%s", i, strings.TrimPrefix(instance.PrivateIP, "172.16.0."))
+		}
+		interfaces[i] = map[string]interface{}{
+			"port_state": "ACTIVE",
+			"fixed_ips":  []interface{}{map[string]interface{}{"subnet_id": subnetID, "ip_address": ip}},
+			"net_id":     subnetID,
+			"port_id":    fmt.Sprintf("port-%s-%d", instance.ID, i),
+			"mac_addr":   fmt.Sprintf("fa:16:3e:00:00:%02x", i),
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"interfaceAttachments": interfaces})
+}
+
+// attachedVolumes returns the volumes attached to the server in the order of the devices.
+func (s *Server) attachedVolumes(serverID string) []*Volume {
+	var volumes []*Volume
+	for _, volume := range s.volumes {
+		if volume.ServerID == serverID {
+			volumes = append(volumes, volume)
+		}
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		if len(volumes[i].Device) != len(volumes[j].Device) {
+			return len(volumes[i].Device) < len(volumes[j].Device)
+		}
+		return volumes[i].Device < volumes[j].Device
+	})
+	return volumes
+}
+
+// nextDevice returns the first free device name of the server, e.g. /dev/vdb.
+func (s *Server) nextDevice(serverID string) string {
+	used := make(map[string]bool)
+	for _, volume := range s.attachedVolumes(serverID) {
+		used[volume.Device] = true
+	}
+	for c := 'b'; c <= 'z'; c++ {
+		device := "/dev/vd" + string(c)
+		if !used[device] {
+			return device
+		}
+	}
+	return "/dev/vdaa"
+}
+
+func (s *Server) listServerBlockDevices(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	instance, ok := s.instance(w, params)
+	if !ok {
+		return
+	}
+
+	var devices []interface{}
+	// the devices are listed in the reverse order like the real API, which is not sorted
+	volumes := s.attachedVolumes(instance.ID)
+	for i := len(volumes) - 1; i >= 0; i-- {
+		volume := volumes[i]
+		device := map[string]interface{}{
+			"serverId": instance.ID,
+			"volumeId": volume.ID,
+			"id":       volume.ID,
+			"device":   volume.Device,
+			"size":     volume.Size,
+		}
+		if volume.Bootable {
+			device["bootIndex"] = 0
+		}
+		devices = append(devices, device)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"volumeAttachments": devices})
+}
+
+func (s *Server) attachVolume(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body struct {
+		VolumeAttachment struct {
+			VolumeID string `json:"volumeId"`
+			Device   string `json:"device"`
+		} `json:"volumeAttachment"`
+	}
+	if !readJSON(w, r, "ecs", &body) {
+		return
+	}
+	instance, ok := s.instance(w, params)
+	if !ok {
+		return
+	}
+
+	volumeID := body.VolumeAttachment.VolumeID
+	volume, ok := s.volumes[volumeID]
+	switch {
+	case !ok:
+		writeError(w, "ecs", http.StatusBadRequest, "Ecs.0010", fmt.Sprintf("The volume %s does not exist.", volumeID))
+		return
+	case volume.ServerID != "":
+		writeError(w, "ecs", http.StatusBadRequest, "Ecs.0010", fmt.Sprintf("The volume %s is in use.", volumeID))
+		return
+	case volume.AvailabilityZone != instance.AvailabilityZone:
+		writeError(w, "ecs", http.StatusBadRequest, "Ecs.0010",
+			fmt.Sprintf("The volume %s is not in the availability zone %s.", volumeID, instance.AvailabilityZone))
+		return
+	}
+
+	j := s.newJob("ecs", JobAttachVolume, func() map[string]interface{} {
+		volume.ServerID = instance.ID
+		volume.Device = s.nextDevice(instance.ID)
+		return map[string]interface{}{"server_id": instance.ID}
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": j.id})
+}
+
+func (s *Server) detachVolume(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	instance, ok := s.instance(w, params)
+	if !ok {
+		return
+	}
+
+	volumeID := params["volume_id"]
+	volume, ok := s.volumes[volumeID]
+	if !ok || volume.ServerID != instance.ID {
+		writeError(w, "ecs", http.StatusBadRequest, "Ecs.0010",
+			fmt.Sprintf("The volume %s is not attached to the server %s.", volumeID, instance.ID))
+		return
+	}
+
+	j := s.newJob("ecs", JobDetachVolume, func() map[string]interface{} {
+		volume.ServerID = ""
+		volume.Device = ""
+		return map[string]interface{}{"server_id": instance.ID}
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": j.id})
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
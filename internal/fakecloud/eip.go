@@ -0,0 +1,105 @@
+package fakecloud
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+func (s *Server) eipRoutes() []route {
+	return []route{
+		{http.MethodPost, "/v1/{project_id}/publicips", s.createPublicIP},
+		{http.MethodGet, "/v1/{project_id}/publicips", s.listPublicIPs},
+		{http.MethodGet, "/v1/{project_id}/publicips/{publicip_id}", s.showPublicIP},
+		{http.MethodDelete, "/v1/{project_id}/publicips/{publicip_id}", s.deletePublicIP},
+		{http.MethodGet, "/v1/{project_id}/quotas", s.listQuotas(QuotaPublicIPs)},
+	}
+}
+
+func publicIPBody(eip *PublicIP) map[string]interface{} {
+	body := map[string]interface{}{
+		"id":                eip.ID,
+		"type":              "5_bgp",
+		"public_ip_address": eip.Address,
+		"status":            eip.Status,
+		"bandwidth_size":    eip.BandwidthSize,
+		"ip_version":        4,
+		"create_time":       timestamp(),
+	}
+	if eip.PortID != "" {
+		body["port_id"] = eip.PortID
+	}
+	return body
+}
+
+// createPublicIP creates a public IP in the PENDING_CREATE status, it becomes DOWN when it's queried.
+func (s *Server) createPublicIP(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body struct {
+		Publicip struct {
+			Type string `json:"type"`
+		} `json:"publicip"`
+		Bandwidth struct {
+			Size int `json:"size"`
+		} `json:"bandwidth"`
+	}
+	if !readJSON(w, r, "eip", &body) {
+		return
+	}
+
+	eip := &PublicIP{ID: s.newID("eip"), Address: s.newAddress(), Status: "PENDING_CREATE", BandwidthSize: body.Bandwidth.Size}
+	s.eips[eip.ID] = eip
+	writeJSON(w, http.StatusOK, map[string]interface{}{"publicip": publicIPBody(eip)})
+}
+
+func (s *Server) showPublicIP(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	eip, ok := s.eips[params["publicip_id"]]
+	if !ok {
+		writeError(w, "eip", http.StatusNotFound, "VPC.0504", fmt.Sprintf("The public IP %s does not exist.", params["publicip_id"]))
+		return
+	}
+
+	body := publicIPBody(eip)
+	if eip.Status == "PENDING_CREATE" {
+		eip.Status = "DOWN"
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"publicip": body})
+}
+
+// listPublicIPs returns the public IPs sorted by ID, it supports the marker and limit paging.
+func (s *Server) listPublicIPs(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	ids := make([]string, 0, len(s.eips))
+	for id := range s.eips {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	query := r.URL.Query()
+	if marker := query.Get("marker"); marker != "" {
+		index := sort.SearchStrings(ids, marker)
+		if index < len(ids) && ids[index] == marker {
+			index++
+		}
+		ids = ids[index:]
+	}
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 && limit < len(ids) {
+		ids = ids[:limit]
+	}
+
+	publicips := make([]interface{}, len(ids))
+	for i, id := range ids {
+		publicips[i] = publicIPBody(s.eips[id])
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"publicips": publicips})
+}
+
+func (s *Server) deletePublicIP(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	id := params["publicip_id"]
+	if _, ok := s.eips[id]; !ok {
+		writeError(w, "eip", http.StatusNotFound, "VPC.0504", fmt.Sprintf("The public IP %s does not exist.", id))
+		return
+	}
+
+	delete(s.eips, id)
+	w.WriteHeader(http.StatusNoContent)
+}
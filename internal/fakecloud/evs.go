@@ -0,0 +1,159 @@
+package fakecloud
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+func (s *Server) evsRoutes() []route {
+	return []route{
+		{http.MethodPost, "/v2.1/{project_id}/cloudvolumes", s.createVolume},
+		{http.MethodGet, "/v2/{project_id}/cloudvolumes/detail", s.listVolumes},
+		{http.MethodGet, "/v2/{project_id}/cloudsnapshots/detail", s.listSnapshots},
+		{http.MethodGet, "/v2/{project_id}/os-quota-sets/{target_project_id}", s.listVolumeQuotas},
+		{http.MethodGet, "/v1/{project_id}/jobs/{job_id}", s.showJob("evs")},
+	}
+}
+
+// createVolume creates a volume with a job, the volume is attached to the server if the
+// server_id is specified.
+func (s *Server) createVolume(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body struct {
+		Volume struct {
+			AvailabilityZone string            `json:"availability_zone"`
+			VolumeType       string            `json:"volume_type"`
+			Size             int               `json:"size"`
+			Name             string            `json:"name"`
+			ImageRef         string            `json:"imageRef"`
+			SnapshotID       string            `json:"snapshot_id"`
+			Metadata         map[string]string `json:"metadata"`
+		} `json:"volume"`
+		ServerID string `json:"server_id"`
+	}
+	if !readJSON(w, r, "evs", &body) {
+		return
+	}
+
+	opts := body.Volume
+	if !containsString(s.Zones, opts.AvailabilityZone) {
+		writeError(w, "evs", http.StatusBadRequest, "EVS.2024", fmt.Sprintf("The availability zone %s is invalid.", opts.AvailabilityZone))
+		return
+	}
+	if opts.ImageRef != "" {
+		if _, ok := s.images[opts.ImageRef]; !ok {
+			writeError(w, "evs", http.StatusBadRequest, "EVS.2063", fmt.Sprintf("The image %s does not exist.", opts.ImageRef))
+			return
+		}
+	}
+	if opts.SnapshotID != "" {
+		if _, ok := s.snapshots[opts.SnapshotID]; !ok {
+			writeError(w, "evs", http.StatusBadRequest, "EVS.2064", fmt.Sprintf("The snapshot %s does not exist.", opts.SnapshotID))
+			return
+		}
+	}
+	if body.ServerID != "" {
+		if _, ok := s.instances[body.ServerID]; !ok {
+			writeError(w, "evs", http.StatusBadRequest, "EVS.2065", fmt.Sprintf("The server %s does not exist.", body.ServerID))
+			return
+		}
+	}
+
+	j := s.newJob("evs", JobCreateVolume, func() map[string]interface{} {
+		volume := &Volume{
+			ID:               s.newID("volume"),
+			Name:             opts.Name,
+			VolumeType:       opts.VolumeType,
+			Size:             opts.Size,
+			AvailabilityZone: opts.AvailabilityZone,
+			SnapshotID:       opts.SnapshotID,
+			ImageRef:         opts.ImageRef,
+			Encrypted:        opts.Metadata["__system__encrypted"] == "1",
+		}
+		if _, ok := s.instances[body.ServerID]; ok {
+			volume.ServerID = body.ServerID
+			volume.Device = s.nextDevice(body.ServerID)
+		}
+		s.volumes[volume.ID] = volume
+		return map[string]interface{}{"volume_id": volume.ID, "name": volume.Name}
+	})
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{"job_id": j.id})
+}
+
+func volumeBody(volume *Volume) map[string]interface{} {
+	status := "available"
+	attachments := []interface{}{}
+	if volume.ServerID != "" {
+		status = "in-use"
+		attachments = append(attachments, map[string]interface{}{
+			"server_id": volume.ServerID,
+			"device":    volume.Device,
+			"volume_id": volume.ID,
+		})
+	}
+	bootable := "false"
+	if volume.Bootable {
+		bootable = "true"
+	}
+	return map[string]interface{}{
+		"id":                volume.ID,
+		"name":              volume.Name,
+		"status":            status,
+		"size":              volume.Size,
+		"volume_type":       volume.VolumeType,
+		"availability_zone": volume.AvailabilityZone,
+		"bootable":          bootable,
+		"encrypted":         volume.Encrypted,
+		"attachments":       attachments,
+		"created_at":        timestamp(),
+	}
+}
+
+func (s *Server) listVolumes(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	query := r.URL.Query()
+
+	var matched []*Volume
+	for _, volume := range s.volumes {
+		if id := query.Get("id"); id != "" && volume.ID != id {
+			continue
+		}
+		if az := query.Get("availability_zone"); az != "" && volume.AvailabilityZone != az {
+			continue
+		}
+		matched = append(matched, volume)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID < matched[j].ID })
+
+	volumes := make([]interface{}, len(matched))
+	for i, volume := range matched {
+		volumes[i] = volumeBody(volume)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"volumes": volumes, "count": len(volumes)})
+}
+
+func (s *Server) listSnapshots(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	query := r.URL.Query()
+
+	var matched []*Snapshot
+	for _, snapshot := range s.snapshots {
+		if id := query.Get("id"); id != "" && snapshot.ID != id {
+			continue
+		}
+		if az := query.Get("availability_zone"); az != "" && snapshot.AvailabilityZone != az {
+			continue
+		}
+		matched = append(matched, snapshot)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID < matched[j].ID })
+
+	snapshots := make([]interface{}, len(matched))
+	for i, snapshot := range matched {
+		snapshots[i] = map[string]interface{}{
+			"id":         snapshot.ID,
+			"status":     "available",
+			"size":       snapshot.Size,
+			"created_at": timestamp(),
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"snapshots": snapshots, "count": len(snapshots)})
+}
@@ -0,0 +1,72 @@
+package fakecloud
+
+import (
+	"crypto/md5" // #nosec G501 -- only used to derive the fake project IDs
+	"fmt"
+	"net/http"
+	"time"
+)
+
+func (s *Server) iamRoutes() []route {
+	return []route{
+		{http.MethodGet, "/v3/regions", s.listRegions},
+		{http.MethodGet, "/v3/projects", s.listProjects},
+		{http.MethodGet, "/v3/auth/domains", s.listAuthDomains},
+		{http.MethodGet, "/v3/auth/projects", s.listProjects},
+		{http.MethodPost, "/v3.0/OS-CREDENTIAL/securitytokens", s.createSecurityToken},
+	}
+}
+
+func (s *Server) listRegions(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	regions := []interface{}{}
+	for _, region := range append([]string{s.Region}, s.OtherRegions...) {
+		regions = append(regions, map[string]interface{}{"id": region, "type": "public", "parent_region_id": nil})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"regions": regions})
+}
+
+// listProjects returns the projects of the regions, they're named after the regions.
+func (s *Server) listProjects(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	projects := []interface{}{}
+	for _, region := range append([]string{s.Region}, s.OtherRegions...) {
+		if name := r.URL.Query().Get("name"); name != "" && name != region {
+			continue
+		}
+		projects = append(projects, map[string]interface{}{
+			"id":        s.RegionProjectID(region),
+			"name":      region,
+			"domain_id": s.DomainID,
+			"enabled":   true,
+			"is_domain": false,
+			"parent_id": s.DomainID,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"projects": projects})
+}
+
+// RegionProjectID returns the ID of the project named after the region, it's ProjectID for Region.
+func (s *Server) RegionProjectID(region string) string {
+	if region == s.Region {
+		return s.ProjectID
+	}
+	return fmt.Sprintf("%x", md5.Sum([]byte(region))) // #nosec G401 -- only a fake ID
+}
+
+func (s *Server) listAuthDomains(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"domains": []interface{}{
+			map[string]interface{}{"id": s.DomainID, "name": s.DomainName, "enabled": true},
+		},
+	})
+}
+
+func (s *Server) createSecurityToken(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"credential": map[string]interface{}{
+			"access":        "FAKETEMPORARYAK",
+			"secret":        "FakeTemporarySecretKey",
+			"securitytoken": "fake-security-token",
+			"expires_at":    time.Now().Add(time.Hour).UTC().Format("2006-01-02T15:04:05.000000Z"),
+		},
+	})
+}
@@ -0,0 +1,450 @@
+package fakecloud
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (s *Server) imsRoutes() []route {
+	return []route{
+		{http.MethodGet, "/v2/cloudimages", s.listImages},
+		{http.MethodPost, "/v2/cloudimages/action", s.createImage},
+		{http.MethodPost, "/v1/cloudimages/wholeimages/action", s.createWholeImage},
+		{http.MethodPost, "/v2/cloudimages/quickimport/action", s.importImageQuick},
+		{http.MethodPost, "/v1/cloudimages/members", s.addImageMembers},
+		{http.MethodDelete, "/v1/cloudimages/members", s.deleteImageMembers},
+		{http.MethodGet, "/v2/images/{image_id}/members", s.listImageMembers},
+		{http.MethodDelete, "/v2/images/{image_id}", s.deleteImage},
+		{http.MethodPost, "/v2/{project_id}/images/{image_id}/tags", s.addImageTag},
+		{http.MethodDelete, "/v2/{project_id}/images/{image_id}/tags/{key}", s.deleteImageTag},
+		{http.MethodGet, "/v1/cloudimages/quota", s.listQuotas(QuotaImages)},
+		{http.MethodGet, "/v1/{project_id}/jobs/{job_id}", s.showJob("ims")},
+	}
+}
+
+type imageTag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// imageTags merges the tags in "key.value" format and the image_tags.
+func imageTags(tags []string, imageTags []imageTag) map[string]string {
+	result := make(map[string]string)
+	for _, tag := range tags {
+		parts := strings.SplitN(tag, ".", 2)
+		if len(parts) == 2 {
+			result[parts[0]] = parts[1]
+		} else {
+			result[parts[0]] = ""
+		}
+	}
+	for _, tag := range imageTags {
+		result[tag.Key] = tag.Value
+	}
+	return result
+}
+
+func (s *Server) imageBody(image *Image) map[string]interface{} {
+	tags := make([]string, 0, len(image.Tags))
+	for k, v := range image.Tags {
+		tags = append(tags, k+"."+v)
+	}
+	sort.Strings(tags)
+
+	visibility := "private"
+	if image.ImageType == "gold" {
+		visibility = "public"
+	}
+	created := image.CreatedAt.UTC().Format(time.RFC3339)
+	return map[string]interface{}{
+		"id":                  image.ID,
+		"name":                image.Name,
+		"status":              image.Status,
+		"__imagetype":         image.ImageType,
+		"__os_type":           image.OsType,
+		"__platform":          image.Platform,
+		"__description":       image.Description,
+		"visibility":          visibility,
+		"min_disk":            image.MinDisk,
+		"min_ram":             0,
+		"disk_format":         "zvhd2",
+		"container_format":    "bare",
+		"protected":           false,
+		"__isregistered":      "true",
+		"__image_source_type": "uds",
+		"self":                "/v2/images/" + image.ID,
+		"file":                "/v2/images/" + image.ID + "/file",
+		"schema":              "/v2/schemas/image",
+		"owner":               s.ProjectID,
+		"tags":                tags,
+		"created_at":          created,
+		"updated_at":          created,
+	}
+}
+
+func (s *Server) listImages(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	query := r.URL.Query()
+
+	var matched []*Image
+	for _, image := range s.images {
+		if id := query.Get("id"); id != "" && image.ID != id {
+			continue
+		}
+		if name := query.Get("name"); name != "" && image.Name != name {
+			continue
+		}
+		if imageType := query.Get("__imagetype"); imageType != "" && image.ImageType != imageType {
+			continue
+		}
+		if status := query.Get("status"); status != "" && image.Status != status {
+			continue
+		}
+		if osType := query.Get("__os_type"); osType != "" && image.OsType != osType {
+			continue
+		}
+		if platform := query.Get("__platform"); platform != "" && image.Platform != platform {
+			continue
+		}
+		if tag := query.Get("tag"); tag != "" {
+			parts := strings.SplitN(tag, ".", 2)
+			if value, ok := image.Tags[parts[0]]; !ok || len(parts) == 2 && value != parts[1] {
+				continue
+			}
+		}
+		matched = append(matched, image)
+	}
+
+	// the images are sorted by the creation time in descending order
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].ID > matched[j].ID
+		}
+		return matched[i].CreatedAt.After(matched[j].CreatedAt)
+	})
+
+	// the pages start after the marker
+	if marker := query.Get("marker"); marker != "" {
+		for i, image := range matched {
+			if image.ID == marker {
+				matched = matched[i+1:]
+				break
+			}
+		}
+	}
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 && limit < len(matched) {
+		matched = matched[:limit]
+	}
+
+	images := make([]interface{}, len(matched))
+	for i, image := range matched {
+		images[i] = s.imageBody(image)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"images": images})
+}
+
+// newImage adds a private image, it's called with the lock held.
+func (s *Server) newImage(name, description string, tags map[string]string, source *Instance) *Image {
+	image := &Image{
+		ID:          s.newID("image"),
+		Name:        name,
+		Status:      "active",
+		ImageType:   "private",
+		OsType:      "Linux",
+		Description: description,
+		Tags:        tags,
+		CreatedAt:   time.Now(),
+	}
+	if source != nil {
+		image.InstanceID = source.ID
+		if sourceImage, ok := s.images[source.ImageRef]; ok {
+			image.OsType = sourceImage.OsType
+			image.Platform = sourceImage.Platform
+			image.MinDisk = sourceImage.MinDisk
+		}
+	}
+	s.images[image.ID] = image
+	return image
+}
+
+func (s *Server) createImage(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body struct {
+		Name        string     `json:"name"`
+		Description string     `json:"description"`
+		InstanceID  string     `json:"instance_id"`
+		ImageURL    string     `json:"image_url"`
+		MinDisk     int        `json:"min_disk"`
+		Tags        []string   `json:"tags"`
+		ImageTags   []imageTag `json:"image_tags"`
+		DataImages  []struct {
+			Name        string `json:"name"`
+			VolumeID    string `json:"volume_id"`
+			Description string `json:"description"`
+		} `json:"data_images"`
+	}
+	if !readJSON(w, r, "ims", &body) {
+		return
+	}
+	tags := imageTags(body.Tags, body.ImageTags)
+
+	switch {
+	case body.InstanceID != "":
+		// system disk image
+		instance, ok := s.instances[body.InstanceID]
+		if !ok {
+			writeError(w, "ims", http.StatusBadRequest, "IMG.0011", fmt.Sprintf("The server %s does not exist.", body.InstanceID))
+			return
+		}
+		j := s.newJob("ims", JobCreateImage, func() map[string]interface{} {
+			image := s.newImage(body.Name, body.Description, tags, instance)
+			return map[string]interface{}{"image_id": image.ID, "image_name": image.Name}
+		})
+		writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": j.id})
+	case len(body.DataImages) > 0:
+		// data disk images
+		for _, opts := range body.DataImages {
+			volume, ok := s.volumes[opts.VolumeID]
+			if !ok || volume.Bootable {
+				writeError(w, "ims", http.StatusBadRequest, "IMG.0012", fmt.Sprintf("The volume %s is not a data disk.", opts.VolumeID))
+				return
+			}
+		}
+		j := s.newJob("ims", JobCreateDataImage, func() map[string]interface{} {
+			results := make([]interface{}, len(body.DataImages))
+			for i, opts := range body.DataImages {
+				image := s.newImage(opts.Name, opts.Description, tags, nil)
+				image.OsType = "Linux"
+				image.VolumeID = opts.VolumeID
+				image.MinDisk = s.volumes[opts.VolumeID].Size
+				results[i] = map[string]interface{}{
+					"status":   "SUCCESS",
+					"job_id":   s.newID("job"),
+					"job_type": "createDataImage",
+					"entities": map[string]interface{}{"image_id": image.ID, "image_name": image.Name},
+				}
+			}
+			return map[string]interface{}{"sub_jobs_result": results}
+		})
+		writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": j.id})
+	case body.ImageURL != "":
+		// import from an OBS object
+		if _, ok := s.lookupObject(body.ImageURL); !ok {
+			writeError(w, "ims", http.StatusBadRequest, "IMG.0013", fmt.Sprintf("The OBS object %s does not exist.", body.ImageURL))
+			return
+		}
+		j := s.newJob("ims", JobImportImage, func() map[string]interface{} {
+			image := s.newImage(body.Name, body.Description, tags, nil)
+			image.MinDisk = body.MinDisk
+			return map[string]interface{}{"image_id": image.ID, "image_name": image.Name}
+		})
+		writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": j.id})
+	default:
+		writeError(w, "ims", http.StatusBadRequest, "IMG.0001", "One of instance_id, data_images and image_url is required.")
+	}
+}
+
+func (s *Server) createWholeImage(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body struct {
+		Name        string     `json:"name"`
+		Description string     `json:"description"`
+		InstanceID  string     `json:"instance_id"`
+		VaultID     string     `json:"vault_id"`
+		Tags        []string   `json:"tags"`
+		ImageTags   []imageTag `json:"image_tags"`
+	}
+	if !readJSON(w, r, "ims", &body) {
+		return
+	}
+
+	instance, ok := s.instances[body.InstanceID]
+	if !ok {
+		writeError(w, "ims", http.StatusBadRequest, "IMG.0011", fmt.Sprintf("The server %s does not exist.", body.InstanceID))
+		return
+	}
+	if body.VaultID == "" {
+		writeError(w, "ims", http.StatusBadRequest, "IMG.0001", "The vault_id is required.")
+		return
+	}
+
+	tags := imageTags(body.Tags, body.ImageTags)
+	j := s.newJob("ims", JobCreateWholeImage, func() map[string]interface{} {
+		image := s.newImage(body.Name, body.Description, tags, instance)
+		return map[string]interface{}{"image_id": image.ID, "image_name": image.Name}
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": j.id})
+}
+
+func (s *Server) importImageQuick(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body struct {
+		Name        string     `json:"name"`
+		Description string     `json:"description"`
+		ImageURL    string     `json:"image_url"`
+		MinDisk     int        `json:"min_disk"`
+		OsVersion   string     `json:"os_version"`
+		Tags        []string   `json:"tags"`
+		ImageTags   []imageTag `json:"image_tags"`
+	}
+	if !readJSON(w, r, "ims", &body) {
+		return
+	}
+	if _, ok := s.lookupObject(body.ImageURL); !ok {
+		writeError(w, "ims", http.StatusBadRequest, "IMG.0013", fmt.Sprintf("The OBS object %s does not exist.", body.ImageURL))
+		return
+	}
+
+	tags := imageTags(body.Tags, body.ImageTags)
+	j := s.newJob("ims", JobImportImage, func() map[string]interface{} {
+		image := s.newImage(body.Name, body.Description, tags, nil)
+		image.MinDisk = body.MinDisk
+		return map[string]interface{}{"image_id": image.ID, "image_name": image.Name}
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": j.id})
+}
+
+func (s *Server) addImageMembers(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body struct {
+		Images   []string `json:"images"`
+		Projects []string `json:"projects"`
+	}
+	if !readJSON(w, r, "ims", &body) {
+		return
+	}
+
+	for _, id := range body.Images {
+		if image, ok := s.images[id]; !ok || image.ImageType != "private" {
+			writeError(w, "ims", http.StatusBadRequest, "IMG.0014", fmt.Sprintf("The image %s can not be shared.", id))
+			return
+		}
+	}
+
+	j := s.newJob("ims", JobAddMembers, func() map[string]interface{} {
+		for _, id := range body.Images {
+			image := s.images[id]
+			for _, project := range body.Projects {
+				if !containsString(image.Members, project) {
+					image.Members = append(image.Members, project)
+				}
+			}
+		}
+		return map[string]interface{}{}
+	})
+	// the members are added without querying the job
+	s.refreshJob(j)
+	j.polls = s.JobPolls
+	s.refreshJob(j)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": j.id})
+}
+
+func (s *Server) deleteImageMembers(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body struct {
+		Images   []string `json:"images"`
+		Projects []string `json:"projects"`
+	}
+	if !readJSON(w, r, "ims", &body) {
+		return
+	}
+
+	for _, id := range body.Images {
+		if _, ok := s.images[id]; !ok {
+			writeError(w, "ims", http.StatusBadRequest, "IMG.0027", fmt.Sprintf("The image %s does not exist.", id))
+			return
+		}
+	}
+
+	j := s.newJob("ims", JobDeleteMembers, func() map[string]interface{} {
+		for _, id := range body.Images {
+			image, ok := s.images[id]
+			if !ok {
+				continue
+			}
+			members := image.Members[:0]
+			for _, member := range image.Members {
+				if !containsString(body.Projects, member) {
+					members = append(members, member)
+				}
+			}
+			image.Members = members
+		}
+		return map[string]interface{}{}
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"job_id": j.id})
+}
+
+func (s *Server) listImageMembers(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	id := params["image_id"]
+	image, ok := s.images[id]
+	if !ok {
+		writeError(w, "ims", http.StatusNotFound, "IMG.0027", fmt.Sprintf("The image %s does not exist.", id))
+		return
+	}
+
+	members := make([]interface{}, len(image.Members))
+	for i, member := range image.Members {
+		members[i] = map[string]interface{}{
+			"image_id":   id,
+			"member_id":  member,
+			"status":     "accepted",
+			"created_at": image.CreatedAt.UTC().Format(time.RFC3339),
+			"updated_at": image.CreatedAt.UTC().Format(time.RFC3339),
+			"schema":     "/v2/schemas/member",
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"members": members, "schema": "/v2/schemas/members"})
+}
+
+// addImageTag adds a tag to the image, the value of an existing key is replaced.
+func (s *Server) addImageTag(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	var body struct {
+		Tag imageTag `json:"tag"`
+	}
+	if !readJSON(w, r, "ims", &body) {
+		return
+	}
+
+	id := params["image_id"]
+	image, ok := s.images[id]
+	if !ok {
+		writeError(w, "ims", http.StatusNotFound, "IMG.0027", fmt.Sprintf("The image %s does not exist.", id))
+		return
+	}
+	if image.Tags == nil {
+		image.Tags = make(map[string]string)
+	}
+	image.Tags[body.Tag.Key] = body.Tag.Value
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteImageTag(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	id := params["image_id"]
+	image, ok := s.images[id]
+	if !ok {
+		writeError(w, "ims", http.StatusNotFound, "IMG.0027", fmt.Sprintf("The image %s does not exist.", id))
+		return
+	}
+	if _, ok := image.Tags[params["key"]]; !ok {
+		writeError(w, "ims", http.StatusNotFound, "IMG.0029", fmt.Sprintf("The tag %s does not exist.", params["key"]))
+		return
+	}
+	delete(image.Tags, params["key"])
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteImage(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	id := params["image_id"]
+	image, ok := s.images[id]
+	if !ok {
+		writeError(w, "ims", http.StatusNotFound, "IMG.0027", fmt.Sprintf("The image %s does not exist.", id))
+		return
+	}
+	if image.ImageType != "private" {
+		writeError(w, "ims", http.StatusForbidden, "IMG.0028", fmt.Sprintf("The image %s can not be deleted.", id))
+		return
+	}
+
+	delete(s.images, id)
+	w.WriteHeader(http.StatusNoContent)
+}
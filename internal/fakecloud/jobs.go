@@ -0,0 +1,126 @@
+package fakecloud
+
+import (
+	"net/http"
+)
+
+// The types of the asynchronous jobs, they are used to script the job failures with FailJob.
+const (
+	JobCreateServer     = "createServer"
+	JobDeleteServer     = "deleteServer"
+	JobStopServer       = "batchStopServer"
+	JobAttachVolume     = "attachVolume"
+	JobDetachVolume     = "detachVolume"
+	JobCreateVolume     = "createVolume"
+	JobCreateImage      = "createImageByInstance"
+	JobCreateDataImage  = "createDataImageByInstance"
+	JobCreateWholeImage = "createWholeImageByServer"
+	JobImportImage      = "createImageByFile"
+	JobAddMembers       = "batchAddMembers"
+	JobDeleteMembers    = "batchDeleteMembers"
+)
+
+// job is an asynchronous job, it's running in the first JobPolls queries and then completes.
+type job struct {
+	id        string
+	service   string
+	jobType   string
+	beginTime string
+	endTime   string
+	polls     int
+	status    string
+	errorCode string
+	reason    string
+	// complete applies the changes of the job and returns the entities, it's called with the lock held
+	complete func() map[string]interface{}
+	entities map[string]interface{}
+}
+
+// newJob creates a job of the service, it's called with the lock held.
+func (s *Server) newJob(service, jobType string, complete func() map[string]interface{}) *job {
+	j := &job{
+		id:        s.newID("job"),
+		service:   service,
+		jobType:   jobType,
+		beginTime: timestamp(),
+		status:    "INIT",
+		complete:  complete,
+		entities:  map[string]interface{}{},
+	}
+
+	for i, failure := range s.failures {
+		if failure.jobType == jobType {
+			j.errorCode = failure.errorCode
+			j.reason = failure.reason
+			s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			break
+		}
+	}
+
+	s.jobs[j.id] = j
+	return j
+}
+
+// refresh advances the job, it's called with the lock held.
+func (s *Server) refreshJob(j *job) {
+	if j.status == "SUCCESS" || j.status == "FAIL" {
+		return
+	}
+	if j.polls < s.JobPolls {
+		j.polls++
+		j.status = "RUNNING"
+		return
+	}
+
+	j.endTime = timestamp()
+	if j.reason != "" {
+		j.status = "FAIL"
+		return
+	}
+	if j.complete != nil {
+		j.entities = j.complete()
+	}
+	j.status = "SUCCESS"
+}
+
+// advanceJobs refreshes the jobs of the type which are observed through the resources rather than
+// the job queries, e.g. the server is queried until it's deleted. It's called with the lock held.
+func (s *Server) advanceJobs(service, jobType string) {
+	for _, j := range s.jobs {
+		if j.service == service && j.jobType == jobType {
+			s.refreshJob(j)
+		}
+	}
+}
+
+func (j *job) body() map[string]interface{} {
+	body := map[string]interface{}{
+		"job_id":     j.id,
+		"job_type":   j.jobType,
+		"status":     j.status,
+		"begin_time": j.beginTime,
+		"entities":   j.entities,
+	}
+	if j.endTime != "" {
+		body["end_time"] = j.endTime
+	}
+	if j.status == "FAIL" {
+		body["error_code"] = j.errorCode
+		body["fail_reason"] = j.reason
+	}
+	return body
+}
+
+// showJob handles the queries of the jobs of the service.
+func (s *Server) showJob(service string) func(http.ResponseWriter, *http.Request, map[string]string) {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		j, ok := s.jobs[params["job_id"]]
+		if !ok || j.service != service {
+			writeError(w, service, http.StatusNotFound, "Common.0024", "the job does not exist")
+			return
+		}
+
+		s.refreshJob(j)
+		writeJSON(w, http.StatusOK, j.body())
+	}
+}
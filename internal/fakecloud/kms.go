@@ -0,0 +1,34 @@
+package fakecloud
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func (s *Server) kmsRoutes() []route {
+	return []route{
+		{http.MethodPost, "/v1.0/{project_id}/kms/describe-key", s.describeKey},
+	}
+}
+
+func (s *Server) describeKey(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body struct {
+		KeyID string `json:"key_id"`
+	}
+	if !readJSON(w, r, "kms", &body) {
+		return
+	}
+
+	key, ok := s.keys[body.KeyID]
+	if !ok {
+		writeError(w, "kms", http.StatusNotFound, "KMS.0207", fmt.Sprintf("The key %s does not exist.", body.KeyID))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"key_info": map[string]interface{}{
+			"key_id":    key.ID,
+			"key_alias": key.Alias,
+			"key_state": key.State,
+		},
+	})
+}
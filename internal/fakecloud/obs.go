@@ -0,0 +1,77 @@
+package fakecloud
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// obsHandler serves the OBS requests in the path style, which is used by the OBS SDK when the
+// endpoint host is an IP address: /{bucket} and /{bucket}/{key}.
+func (s *Server) obsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.intercept("obs", w, r) {
+			return
+		}
+
+		bucket, key, _ := strings.Cut(strings.TrimLeft(r.URL.Path, "/"), "/")
+
+		var data []byte
+		if r.Method == http.MethodPut {
+			var err error
+			if data, err = io.ReadAll(r.Body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		objects, ok := s.buckets[bucket]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		switch {
+		case key == "" && (r.Method == http.MethodHead || r.Method == http.MethodGet):
+			w.WriteHeader(http.StatusOK)
+		case key == "":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.Method == http.MethodPut:
+			objects[key] = data
+			sum := md5.Sum(data)
+			w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet || r.Method == http.MethodHead:
+			object, ok := objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			if r.Method == http.MethodGet {
+				_, _ = w.Write(object)
+			}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// lookupObject returns the object of the image URL in the format "bucket:key", it's called
+// with the lock held.
+func (s *Server) lookupObject(imageURL string) ([]byte, bool) {
+	bucket, key, ok := strings.Cut(imageURL, ":")
+	if !ok {
+		return nil, false
+	}
+	data, ok := s.buckets[bucket][key]
+	return data, ok
+}
@@ -0,0 +1,139 @@
+package fakecloud
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// The quota types in Server.Quotas.
+const (
+	QuotaInstances = "instances"
+	QuotaCores     = "cores"
+	QuotaRAM       = "ram"
+	QuotaVolumes   = "volumes"
+	QuotaGigabytes = "gigabytes"
+	QuotaVPCs      = "vpc"
+	QuotaSubnets   = "subnet"
+	QuotaPublicIPs = "publicIp"
+	QuotaImages    = "image"
+)
+
+// quota returns the limit and the usage of the quota type, the limit is -1 if it's unlimited.
+// It's called with the lock held.
+func (s *Server) quota(quotaType string) (int, int) {
+	limit, ok := s.Quotas[quotaType]
+	if !ok {
+		limit = -1
+	}
+	return limit, s.quotaUsage(quotaType)
+}
+
+// quotaUsage counts the usage of the quota type from the existing resources, it's called with the
+// lock held.
+func (s *Server) quotaUsage(quotaType string) int {
+	used := 0
+	switch quotaType {
+	case QuotaInstances:
+		used = len(s.instances)
+	case QuotaCores, QuotaRAM:
+		for _, instance := range s.instances {
+			flavor, _ := s.flavor(instance.FlavorRef)
+			if quotaType == QuotaCores {
+				used += flavor.Vcpus
+			} else {
+				used += flavor.RAM
+			}
+		}
+	case QuotaVolumes:
+		used = len(s.volumes)
+	case QuotaGigabytes:
+		for _, volume := range s.volumes {
+			used += volume.Size
+		}
+	case QuotaVPCs:
+		used = len(s.vpcs)
+	case QuotaSubnets:
+		used = len(s.subnets)
+	case QuotaPublicIPs:
+		used = len(s.eips)
+	case QuotaImages:
+		for _, image := range s.images {
+			if image.ImageType == "private" {
+				used++
+			}
+		}
+	}
+	return used
+}
+
+func (s *Server) flavor(id string) (Flavor, bool) {
+	for _, flavor := range s.Flavors {
+		if flavor.ID == id {
+			return flavor, true
+		}
+	}
+	return Flavor{}, false
+}
+
+func (s *Server) showServerLimits(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	maxInstances, instances := s.quota(QuotaInstances)
+	maxCores, cores := s.quota(QuotaCores)
+	maxRAM, ram := s.quota(QuotaRAM)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"absolute": map[string]interface{}{
+			"maxTotalInstances":  maxInstances,
+			"totalInstancesUsed": instances,
+			"maxTotalCores":      maxCores,
+			"totalCoresUsed":     cores,
+			"maxTotalRAMSize":    maxRAM,
+			"totalRAMUsed":       ram,
+		},
+	})
+}
+
+func (s *Server) listFlavors(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	flavors := make([]interface{}, len(s.Flavors))
+	for i, flavor := range s.Flavors {
+		flavors[i] = map[string]interface{}{
+			"id":    flavor.ID,
+			"name":  flavor.ID,
+			"vcpus": strconv.Itoa(flavor.Vcpus),
+			"ram":   flavor.RAM,
+			"disk":  "0",
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"flavors": flavors})
+}
+
+func (s *Server) listVolumeQuotas(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	quotaDetail := func(quotaType string) map[string]interface{} {
+		limit, used := s.quota(quotaType)
+		return map[string]interface{}{"in_use": used, "limit": limit, "reserved": 0}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"quota_set": map[string]interface{}{
+			"id":        params["target_project_id"],
+			"volumes":   quotaDetail(QuotaVolumes),
+			"gigabytes": quotaDetail(QuotaGigabytes),
+		},
+	})
+}
+
+// listQuotas returns the quotas of the types in the VPC format, it's used by VPC, EIP and IMS.
+func (s *Server) listQuotas(quotaTypes ...string) func(http.ResponseWriter, *http.Request, map[string]string) {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		resources := make([]interface{}, 0, len(quotaTypes))
+		for _, quotaType := range quotaTypes {
+			if filter := r.URL.Query().Get("type"); filter != "" && filter != quotaType {
+				continue
+			}
+			limit, used := s.quota(quotaType)
+			resources = append(resources, map[string]interface{}{
+				"type": quotaType, "used": used, "quota": limit, "min": 0,
+			})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"quotas": map[string]interface{}{"resources": resources},
+		})
+	}
+}
@@ -0,0 +1,407 @@
+package fakecloud
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// The resources of the fake cloud. The resources added with the AddXxx methods are seeded, they
+// are not reported by Leftovers.
+
+// Keypair is an SSH key pair.
+type Keypair struct {
+	Name       string
+	PublicKey  string
+	PrivateKey string
+
+	key *rsa.PrivateKey
+}
+
+// Instance is an ECS server.
+type Instance struct {
+	ID               string
+	Name             string
+	Status           string
+	ImageRef         string
+	FlavorRef        string
+	AvailabilityZone string
+	VpcID            string
+	SubnetIDs        []string
+	PrivateIP        string
+	PublicIPID       string
+	KeyName          string
+	Metadata         map[string]string
+	// EncryptedPassword is the password of a Windows server encrypted with the public key of the key pair.
+	EncryptedPassword string
+	CreatedAt         time.Time
+
+	seeded bool
+}
+
+// Volume is an EVS volume. The system volume of a server is attached as /dev/vda.
+type Volume struct {
+	ID               string
+	Name             string
+	VolumeType       string
+	Size             int
+	AvailabilityZone string
+	ServerID         string
+	Device           string
+	Bootable         bool
+	SnapshotID       string
+	ImageRef         string
+	Encrypted        bool
+
+	seeded bool
+}
+
+// Snapshot is an EVS snapshot.
+type Snapshot struct {
+	ID               string
+	Size             int
+	AvailabilityZone string
+}
+
+// Image is an IMS image. The ImageType is "gold" for the public images and "private" for the
+// images created by the requests.
+type Image struct {
+	ID          string
+	Name        string
+	Status      string
+	ImageType   string
+	OsType      string
+	Platform    string
+	MinDisk     int
+	Description string
+	// InstanceID is the ID of the server the image was created from, VolumeID is the ID of the volume
+	// of a data disk image.
+	InstanceID string
+	VolumeID   string
+	Tags       map[string]string
+	Members    []string
+	CreatedAt  time.Time
+}
+
+// VPC is a virtual private cloud.
+type VPC struct {
+	ID     string
+	Name   string
+	CIDR   string
+	Status string
+
+	seeded bool
+}
+
+// Subnet is a subnet of a VPC.
+type Subnet struct {
+	ID        string
+	VpcID     string
+	Name      string
+	CIDR      string
+	GatewayIP string
+	Status    string
+
+	seeded bool
+}
+
+// PublicIP is an EIP, the PortID is set when it's associated with a server.
+type PublicIP struct {
+	ID            string
+	Address       string
+	Status        string
+	PortID        string
+	BandwidthSize int
+
+	seeded bool
+}
+
+// SecurityGroup is a security group.
+type SecurityGroup struct {
+	ID   string
+	Name string
+}
+
+// Flavor is a flavor of the servers, the RAM is in MB.
+type Flavor struct {
+	ID    string
+	Vcpus int
+	RAM   int
+}
+
+// Key is a KMS key, the State is "2" if the key is enabled, "3" if it's disabled and "4" if it's
+// pending deletion.
+type Key struct {
+	ID    string
+	Alias string
+	State string
+}
+
+// Vault is a CBR vault, the Status is "available" if it can be used.
+type Vault struct {
+	ID         string
+	Name       string
+	Status     string
+	ObjectType string
+}
+
+// AddImage adds an image, e.g. a public image used as the source image. It returns the image ID.
+func (s *Server) AddImage(image Image) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if image.ID == "" {
+		image.ID = s.newID("image")
+	}
+	if image.Status == "" {
+		image.Status = "active"
+	}
+	if image.ImageType == "" {
+		image.ImageType = "gold"
+	}
+	if image.OsType == "" {
+		image.OsType = "Linux"
+	}
+	if image.CreatedAt.IsZero() {
+		image.CreatedAt = time.Now()
+	}
+	// the tags are changed by the requests, don't share them with the caller
+	tags := make(map[string]string, len(image.Tags))
+	for k, v := range image.Tags {
+		tags[k] = v
+	}
+	image.Tags = tags
+	s.images[image.ID] = &image
+	return image.ID
+}
+
+// AddServer adds a running server, which is not a leftover. It returns the server ID.
+func (s *Server) AddServer(instance Instance) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if instance.ID == "" {
+		instance.ID = s.newID("server")
+	}
+	if instance.Status == "" {
+		instance.Status = "ACTIVE"
+	}
+	if instance.CreatedAt.IsZero() {
+		instance.CreatedAt = time.Now()
+	}
+	instance.seeded = true
+	s.instances[instance.ID] = &instance
+	return instance.ID
+}
+
+// AddVolume adds a volume which is not attached to any server. It returns the volume ID.
+func (s *Server) AddVolume(volume Volume) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if volume.ID == "" {
+		volume.ID = s.newID("volume")
+	}
+	if volume.AvailabilityZone == "" {
+		volume.AvailabilityZone = s.Zones[0]
+	}
+	volume.seeded = true
+	s.volumes[volume.ID] = &volume
+	return volume.ID
+}
+
+// AddSnapshot adds a snapshot. It returns the snapshot ID.
+func (s *Server) AddSnapshot(snapshot Snapshot) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if snapshot.ID == "" {
+		snapshot.ID = s.newID("snapshot")
+	}
+	if snapshot.AvailabilityZone == "" {
+		snapshot.AvailabilityZone = s.Zones[0]
+	}
+	s.snapshots[snapshot.ID] = &snapshot
+	return snapshot.ID
+}
+
+// AddNetwork adds a VPC with a subnet. It returns the VPC ID and the subnet ID.
+func (s *Server) AddNetwork() (string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vpc := &VPC{ID: s.newID("vpc"), Name: "vpc-default", CIDR: "192.168.0.0/16", Status: "OK", seeded: true}
+	subnet := &Subnet{ID: s.newID("subnet"), VpcID: vpc.ID, Name: "subnet-default", CIDR: "192.168.0.0/24",
+		GatewayIP: "192.168.0.1", Status: "ACTIVE", seeded: true}
+	s.vpcs[vpc.ID] = vpc
+	s.subnets[subnet.ID] = subnet
+	return vpc.ID, subnet.ID
+}
+
+// AddPublicIP adds a public IP, it's associated with a port if the PortID is set. It returns the ID.
+func (s *Server) AddPublicIP(eip PublicIP) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if eip.ID == "" {
+		eip.ID = s.newID("eip")
+	}
+	if eip.Address == "" {
+		eip.Address = s.newAddress()
+	}
+	if eip.Status == "" {
+		eip.Status = "DOWN"
+		if eip.PortID != "" {
+			eip.Status = "ACTIVE"
+		}
+	}
+	eip.seeded = true
+	s.eips[eip.ID] = &eip
+	return eip.ID
+}
+
+// AddSecurityGroup adds a security group. It returns the security group ID.
+func (s *Server) AddSecurityGroup(secgroup SecurityGroup) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if secgroup.ID == "" {
+		secgroup.ID = s.newID("secgroup")
+	}
+	if secgroup.Name == "" {
+		secgroup.Name = "default"
+	}
+	s.secgroups[secgroup.ID] = &secgroup
+	return secgroup.ID
+}
+
+// AddKey adds a KMS key, it's enabled if the State is not set. It returns the key ID.
+func (s *Server) AddKey(key Key) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key.ID == "" {
+		key.ID = s.newID("key")
+	}
+	if key.State == "" {
+		key.State = "2"
+	}
+	s.keys[key.ID] = &key
+	return key.ID
+}
+
+// AddVault adds a CBR vault of the servers, it's available if the Status is not set. It returns the
+// vault ID.
+func (s *Server) AddVault(vault Vault) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if vault.ID == "" {
+		vault.ID = s.newID("vault")
+	}
+	if vault.Status == "" {
+		vault.Status = "available"
+	}
+	if vault.ObjectType == "" {
+		vault.ObjectType = "server"
+	}
+	s.vaults[vault.ID] = &vault
+	return vault.ID
+}
+
+// AddBucket adds an empty OBS bucket.
+func (s *Server) AddBucket(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buckets[name] = make(map[string][]byte)
+}
+
+// Image returns a copy of the image.
+func (s *Server) Image(id string) (Image, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	image, ok := s.images[id]
+	if !ok {
+		return Image{}, false
+	}
+	return *image, true
+}
+
+// Images returns the copies of the images with the image type, e.g. "private", or all the images
+// if the type is empty. They are sorted by the creation time.
+func (s *Server) Images(imageType string) []Image {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var images []Image
+	for _, image := range s.images {
+		if imageType == "" || image.ImageType == imageType {
+			images = append(images, *image)
+		}
+	}
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].CreatedAt.Equal(images[j].CreatedAt) {
+			return images[i].ID < images[j].ID
+		}
+		return images[i].CreatedAt.Before(images[j].CreatedAt)
+	})
+	return images
+}
+
+// Instances returns the copies of the existing servers.
+func (s *Server) Instances() []Instance {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	instances := make([]Instance, 0, len(s.instances))
+	for _, instance := range s.instances {
+		instances = append(instances, *instance)
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+	return instances
+}
+
+// PublicIP returns a copy of the public IP.
+func (s *Server) PublicIP(id string) (PublicIP, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	eip, ok := s.eips[id]
+	if !ok {
+		return PublicIP{}, false
+	}
+	return *eip, true
+}
+
+// Volume returns a copy of the volume.
+func (s *Server) Volume(id string) (Volume, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	volume, ok := s.volumes[id]
+	if !ok {
+		return Volume{}, false
+	}
+	return *volume, true
+}
+
+// Object returns the content of the OBS object.
+func (s *Server) Object(bucket, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	objects, ok := s.buckets[bucket]
+	if !ok {
+		return nil, false
+	}
+	data, ok := objects[key]
+	return data, ok
+}
+
+// newAddress returns a new public IP address, it's called with the lock held.
+func (s *Server) newAddress() string {
+	s.sequence++
+	return fmt.Sprintf("192.0.2.%d", s.sequence%250+1)
+}
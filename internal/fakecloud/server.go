@@ -0,0 +1,430 @@
+// Package fakecloud is an in-memory stand-in of the Huawei Cloud APIs used by the plugin: IAM, ECS,
+// IMS, VPC, EIP, EVS, KMS, CBR and OBS. Every service is served by its own httptest server, the endpoints
+// are passed to the plugin through the `endpoints` option, so the builder and the post-processor
+// can be tested end to end without an account.
+//
+// The requests are not authenticated. The responses can be scripted with rules to return errors
+// or to be delayed, and the asynchronous jobs can be scripted to fail.
+package fakecloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	DefaultRegion     = "cn-north-4"
+	DefaultProjectID  = "0970dd7a1300f5672ff2c003c60ae115"
+	DefaultDomainID   = "0970dd7a0f00f5650f32c0136c4d5ba0"
+	DefaultDomainName = "packer-test"
+)
+
+// Services lists the services served by the fake cloud.
+var Services = []string{"iam", "ecs", "ims", "vpc", "eip", "evs", "kms", "cbr", "obs"}
+
+// Request is a request received by the fake cloud.
+type Request struct {
+	Service string
+	Method  string
+	Path    string
+}
+
+func (r Request) String() string {
+	return fmt.Sprintf("%s %s %s", r.Service, r.Method, r.Path)
+}
+
+// Rule scripts the response of the requests it matches. The request is delayed by Delay,
+// and then answered with an error if Status is set.
+type Rule struct {
+	Service string // the service, e.g. "ecs", it matches all the services if empty
+	Method  string // the HTTP method, it matches all the methods if empty
+	Path    string // a regular expression matched against the URL path, it matches all paths if empty
+
+	Status  int    // the HTTP status code of the error response, the request is handled if 0
+	Code    string // the error code, e.g. "Ecs.0319"
+	Message string // the error message
+
+	Delay time.Duration // the delay before handling the request
+	Times int           // the number of requests the rule applies to, 0 means unlimited
+
+	path *regexp.Regexp
+	hits int
+}
+
+func (r *Rule) matches(req Request) bool {
+	if r.Times > 0 && r.hits >= r.Times {
+		return false
+	}
+	if r.Service != "" && r.Service != req.Service {
+		return false
+	}
+	if r.Method != "" && r.Method != req.Method {
+		return false
+	}
+	return r.path == nil || r.path.MatchString(req.Path)
+}
+
+// jobFailure makes the next job of the type fail.
+type jobFailure struct {
+	jobType   string
+	errorCode string
+	reason    string
+}
+
+// Server is the fake cloud. The exported fields can be changed before the first request.
+type Server struct {
+	Region     string
+	ProjectID  string
+	DomainID   string
+	DomainName string
+	Zones      []string
+	// OtherRegions are listed by IAM besides Region, their projects are named after them.
+	// Only the IAM requests are served for them.
+	OtherRegions []string
+	// JobPolls is the number of queries in which a job is still running.
+	JobPolls int
+	// ConsoleOutput is the console log of the servers.
+	ConsoleOutput string
+	// Flavors are the flavors of the servers.
+	Flavors []Flavor
+	// Quotas are the limits of the resources by the quota types, e.g. "instances", a missing type is
+	// unlimited. The usage is counted from the existing resources, see quotaUsage.
+	Quotas map[string]int
+
+	mu        sync.Mutex
+	servers   map[string]*httptest.Server
+	rules     []*Rule
+	failures  []jobFailure
+	requests  []Request
+	sequence  int
+	requestID int
+
+	jobs      map[string]*job
+	keypairs  map[string]*Keypair
+	instances map[string]*Instance
+	volumes   map[string]*Volume
+	snapshots map[string]*Snapshot
+	images    map[string]*Image
+	vpcs      map[string]*VPC
+	subnets   map[string]*Subnet
+	eips      map[string]*PublicIP
+	secgroups map[string]*SecurityGroup
+	keys      map[string]*Key
+	vaults    map[string]*Vault
+	buckets   map[string]map[string][]byte
+}
+
+// New starts the fake cloud, it's closed when the test finishes.
+func New(t testing.TB) *Server {
+	s := &Server{
+		Region:     DefaultRegion,
+		ProjectID:  DefaultProjectID,
+		DomainID:   DefaultDomainID,
+		DomainName: DefaultDomainName,
+		Zones:      []string{DefaultRegion + "a", DefaultRegion + "b"},
+		JobPolls:   1,
+		ConsoleOutput: "[    0.000000] Linux version 5.15.0-91-generic\n" +
+			"[    2.481234] systemd[1]: Reached target Multi-User System.\n" +
+			"Ubuntu 22.04.3 LTS ecs-packer ttyS0\n",
+		Flavors: []Flavor{
+			{ID: "s6.large.2", Vcpus: 2, RAM: 4096},
+			{ID: "c6.large.2", Vcpus: 2, RAM: 4096},
+		},
+		Quotas: map[string]int{
+			QuotaInstances: 20,
+			QuotaCores:     80,
+			QuotaRAM:       163840,
+			QuotaVolumes:   50,
+			QuotaGigabytes: 12500,
+			QuotaVPCs:      5,
+			QuotaSubnets:   100,
+			QuotaPublicIPs: 10,
+			QuotaImages:    100,
+		},
+
+		servers:   make(map[string]*httptest.Server),
+		jobs:      make(map[string]*job),
+		keypairs:  make(map[string]*Keypair),
+		instances: make(map[string]*Instance),
+		volumes:   make(map[string]*Volume),
+		snapshots: make(map[string]*Snapshot),
+		images:    make(map[string]*Image),
+		vpcs:      make(map[string]*VPC),
+		subnets:   make(map[string]*Subnet),
+		eips:      make(map[string]*PublicIP),
+		secgroups: make(map[string]*SecurityGroup),
+		keys:      make(map[string]*Key),
+		vaults:    make(map[string]*Vault),
+		buckets:   make(map[string]map[string][]byte),
+	}
+
+	routes := map[string][]route{
+		"iam": s.iamRoutes(),
+		"ecs": s.ecsRoutes(),
+		"ims": s.imsRoutes(),
+		"vpc": s.vpcRoutes(),
+		"eip": s.eipRoutes(),
+		"evs": s.evsRoutes(),
+		"kms": s.kmsRoutes(),
+		"cbr": s.cbrRoutes(),
+	}
+	for service, serviceRoutes := range routes {
+		s.servers[service] = httptest.NewServer(s.handler(service, serviceRoutes))
+	}
+	s.servers["obs"] = httptest.NewServer(s.obsHandler())
+
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Close shuts down the servers of all the services.
+func (s *Server) Close() {
+	for _, server := range s.servers {
+		server.Close()
+	}
+}
+
+// Endpoints returns the endpoints of all the services, it's the value of the `endpoints` option.
+func (s *Server) Endpoints() map[string]string {
+	endpoints := make(map[string]string, len(s.servers))
+	for service, server := range s.servers {
+		endpoints[service] = server.URL + "/"
+	}
+	return endpoints
+}
+
+// Endpoint returns the endpoint of the service.
+func (s *Server) Endpoint(service string) string {
+	return s.servers[service].URL + "/"
+}
+
+// AddRule scripts the responses of the matched requests, the rules are checked in order.
+func (s *Server) AddRule(rule Rule) {
+	if rule.Path != "" {
+		rule.path = regexp.MustCompile(rule.Path)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = append(s.rules, &rule)
+}
+
+// FailJob makes the next job of the type fail with the error code and reason. The job types
+// are the Job constants.
+func (s *Server) FailJob(jobType, errorCode, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, jobFailure{jobType: jobType, errorCode: errorCode, reason: reason})
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// CountRequests returns the number of requests to the service with the method and a path
+// matching the regular expression.
+func (s *Server) CountRequests(service, method, path string) int {
+	re := regexp.MustCompile(path)
+	count := 0
+	for _, req := range s.Requests() {
+		if req.Service == service && req.Method == method && re.MatchString(req.Path) {
+			count++
+		}
+	}
+	return count
+}
+
+// Leftovers returns the temporary resources that still exist: the servers, volumes, VPCs,
+// subnets, public IPs and key pairs which were created by the requests.
+func (s *Server) Leftovers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var leftovers []string
+	for name := range s.keypairs {
+		leftovers = append(leftovers, "keypair "+name)
+	}
+	for id, instance := range s.instances {
+		if !instance.seeded {
+			leftovers = append(leftovers, "server "+id)
+		}
+	}
+	for id, v := range s.volumes {
+		if !v.seeded {
+			leftovers = append(leftovers, "volume "+id)
+		}
+	}
+	for id, v := range s.vpcs {
+		if !v.seeded {
+			leftovers = append(leftovers, "vpc "+id)
+		}
+	}
+	for id, subnet := range s.subnets {
+		if !subnet.seeded {
+			leftovers = append(leftovers, "subnet "+id)
+		}
+	}
+	for id, eip := range s.eips {
+		if !eip.seeded {
+			leftovers = append(leftovers, "publicip "+id)
+		}
+	}
+	sort.Strings(leftovers)
+	return leftovers
+}
+
+// newID returns a new resource ID with the prefix.
+func (s *Server) newID(prefix string) string {
+	s.sequence++
+	return fmt.Sprintf("%s-%04d", prefix, s.sequence)
+}
+
+// route handles the requests matching the method and path, the path segments in braces are
+// captured as parameters, and {project_id} must be the project ID of the server.
+type route struct {
+	method  string
+	path    string
+	handler func(w http.ResponseWriter, r *http.Request, params map[string]string)
+}
+
+func (s *Server) match(rt route, method, path string) (map[string]string, bool) {
+	if rt.method != method {
+		return nil, false
+	}
+
+	want := strings.Split(strings.Trim(rt.path, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(want) != len(got) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, segment := range want {
+		switch {
+		case segment == "{project_id}":
+			if got[i] != s.ProjectID {
+				return nil, false
+			}
+		case strings.HasPrefix(segment, "{"):
+			params[strings.Trim(segment, "{}")] = got[i]
+		case segment != got[i]:
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (s *Server) handler(service string, routes []route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.intercept(service, w, r) {
+			return
+		}
+
+		// the paths are cleaned since the endpoints end with a slash
+		path := "/" + strings.TrimLeft(r.URL.Path, "/")
+		for _, rt := range routes {
+			if params, ok := s.match(rt, r.Method, path); ok {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				rt.handler(w, r, params)
+				return
+			}
+		}
+		writeError(w, service, http.StatusNotFound, "APIGW.0101", fmt.Sprintf("the API %s %s does not exist", r.Method, path))
+	})
+}
+
+// intercept records the request and applies the rules, it returns false if the request
+// has been answered with an error or cancelled.
+func (s *Server) intercept(service string, w http.ResponseWriter, r *http.Request) bool {
+	req := Request{Service: service, Method: r.Method, Path: "/" + strings.TrimLeft(r.URL.Path, "/")}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.requestID++
+	w.Header().Set("X-Request-Id", fmt.Sprintf("fake-request-%06d", s.requestID))
+
+	var delay time.Duration
+	var failure *Rule
+	for _, rule := range s.rules {
+		if !rule.matches(req) {
+			continue
+		}
+		rule.hits++
+		delay += rule.Delay
+		if rule.Status != 0 {
+			failure = rule
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return false
+		}
+	}
+
+	if failure != nil {
+		message := failure.Message
+		if message == "" {
+			message = http.StatusText(failure.Status)
+		}
+		writeError(w, service, failure.Status, failure.Code, message)
+		return false
+	}
+	return true
+}
+
+// writeError writes the error in the format of the service.
+func writeError(w http.ResponseWriter, service string, status int, code, message string) {
+	switch service {
+	case "ecs":
+		writeJSON(w, status, map[string]interface{}{
+			"error": map[string]interface{}{"code": code, "message": message},
+		})
+	case "obs":
+		w.WriteHeader(status)
+	default:
+		writeJSON(w, status, map[string]interface{}{"error_code": code, "error_msg": message})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	if body != nil {
+		_ = json.NewEncoder(w).Encode(body)
+	}
+}
+
+// readJSON decodes the request body, it answers the request with an error if the body is invalid.
+func readJSON(w http.ResponseWriter, r *http.Request, service string, body interface{}) bool {
+	data, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(data, body)
+	}
+	if err != nil {
+		writeError(w, service, http.StatusBadRequest, "Common.0001", fmt.Sprintf("invalid request body: %s", err))
+		return false
+	}
+	return true
+}
+
+func timestamp() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,160 @@
+package fakecloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func doRequest(t *testing.T, ctx context.Context, method, url, body string) (int, map[string]interface{}) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, nil
+		}
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("X-Request-Id") == "" {
+		t.Errorf("the response of %s %s has no request ID", method, url)
+	}
+
+	var result map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func TestServer_Rules(t *testing.T) {
+	s := New(t)
+	url := s.Endpoint("iam") + "v3/regions"
+
+	s.AddRule(Rule{Service: "iam", Path: "/v3/regions$", Status: http.StatusTooManyRequests, Code: "APIGW.0308", Times: 1})
+
+	status, body := doRequest(t, context.Background(), http.MethodGet, url, "")
+	if status != http.StatusTooManyRequests || body["error_code"] != "APIGW.0308" {
+		t.Fatalf("expected the scripted error, got %d %v", status, body)
+	}
+
+	// the rule applies to the first request only
+	if status, _ := doRequest(t, context.Background(), http.MethodGet, url, ""); status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+
+	if n := s.CountRequests("iam", http.MethodGet, "^/v3/regions$"); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestServer_RuleDelay(t *testing.T) {
+	s := New(t)
+	s.AddRule(Rule{Service: "iam", Delay: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if status, _ := doRequest(t, ctx, http.MethodGet, s.Endpoint("iam")+"v3/regions", ""); status != 0 {
+		t.Fatalf("the request should be delayed until the context is done, got %d", status)
+	}
+}
+
+func TestServer_ErrorFormats(t *testing.T) {
+	s := New(t)
+
+	status, body := doRequest(t, context.Background(), http.MethodGet,
+		s.Endpoint("ecs")+"v1/"+s.ProjectID+"/cloudservers/server-9999", "")
+	if status != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", status)
+	}
+	if detail, ok := body["error"].(map[string]interface{}); !ok || detail["code"] != "Ecs.0114" {
+		t.Fatalf("expected the ECS error format, got %v", body)
+	}
+
+	// the project ID must match
+	status, body = doRequest(t, context.Background(), http.MethodGet, s.Endpoint("vpc")+"v1/other/vpcs/vpc-0001", "")
+	if status != http.StatusNotFound || body["error_code"] != "APIGW.0101" {
+		t.Fatalf("expected the API not found error, got %d %v", status, body)
+	}
+}
+
+func TestServer_FailJob(t *testing.T) {
+	s := New(t)
+	s.FailJob(JobCreateVolume, "EVS.5400", "the volume quota is insufficient")
+
+	createURL := s.Endpoint("evs") + "v2.1/" + s.ProjectID + "/cloudvolumes"
+	volume := `{"volume": {"availability_zone": "` + s.Zones[0] + `", "volume_type": "SSD", "size": 10}}`
+
+	_, body := doRequest(t, context.Background(), http.MethodPost, createURL, volume)
+	failedJob, _ := body["job_id"].(string)
+	_, body = doRequest(t, context.Background(), http.MethodPost, createURL, volume)
+	job, _ := body["job_id"].(string)
+
+	jobURL := s.Endpoint("evs") + "v1/" + s.ProjectID + "/jobs/"
+	for i := 0; i < s.JobPolls; i++ {
+		if _, body := doRequest(t, context.Background(), http.MethodGet, jobURL+failedJob, ""); body["status"] != "RUNNING" {
+			t.Fatalf("expected the job to be running, got %v", body)
+		}
+	}
+
+	_, body = doRequest(t, context.Background(), http.MethodGet, jobURL+failedJob, "")
+	if body["status"] != "FAIL" || body["error_code"] != "EVS.5400" || body["fail_reason"] != "the volume quota is insufficient" {
+		t.Fatalf("expected the job to fail, got %v", body)
+	}
+
+	// only the first job fails
+	for i := 0; i <= s.JobPolls; i++ {
+		_, body = doRequest(t, context.Background(), http.MethodGet, jobURL+job, "")
+	}
+	entities, _ := body["entities"].(map[string]interface{})
+	if body["status"] != "SUCCESS" || entities["volume_id"] == nil {
+		t.Fatalf("expected the job to succeed, got %v", body)
+	}
+
+	// the jobs are not shared between the services
+	if status, _ := doRequest(t, context.Background(), http.MethodGet,
+		s.Endpoint("ims")+"v1/"+s.ProjectID+"/jobs/"+job, ""); status != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", status)
+	}
+
+	if leftovers := s.Leftovers(); len(leftovers) != 1 || leftovers[0] != "volume "+entities["volume_id"].(string) {
+		t.Fatalf("expected the volume to be left over, got %v", leftovers)
+	}
+}
+
+func TestServer_NetworkLifecycle(t *testing.T) {
+	s := New(t)
+	base := s.Endpoint("vpc") + "v1/" + s.ProjectID
+
+	_, body := doRequest(t, context.Background(), http.MethodPost, base+"/vpcs", `{"vpc": {"name": "vpc-test", "cidr": "172.16.0.0/16"}}`)
+	vpcID := body["vpc"].(map[string]interface{})["id"].(string)
+	_, body = doRequest(t, context.Background(), http.MethodPost, base+"/subnets",
+		`{"subnet": {"name": "subnet-test", "cidr": "172.16.0.0/24", "gateway_ip": "172.16.0.1", "vpc_id": "`+vpcID+`"}}`)
+	subnetID := body["subnet"].(map[string]interface{})["id"].(string)
+
+	if status, _ := doRequest(t, context.Background(), http.MethodDelete, base+"/vpcs/"+vpcID, ""); status != http.StatusConflict {
+		t.Fatalf("deleting a VPC with subnets should conflict, got %d", status)
+	}
+	if status, _ := doRequest(t, context.Background(), http.MethodDelete, base+"/vpcs/"+vpcID+"/subnets/"+subnetID, ""); status != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", status)
+	}
+	if status, _ := doRequest(t, context.Background(), http.MethodDelete, base+"/vpcs/"+vpcID, ""); status != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", status)
+	}
+	if status, _ := doRequest(t, context.Background(), http.MethodDelete, base+"/vpcs/"+vpcID, ""); status != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", status)
+	}
+
+	if leftovers := s.Leftovers(); len(leftovers) > 0 {
+		t.Fatalf("expected no leftovers, got %v", leftovers)
+	}
+}
@@ -0,0 +1,167 @@
+package fakecloud
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func (s *Server) vpcRoutes() []route {
+	return []route{
+		{http.MethodPost, "/v1/{project_id}/vpcs", s.createVPC},
+		{http.MethodGet, "/v1/{project_id}/vpcs/{vpc_id}", s.showVPC},
+		{http.MethodDelete, "/v1/{project_id}/vpcs/{vpc_id}", s.deleteVPC},
+		{http.MethodPost, "/v1/{project_id}/subnets", s.createSubnet},
+		{http.MethodGet, "/v1/{project_id}/subnets/{subnet_id}", s.showSubnet},
+		{http.MethodDelete, "/v1/{project_id}/vpcs/{vpc_id}/subnets/{subnet_id}", s.deleteSubnet},
+		{http.MethodGet, "/v1/{project_id}/security-groups/{security_group_id}", s.showSecurityGroup},
+		{http.MethodGet, "/v1/{project_id}/quotas", s.listQuotas(QuotaVPCs, QuotaSubnets)},
+	}
+}
+
+func vpcBody(vpc *VPC) map[string]interface{} {
+	return map[string]interface{}{
+		"vpc": map[string]interface{}{
+			"id":     vpc.ID,
+			"name":   vpc.Name,
+			"cidr":   vpc.CIDR,
+			"status": vpc.Status,
+			"routes": []interface{}{},
+		},
+	}
+}
+
+func subnetBody(subnet *Subnet) map[string]interface{} {
+	return map[string]interface{}{
+		"subnet": map[string]interface{}{
+			"id":          subnet.ID,
+			"name":        subnet.Name,
+			"cidr":        subnet.CIDR,
+			"gateway_ip":  subnet.GatewayIP,
+			"vpc_id":      subnet.VpcID,
+			"status":      subnet.Status,
+			"dhcp_enable": true,
+		},
+	}
+}
+
+// createVPC creates a VPC in the CREATING status, it becomes OK when it's queried.
+func (s *Server) createVPC(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body struct {
+		Vpc struct {
+			Name string `json:"name"`
+			Cidr string `json:"cidr"`
+		} `json:"vpc"`
+	}
+	if !readJSON(w, r, "vpc", &body) {
+		return
+	}
+
+	vpc := &VPC{ID: s.newID("vpc"), Name: body.Vpc.Name, CIDR: body.Vpc.Cidr, Status: "CREATING"}
+	s.vpcs[vpc.ID] = vpc
+	writeJSON(w, http.StatusOK, vpcBody(vpc))
+}
+
+func (s *Server) showVPC(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	vpc, ok := s.vpcs[params["vpc_id"]]
+	if !ok {
+		writeError(w, "vpc", http.StatusNotFound, "VPC.0202", fmt.Sprintf("Query resource by id %s fail.", params["vpc_id"]))
+		return
+	}
+
+	body := vpcBody(vpc)
+	vpc.Status = "OK"
+	writeJSON(w, http.StatusOK, body)
+}
+
+func (s *Server) deleteVPC(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	id := params["vpc_id"]
+	if _, ok := s.vpcs[id]; !ok {
+		writeError(w, "vpc", http.StatusNotFound, "VPC.0202", fmt.Sprintf("Query resource by id %s fail.", id))
+		return
+	}
+	for _, subnet := range s.subnets {
+		if subnet.VpcID == id {
+			writeError(w, "vpc", http.StatusConflict, "VPC.0204", fmt.Sprintf("The VPC %s still has subnets.", id))
+			return
+		}
+	}
+
+	delete(s.vpcs, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createSubnet creates a subnet in the UNKNOWN status, it becomes ACTIVE when it's queried.
+func (s *Server) createSubnet(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body struct {
+		Subnet struct {
+			Name      string `json:"name"`
+			Cidr      string `json:"cidr"`
+			GatewayIP string `json:"gateway_ip"`
+			VpcID     string `json:"vpc_id"`
+		} `json:"subnet"`
+	}
+	if !readJSON(w, r, "vpc", &body) {
+		return
+	}
+	if _, ok := s.vpcs[body.Subnet.VpcID]; !ok {
+		writeError(w, "vpc", http.StatusNotFound, "VPC.0202", fmt.Sprintf("Query resource by id %s fail.", body.Subnet.VpcID))
+		return
+	}
+
+	subnet := &Subnet{
+		ID:        s.newID("subnet"),
+		VpcID:     body.Subnet.VpcID,
+		Name:      body.Subnet.Name,
+		CIDR:      body.Subnet.Cidr,
+		GatewayIP: body.Subnet.GatewayIP,
+		Status:    "UNKNOWN",
+	}
+	s.subnets[subnet.ID] = subnet
+	writeJSON(w, http.StatusOK, subnetBody(subnet))
+}
+
+func (s *Server) showSubnet(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	subnet, ok := s.subnets[params["subnet_id"]]
+	if !ok {
+		writeError(w, "vpc", http.StatusNotFound, "VPC.0202", fmt.Sprintf("Query resource by id %s fail.", params["subnet_id"]))
+		return
+	}
+
+	body := subnetBody(subnet)
+	subnet.Status = "ACTIVE"
+	writeJSON(w, http.StatusOK, body)
+}
+
+func (s *Server) deleteSubnet(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	id := params["subnet_id"]
+	subnet, ok := s.subnets[id]
+	if !ok || subnet.VpcID != params["vpc_id"] {
+		writeError(w, "vpc", http.StatusNotFound, "VPC.0202", fmt.Sprintf("Query resource by id %s fail.", id))
+		return
+	}
+	for _, instance := range s.instances {
+		if containsString(instance.SubnetIDs, id) {
+			writeError(w, "vpc", http.StatusConflict, "VPC.0204", fmt.Sprintf("The subnet %s is still in use.", id))
+			return
+		}
+	}
+
+	delete(s.subnets, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) showSecurityGroup(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	secgroup, ok := s.secgroups[params["security_group_id"]]
+	if !ok {
+		writeError(w, "vpc", http.StatusNotFound, "VPC.0602", fmt.Sprintf("Query resource by id %s fail.", params["security_group_id"]))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"security_group": map[string]interface{}{
+			"id":                   secgroup.ID,
+			"name":                 secgroup.Name,
+			"security_group_rules": []interface{}{},
+		},
+	})
+}
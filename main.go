@@ -10,6 +10,8 @@ import (
 
 	ecsbuilder "github.com/huaweicloud/packer-builder-huaweicloud/builder/ecs"
 	huaweicloudimport "github.com/huaweicloud/packer-builder-huaweicloud/post-processor/huaweicloud-import"
+	huaweicloudpromote "github.com/huaweicloud/packer-builder-huaweicloud/post-processor/huaweicloud-promote"
+	huaweicloudretention "github.com/huaweicloud/packer-builder-huaweicloud/post-processor/huaweicloud-retention"
 )
 
 var (
@@ -34,6 +36,8 @@ func main() {
 	pps := plugin.NewSet()
 	pps.RegisterBuilder("ecs", new(ecsbuilder.Builder))
 	pps.RegisterPostProcessor("import", new(huaweicloudimport.PostProcessor))
+	pps.RegisterPostProcessor("promote", new(huaweicloudpromote.PostProcessor))
+	pps.RegisterPostProcessor("retention", new(huaweicloudretention.PostProcessor))
 	pps.SetVersion(PluginVersion)
 	err := pps.Run()
 	if err != nil {
@@ -3,6 +3,7 @@ package huaweicloudimport
 import (
 	"fmt"
 	"log"
+	"net/http"
 
 	"github.com/huaweicloud/huaweicloud-sdk-go-obs/obs"
 	ecsbuilder "github.com/huaweicloud/packer-builder-huaweicloud/builder/ecs"
@@ -10,14 +11,47 @@ import (
 
 func (p *PostProcessor) newOBSClient(region string) (*obs.ObsClient, error) {
 	conf := p.config
-	obsEndpoint := ecsbuilder.GetServiceEndpoint(conf.Cloud, "obs", region)
-	envProxyConfigure := obs.WithProxyFromEnv(true)
+	obsEndpoint := conf.ServiceEndpoint("obs", region)
+	httpClientConfigure := obs.WithHttpClient(p.newOBSHTTPClient())
 
-	if conf.SecurityToken != "" {
-		return obs.New(conf.AccessKey, conf.SecretKey, obsEndpoint,
-			obs.WithSignature("OBS"), obs.WithSecurityToken(conf.SecurityToken), envProxyConfigure)
+	credential, err := conf.GetCredential()
+	if err != nil {
+		return nil, err
+	}
+
+	if credential.SecurityToken != "" {
+		return obs.New(credential.AccessKey, credential.SecretKey, obsEndpoint,
+			obs.WithSignature("OBS"), obs.WithSecurityToken(credential.SecurityToken), httpClientConfigure)
+	}
+	return obs.New(credential.AccessKey, credential.SecretKey, obsEndpoint, obs.WithSignature("OBS"), httpClientConfigure)
+}
+
+// refreshOBSClient updates the credential of the OBS client, the temporary credential may
+// have been refreshed during a long-running upload or import.
+func (p *PostProcessor) refreshOBSClient(client *obs.ObsClient) error {
+	credential, err := p.config.GetCredential()
+	if err != nil {
+		return err
+	}
+
+	client.Refresh(credential.AccessKey, credential.SecretKey, credential.SecurityToken)
+	return nil
+}
+
+// newOBSHTTPClient returns an HTTP client with the same TLS and proxy settings as the
+// other service clients, the requests and responses are logged when HW_DEBUG is set.
+func (p *PostProcessor) newOBSHTTPClient() *http.Client {
+	var transport http.RoundTripper = p.config.HTTPTransport()
+	if ecsbuilder.LogEnabled() {
+		transport = &ecsbuilder.LogRoundTripper{Rt: transport}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
 	}
-	return obs.New(conf.AccessKey, conf.SecretKey, obsEndpoint, obs.WithSignature("OBS"), envProxyConfigure)
 }
 
 func queryBucket(client *obs.ObsClient, bucketName string) error {
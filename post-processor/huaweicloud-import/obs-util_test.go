@@ -3,9 +3,12 @@ package huaweicloudimport
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/huaweicloud/huaweicloud-sdk-go-obs/obs"
+
+	"github.com/huaweicloud/packer-builder-huaweicloud/internal/fakecloud"
 )
 
 func TestUploadFileToObject(t *testing.T) {
@@ -30,3 +33,55 @@ func TestUploadFileToObject(t *testing.T) {
 		t.Fatalf("failed to upload file: %s", err)
 	}
 }
+
+func TestUploadFileToObject_FakeCloud(t *testing.T) {
+	cloud := fakecloud.New(t)
+	cloud.AddBucket("packer-images")
+
+	p := &PostProcessor{}
+	err := p.Configure(map[string]interface{}{
+		"access_key":       "FAKEACCESSKEY",
+		"secret_key":       "FakeSecretKey",
+		"region":           cloud.Region,
+		"project_id":       cloud.ProjectID,
+		"endpoints":        cloud.Endpoints(),
+		"obs_bucket_name":  "packer-images",
+		"image_name":       "packer-import",
+		"image_os_version": "Ubuntu 22.04 server 64bit",
+		"min_disk":         40,
+		"format":           "raw",
+	})
+	if err != nil {
+		t.Fatalf("configure failed: %s", err)
+	}
+
+	client, err := p.newOBSClient(cloud.Region)
+	if err != nil {
+		t.Fatalf("failed to create OBS client: %s", err)
+	}
+
+	if err := queryBucket(client, "packer-images"); err != nil {
+		t.Fatal(err)
+	}
+	if err := queryBucket(client, "not-exist"); err == nil {
+		t.Fatal("querying a missing bucket should fail")
+	}
+
+	source := filepath.Join(t.TempDir(), "image.raw")
+	if err := os.WriteFile(source, []byte("fake image"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := uploadFileToObject(client, "packer-images", "image.raw", source); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := cloud.Object("packer-images", "image.raw"); !ok || string(data) != "fake image" {
+		t.Fatalf("unexpected object: %q", data)
+	}
+
+	if err := deleteFile(client, "packer-images", "image.raw"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cloud.Object("packer-images", "image.raw"); ok {
+		t.Fatal("the object should be deleted")
+	}
+}
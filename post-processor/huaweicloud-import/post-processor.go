@@ -182,7 +182,7 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 	}
 
 	region := p.config.Region
-	imsClient, err := p.newIMSClient(region)
+	imsClient, err := p.config.HcImsClient(region)
 	if err != nil {
 		return nil, false, false, fmt.Errorf("error initializing image service client: %s", err)
 	}
@@ -243,7 +243,7 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 	}
 
 	ui.Say(fmt.Sprintf("Waiting for importing image from OBS %s/%s ...", bucketName, keyName))
-	imageId, err := waitImageJobSuccess(imsClient, waitTimeout, jobId)
+	imageId, err := waitImageJobSuccess(ctx, ui, imsClient, waitTimeout, jobId)
 	if err != nil {
 		return nil, false, false, fmt.Errorf("error on waiting for importing image %s from OBS %s/%s: %s",
 			imageId, bucketName, keyName, err)
@@ -253,12 +253,16 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifa
 	ui.Say(fmt.Sprintf("Importing the image ID as %s in region %s completed", imageId, p.config.Region))
 	artifact = &ecsbuilder.Artifact{
 		ImageId:        imageId,
+		Region:         p.config.Region,
 		BuilderIdValue: BuilderId,
 		Client:         imsClient,
 	}
 
 	if !p.config.SkipClean {
 		ui.Message(fmt.Sprintf("Deleting import source OBS object %s/%s", bucketName, keyName))
+		if err = p.refreshOBSClient(obsClient); err != nil {
+			return nil, false, false, fmt.Errorf("failed to refresh the credential of OBS client: %s", err)
+		}
 		if err = deleteFile(obsClient, bucketName, keyName); err != nil {
 			return nil, false, false, fmt.Errorf("failed to delete OBS object %s/%s: %s", bucketName, keyName, err)
 		}
@@ -423,22 +427,24 @@ func isStringInSlice(key string, valid []string, ignoreCase bool) bool {
 	return false
 }
 
-func waitImageJobSuccess(client *ims.ImsClient, timeout time.Duration, jobID string) (string, error) {
-	stateConf := &ecsbuilder.StateChangeConf{
-		Pending:      []string{"INIT", "RUNNING"},
-		Target:       []string{"SUCCESS"},
-		Refresh:      getImsJobStatus(client, jobID),
-		Timeout:      timeout,
-		Delay:        60 * time.Second,
-		PollInterval: 10 * time.Second,
+func waitImageJobSuccess(ctx context.Context, ui packersdk.Ui, client *ims.ImsClient, timeout time.Duration,
+	jobID string) (string, error) {
+	waiter := ecsbuilder.Waiter[*model.ShowJobResponse]{
+		Pending:     []string{"INIT", "RUNNING"},
+		Target:      []string{"SUCCESS"},
+		Refresh:     getImsJobStatus(client, jobID),
+		Timeout:     timeout,
+		Delay:       60 * time.Second,
+		MinInterval: 5 * time.Second,
+		MaxInterval: 10 * time.Second,
+		Progress:    ecsbuilder.UiProgress(ui, fmt.Sprintf("Import job %s", jobID)),
 	}
 
-	result, err := stateConf.WaitForState()
+	jobResult, err := waiter.Wait(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	jobResult := result.(*model.ShowJobResponse)
 	if jobResult.Entities == nil || jobResult.Entities.ImageId == nil {
 		return "", fmt.Errorf("error extracting the image ID from API response")
 	}
@@ -447,30 +453,21 @@ func waitImageJobSuccess(client *ims.ImsClient, timeout time.Duration, jobID str
 	return imageID, nil
 }
 
-func getImsJobStatus(client *ims.ImsClient, jobID string) ecsbuilder.StateRefreshFunc {
-	return func() (interface{}, string, error) {
+func getImsJobStatus(client *ims.ImsClient, jobID string) ecsbuilder.RefreshFunc[*model.ShowJobResponse] {
+	return func(context.Context) (*model.ShowJobResponse, string, error) {
 		jobRequest := &model.ShowJobRequest{
 			JobId: jobID,
 		}
 		jobResponse, err := client.ShowJob(jobRequest)
 		if err != nil {
-			return nil, "", nil
+			return nil, "", fmt.Errorf("error querying the IMS job %s: %s", jobID, err)
 		}
 
 		jobStatus := jobResponse.Status.Value()
 
 		if jobStatus == "FAIL" {
-			return jobResponse, jobStatus, fmt.Errorf("failed to import image: %s", *jobResponse.FailReason)
+			return jobResponse, jobStatus, ecsbuilder.NewIMSJobError(jobResponse)
 		}
 		return jobResponse, jobStatus, nil
 	}
 }
-
-func (p *PostProcessor) newIMSClient(region string) (*ims.ImsClient, error) {
-	hcClient, err := ecsbuilder.NewHcClient(&p.config.AccessConfig, region, "ims")
-	if err != nil {
-		return nil, err
-	}
-
-	return ims.NewImsClient(hcClient), nil
-}
@@ -4,43 +4,56 @@ package huaweicloudimport
 
 import (
 	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/huaweicloud/packer-builder-huaweicloud/builder/ecs"
 	"github.com/zclconf/go-cty/cty"
 )
 
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	PackerBuildName       *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
-	PackerBuilderType     *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
-	PackerCoreVersion     *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
-	PackerDebug           *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
-	PackerForce           *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
-	PackerOnError         *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
-	PackerUserVars        map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
-	PackerSensitiveVars   []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
-	AccessKey             *string           `mapstructure:"access_key" required:"true" cty:"access_key" hcl:"access_key"`
-	SecretKey             *string           `mapstructure:"secret_key" required:"true" cty:"secret_key" hcl:"secret_key"`
-	Region                *string           `mapstructure:"region" required:"true" cty:"region" hcl:"region"`
-	ProjectName           *string           `mapstructure:"project_name" required:"false" cty:"project_name" hcl:"project_name"`
-	ProjectID             *string           `mapstructure:"project_id" required:"false" cty:"project_id" hcl:"project_id"`
-	SecurityToken         *string           `mapstructure:"security_token" required:"false" cty:"security_token" hcl:"security_token"`
-	IdentityEndpoint      *string           `mapstructure:"auth_url" required:"false" cty:"auth_url" hcl:"auth_url"`
-	Insecure              *bool             `mapstructure:"insecure" required:"false" cty:"insecure" hcl:"insecure"`
-	Cloud                 *string           `cty:"cloud" hcl:"cloud"`
-	OBSBucket             *string           `mapstructure:"obs_bucket_name" required:"true" cty:"obs_bucket_name" hcl:"obs_bucket_name"`
-	OBSObject             *string           `mapstructure:"obs_object_name" required:"false" cty:"obs_object_name" hcl:"obs_object_name"`
-	ImageName             *string           `mapstructure:"image_name" required:"true" cty:"image_name" hcl:"image_name"`
-	OsVersion             *string           `mapstructure:"image_os_version" required:"true" cty:"image_os_version" hcl:"image_os_version"`
-	MinDisk               *int              `mapstructure:"min_disk" required:"true" cty:"min_disk" hcl:"min_disk"`
-	Format                *string           `mapstructure:"format" required:"true" cty:"format" hcl:"format"`
-	ImageDescription      *string           `mapstructure:"image_description" required:"false" cty:"image_description" hcl:"image_description"`
-	ImageType             *string           `mapstructure:"image_type" required:"false" cty:"image_type" hcl:"image_type"`
-	ImageTags             map[string]string `mapstructure:"image_tags" required:"false" cty:"image_tags" hcl:"image_tags"`
-	ImageArchitecture     *string           `mapstructure:"image_architecture" required:"false" cty:"image_architecture" hcl:"image_architecture"`
-	EnterpriseProjectId   *string           `mapstructure:"enterprise_project_id" required:"false" cty:"enterprise_project_id" hcl:"enterprise_project_id"`
-	QuickImport           *bool             `mapstructure:"quick_import" required:"false" cty:"quick_import" hcl:"quick_import"`
-	SkipClean             *bool             `mapstructure:"skip_clean" required:"false" cty:"skip_clean" hcl:"skip_clean"`
-	WaitImageReadyTimeout *string           `mapstructure:"wait_image_ready_timeout" required:"false" cty:"wait_image_ready_timeout" hcl:"wait_image_ready_timeout"`
+	PackerBuildName       *string                   `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType     *string                   `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion     *string                   `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug           *bool                     `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce           *bool                     `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError         *string                   `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars        map[string]string         `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars   []string                  `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	AccessKey             *string                   `mapstructure:"access_key" required:"true" cty:"access_key" hcl:"access_key"`
+	SecretKey             *string                   `mapstructure:"secret_key" required:"true" cty:"secret_key" hcl:"secret_key"`
+	Region                *string                   `mapstructure:"region" required:"true" cty:"region" hcl:"region"`
+	ProjectName           *string                   `mapstructure:"project_name" required:"false" cty:"project_name" hcl:"project_name"`
+	ProjectID             *string                   `mapstructure:"project_id" required:"false" cty:"project_id" hcl:"project_id"`
+	SecurityToken         *string                   `mapstructure:"security_token" required:"false" cty:"security_token" hcl:"security_token"`
+	IdentityEndpoint      *string                   `mapstructure:"auth_url" required:"false" cty:"auth_url" hcl:"auth_url"`
+	Insecure              *bool                     `mapstructure:"insecure" required:"false" cty:"insecure" hcl:"insecure"`
+	MaxRetries            *int                      `mapstructure:"max_retries" required:"false" cty:"max_retries" hcl:"max_retries"`
+	CACertFile            *string                   `mapstructure:"cacert_file" required:"false" cty:"cacert_file" hcl:"cacert_file"`
+	ClientCertFile        *string                   `mapstructure:"cert" required:"false" cty:"cert" hcl:"cert"`
+	ClientKeyFile         *string                   `mapstructure:"key" required:"false" cty:"key" hcl:"key"`
+	DomainID              *string                   `mapstructure:"domain_id" required:"false" cty:"domain_id" hcl:"domain_id"`
+	DomainName            *string                   `mapstructure:"domain_name" required:"false" cty:"domain_name" hcl:"domain_name"`
+	SharedConfigFile      *string                   `mapstructure:"shared_config_file" required:"false" cty:"shared_config_file" hcl:"shared_config_file"`
+	Profile               *string                   `mapstructure:"profile" required:"false" cty:"profile" hcl:"profile"`
+	CredentialProcess     *string                   `mapstructure:"credential_process" required:"false" cty:"credential_process" hcl:"credential_process"`
+	AssumeRole            *ecs.FlatAssumeRoleConfig `mapstructure:"assume_role" required:"false" cty:"assume_role" hcl:"assume_role"`
+	OIDC                  *ecs.FlatOIDCConfig       `mapstructure:"oidc" required:"false" cty:"oidc" hcl:"oidc"`
+	Cloud                 *string                   `mapstructure:"cloud" required:"false" cty:"cloud" hcl:"cloud"`
+	Endpoints             map[string]string         `mapstructure:"endpoints" required:"false" cty:"endpoints" hcl:"endpoints"`
+	OBSBucket             *string                   `mapstructure:"obs_bucket_name" required:"true" cty:"obs_bucket_name" hcl:"obs_bucket_name"`
+	OBSObject             *string                   `mapstructure:"obs_object_name" required:"false" cty:"obs_object_name" hcl:"obs_object_name"`
+	ImageName             *string                   `mapstructure:"image_name" required:"true" cty:"image_name" hcl:"image_name"`
+	OsVersion             *string                   `mapstructure:"image_os_version" required:"true" cty:"image_os_version" hcl:"image_os_version"`
+	MinDisk               *int                      `mapstructure:"min_disk" required:"true" cty:"min_disk" hcl:"min_disk"`
+	Format                *string                   `mapstructure:"format" required:"true" cty:"format" hcl:"format"`
+	ImageDescription      *string                   `mapstructure:"image_description" required:"false" cty:"image_description" hcl:"image_description"`
+	ImageType             *string                   `mapstructure:"image_type" required:"false" cty:"image_type" hcl:"image_type"`
+	ImageTags             map[string]string         `mapstructure:"image_tags" required:"false" cty:"image_tags" hcl:"image_tags"`
+	ImageArchitecture     *string                   `mapstructure:"image_architecture" required:"false" cty:"image_architecture" hcl:"image_architecture"`
+	EnterpriseProjectId   *string                   `mapstructure:"enterprise_project_id" required:"false" cty:"enterprise_project_id" hcl:"enterprise_project_id"`
+	QuickImport           *bool                     `mapstructure:"quick_import" required:"false" cty:"quick_import" hcl:"quick_import"`
+	SkipClean             *bool                     `mapstructure:"skip_clean" required:"false" cty:"skip_clean" hcl:"skip_clean"`
+	WaitImageReadyTimeout *string                   `mapstructure:"wait_image_ready_timeout" required:"false" cty:"wait_image_ready_timeout" hcl:"wait_image_ready_timeout"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -71,7 +84,19 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"security_token":             &hcldec.AttrSpec{Name: "security_token", Type: cty.String, Required: false},
 		"auth_url":                   &hcldec.AttrSpec{Name: "auth_url", Type: cty.String, Required: false},
 		"insecure":                   &hcldec.AttrSpec{Name: "insecure", Type: cty.Bool, Required: false},
+		"max_retries":                &hcldec.AttrSpec{Name: "max_retries", Type: cty.Number, Required: false},
+		"cacert_file":                &hcldec.AttrSpec{Name: "cacert_file", Type: cty.String, Required: false},
+		"cert":                       &hcldec.AttrSpec{Name: "cert", Type: cty.String, Required: false},
+		"key":                        &hcldec.AttrSpec{Name: "key", Type: cty.String, Required: false},
+		"domain_id":                  &hcldec.AttrSpec{Name: "domain_id", Type: cty.String, Required: false},
+		"domain_name":                &hcldec.AttrSpec{Name: "domain_name", Type: cty.String, Required: false},
+		"shared_config_file":         &hcldec.AttrSpec{Name: "shared_config_file", Type: cty.String, Required: false},
+		"profile":                    &hcldec.AttrSpec{Name: "profile", Type: cty.String, Required: false},
+		"credential_process":         &hcldec.AttrSpec{Name: "credential_process", Type: cty.String, Required: false},
+		"assume_role":                &hcldec.BlockSpec{TypeName: "assume_role", Nested: hcldec.ObjectSpec((*ecs.FlatAssumeRoleConfig)(nil).HCL2Spec())},
+		"oidc":                       &hcldec.BlockSpec{TypeName: "oidc", Nested: hcldec.ObjectSpec((*ecs.FlatOIDCConfig)(nil).HCL2Spec())},
 		"cloud":                      &hcldec.AttrSpec{Name: "cloud", Type: cty.String, Required: false},
+		"endpoints":                  &hcldec.AttrSpec{Name: "endpoints", Type: cty.Map(cty.String), Required: false},
 		"obs_bucket_name":            &hcldec.AttrSpec{Name: "obs_bucket_name", Type: cty.String, Required: false},
 		"obs_object_name":            &hcldec.AttrSpec{Name: "obs_object_name", Type: cty.String, Required: false},
 		"image_name":                 &hcldec.AttrSpec{Name: "image_name", Type: cty.String, Required: false},
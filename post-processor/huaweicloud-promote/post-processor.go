@@ -0,0 +1,241 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+//go:generate packer-sdc struct-markdown
+
+package huaweicloudpromote
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2/model"
+	ecsbuilder "github.com/huaweicloud/packer-builder-huaweicloud/builder/ecs"
+)
+
+const (
+	BuilderId = "packer.post-processor.huaweicloud-promote"
+
+	defaultChannelTagKey = "channel"
+
+	// the page size to list the images holding the channel tag
+	imagePageSize = 100
+)
+
+// Configuration of this post processor
+type Config struct {
+	common.PackerConfig     `mapstructure:",squash"`
+	ecsbuilder.AccessConfig `mapstructure:",squash"`
+
+	// The channel to promote the images of the artifact to, such as `stable` or `candidate`.
+	Channel string `mapstructure:"channel" required:"true"`
+	// The key of the channel tag. (Default: `channel`).
+	ChannelTagKey string `mapstructure:"channel_tag_key" required:"false"`
+	// Only remove the channel tag from the previous images whose names start with the prefix, such
+	// as `my-golden-image-`, so that the image families can share the same channel tag.
+	ImageNamePrefix string `mapstructure:"image_name_prefix" required:"false"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+
+	// newImageTagClient creates the client of the region with the access config of the region,
+	// the client is created with HcImsClient if nil, the tests replace it with fakes
+	newImageTagClient func(c *ecsbuilder.AccessConfig, region string) (ecsbuilder.ImageTagClient, error)
+}
+
+func (p *PostProcessor) imageTagClient(c *ecsbuilder.AccessConfig, region string) (ecsbuilder.ImageTagClient, error) {
+	if p.newImageTagClient != nil {
+		return p.newImageTagClient(c, region)
+	}
+	return c.HcImsClient(region)
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec {
+	return p.config.FlatMapstructure().HCL2Spec()
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	// Set defaults
+	if p.config.ChannelTagKey == "" {
+		p.config.ChannelTagKey = defaultChannelTagKey
+	}
+
+	errs := new(packersdk.MultiError)
+
+	// Check we have huaweicloud access variables defined somewhere
+	errs = packersdk.MultiErrorAppend(errs, p.config.AccessConfig.Prepare(&p.config.ctx)...)
+
+	if p.config.Channel == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("channel must be specified"))
+	}
+
+	// Anything which flagged return back up the stack
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(p.config.AccessKey, p.config.SecretKey)
+	return nil
+}
+
+// PostProcess moves the channel tag onto the images of the artifact. The tag is added to all the
+// new images in all the regions before it's removed from the previous images, so that the
+// consumers always find an image in the channel. It only accepts the artifacts built by the ECS
+// builder.
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	if artifact.BuilderId() != ecsbuilder.BuilderId {
+		return nil, false, false, fmt.Errorf("unknown artifact type %s, only the artifacts of %s are supported",
+			artifact.BuilderId(), ecsbuilder.BuilderId)
+	}
+
+	artifactImages := ecsbuilder.ArtifactRegionImages(artifact, p.config.Region)
+	if len(artifactImages) == 0 {
+		return nil, false, false, fmt.Errorf("the artifact contains no image to promote")
+	}
+
+	tag := fmt.Sprintf("%s=%s", p.config.ChannelTagKey, p.config.Channel)
+	clients := make(map[string]ecsbuilder.ImageTagClient, len(artifactImages))
+	for _, item := range artifactImages {
+		regional, err := p.config.ForRegion(item.Region)
+		if err != nil {
+			return nil, false, false, fmt.Errorf("error preparing the access config of region %s: %s", item.Region, err)
+		}
+		client, err := p.imageTagClient(regional, item.Region)
+		if err != nil {
+			return nil, false, false, fmt.Errorf("error initializing image service client: %s", err)
+		}
+		clients[item.Region] = client
+	}
+
+	ui.Say(fmt.Sprintf("Promoting the images to %s ...", tag))
+	var added []ecsbuilder.RegionImages
+	for _, item := range artifactImages {
+		for _, id := range item.Images {
+			if err := p.addChannelTag(clients[item.Region], id); err != nil {
+				// the consumers should not see the partial promotion
+				p.rollback(ui, clients, added)
+				return nil, false, false, fmt.Errorf("error adding %s to the image %s in region %s: %s",
+					tag, id, item.Region, err)
+			}
+			ui.Message(fmt.Sprintf("Added %s to the image %s in region %s", tag, id, item.Region))
+			added = ecsbuilder.AppendRegionImage(added, item.Region, id)
+		}
+	}
+
+	var errs []string
+	for _, item := range artifactImages {
+		client := clients[item.Region]
+		previous, err := p.listChannelImages(client, item.Images)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("error querying the images in %s in region %s: %s", tag, item.Region, err))
+			continue
+		}
+		for _, image := range previous {
+			request := &model.DeleteImageTagRequest{ImageId: image.Id, Key: p.config.ChannelTagKey}
+			if _, err := client.DeleteImageTag(request); err != nil {
+				errs = append(errs, fmt.Sprintf("error removing %s from the image %s (%s) in region %s: %s",
+					tag, image.Name, image.Id, item.Region, err))
+				continue
+			}
+			ui.Message(fmt.Sprintf("Removed %s from the previous image %s (%s) in region %s",
+				tag, image.Name, image.Id, item.Region))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, false, false, fmt.Errorf("the images are promoted, but the previous images are still in %s:\n  - %s",
+			tag, strings.Join(errs, "\n  - "))
+	}
+
+	// the artifact is passed through, it must be kept since it's the same one
+	return artifact, true, true, nil
+}
+
+func (p *PostProcessor) addChannelTag(client ecsbuilder.ImageTagClient, imageID string) error {
+	request := &model.AddImageTagRequest{
+		ImageId: imageID,
+		Body: &model.AddImageTagRequestBody{
+			Tag: &model.ResourceTag{
+				Key:   p.config.ChannelTagKey,
+				Value: p.config.Channel,
+			},
+		},
+	}
+	_, err := client.AddImageTag(request)
+	return err
+}
+
+// rollback removes the channel tag from the new images when the promotion fails.
+func (p *PostProcessor) rollback(ui packersdk.Ui, clients map[string]ecsbuilder.ImageTagClient, added []ecsbuilder.RegionImages) {
+	for _, item := range added {
+		for _, id := range item.Images {
+			request := &model.DeleteImageTagRequest{ImageId: id, Key: p.config.ChannelTagKey}
+			if _, err := clients[item.Region].DeleteImageTag(request); err != nil {
+				ui.Error(fmt.Sprintf("Error removing the channel tag from the image %s in region %s: %s",
+					id, item.Region, err))
+			}
+		}
+	}
+}
+
+// listChannelImages lists the private images holding the channel tag except the excluded ones.
+func (p *PostProcessor) listChannelImages(client ecsbuilder.ImageTagClient, exclude []string) ([]model.ImageInfo, error) {
+	filters := ecsbuilder.ImageFilterOptions{
+		Visibility: "private",
+		Tag:        fmt.Sprintf("%s.%s", p.config.ChannelTagKey, p.config.Channel),
+	}
+	request, err := filters.Build()
+	if err != nil {
+		return nil, err
+	}
+	// the tag is removed from the images in any status
+	request.Status = nil
+	limit := int32(imagePageSize)
+	request.Limit = &limit
+
+	excluded := make(map[string]bool, len(exclude))
+	for _, id := range exclude {
+		excluded[id] = true
+	}
+
+	var images []model.ImageInfo
+	for {
+		log.Printf("[DEBUG] Listing the images: %s", request)
+		response, err := client.ListImages(request)
+		if err != nil {
+			return nil, err
+		}
+		if response.Images == nil || len(*response.Images) == 0 {
+			break
+		}
+
+		page := *response.Images
+		for _, image := range page {
+			if !excluded[image.Id] && strings.HasPrefix(image.Name, p.config.ImageNamePrefix) {
+				images = append(images, image)
+			}
+		}
+		if len(page) < imagePageSize {
+			break
+		}
+		request.Marker = &page[len(page)-1].Id
+	}
+	return images, nil
+}
@@ -0,0 +1,94 @@
+// Code generated by "packer-sdc mapstructure-to-hcl2"; DO NOT EDIT.
+
+package huaweicloudpromote
+
+import (
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/huaweicloud/packer-builder-huaweicloud/builder/ecs"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FlatConfig is an auto-generated flat version of Config.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatConfig struct {
+	PackerBuildName     *string                   `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType   *string                   `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion   *string                   `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug         *bool                     `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce         *bool                     `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError       *string                   `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars      map[string]string         `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars []string                  `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	AccessKey           *string                   `mapstructure:"access_key" required:"true" cty:"access_key" hcl:"access_key"`
+	SecretKey           *string                   `mapstructure:"secret_key" required:"true" cty:"secret_key" hcl:"secret_key"`
+	Region              *string                   `mapstructure:"region" required:"true" cty:"region" hcl:"region"`
+	ProjectName         *string                   `mapstructure:"project_name" required:"false" cty:"project_name" hcl:"project_name"`
+	ProjectID           *string                   `mapstructure:"project_id" required:"false" cty:"project_id" hcl:"project_id"`
+	SecurityToken       *string                   `mapstructure:"security_token" required:"false" cty:"security_token" hcl:"security_token"`
+	IdentityEndpoint    *string                   `mapstructure:"auth_url" required:"false" cty:"auth_url" hcl:"auth_url"`
+	Insecure            *bool                     `mapstructure:"insecure" required:"false" cty:"insecure" hcl:"insecure"`
+	MaxRetries          *int                      `mapstructure:"max_retries" required:"false" cty:"max_retries" hcl:"max_retries"`
+	CACertFile          *string                   `mapstructure:"cacert_file" required:"false" cty:"cacert_file" hcl:"cacert_file"`
+	ClientCertFile      *string                   `mapstructure:"cert" required:"false" cty:"cert" hcl:"cert"`
+	ClientKeyFile       *string                   `mapstructure:"key" required:"false" cty:"key" hcl:"key"`
+	DomainID            *string                   `mapstructure:"domain_id" required:"false" cty:"domain_id" hcl:"domain_id"`
+	DomainName          *string                   `mapstructure:"domain_name" required:"false" cty:"domain_name" hcl:"domain_name"`
+	SharedConfigFile    *string                   `mapstructure:"shared_config_file" required:"false" cty:"shared_config_file" hcl:"shared_config_file"`
+	Profile             *string                   `mapstructure:"profile" required:"false" cty:"profile" hcl:"profile"`
+	CredentialProcess   *string                   `mapstructure:"credential_process" required:"false" cty:"credential_process" hcl:"credential_process"`
+	AssumeRole          *ecs.FlatAssumeRoleConfig `mapstructure:"assume_role" required:"false" cty:"assume_role" hcl:"assume_role"`
+	OIDC                *ecs.FlatOIDCConfig       `mapstructure:"oidc" required:"false" cty:"oidc" hcl:"oidc"`
+	Cloud               *string                   `mapstructure:"cloud" required:"false" cty:"cloud" hcl:"cloud"`
+	Endpoints           map[string]string         `mapstructure:"endpoints" required:"false" cty:"endpoints" hcl:"endpoints"`
+	Channel             *string                   `mapstructure:"channel" required:"true" cty:"channel" hcl:"channel"`
+	ChannelTagKey       *string                   `mapstructure:"channel_tag_key" required:"false" cty:"channel_tag_key" hcl:"channel_tag_key"`
+	ImageNamePrefix     *string                   `mapstructure:"image_name_prefix" required:"false" cty:"image_name_prefix" hcl:"image_name_prefix"`
+}
+
+// FlatMapstructure returns a new FlatConfig.
+// FlatConfig is an auto-generated flat version of Config.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatConfig)
+}
+
+// HCL2Spec returns the hcl spec of a Config.
+// This spec is used by HCL to read the fields of Config.
+// The decoded values from this spec will then be applied to a FlatConfig.
+func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"packer_build_name":          &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
+		"packer_builder_type":        &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
+		"packer_core_version":        &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
+		"packer_debug":               &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
+		"packer_force":               &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
+		"packer_on_error":            &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
+		"packer_user_variables":      &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
+		"packer_sensitive_variables": &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
+		"access_key":                 &hcldec.AttrSpec{Name: "access_key", Type: cty.String, Required: false},
+		"secret_key":                 &hcldec.AttrSpec{Name: "secret_key", Type: cty.String, Required: false},
+		"region":                     &hcldec.AttrSpec{Name: "region", Type: cty.String, Required: false},
+		"project_name":               &hcldec.AttrSpec{Name: "project_name", Type: cty.String, Required: false},
+		"project_id":                 &hcldec.AttrSpec{Name: "project_id", Type: cty.String, Required: false},
+		"security_token":             &hcldec.AttrSpec{Name: "security_token", Type: cty.String, Required: false},
+		"auth_url":                   &hcldec.AttrSpec{Name: "auth_url", Type: cty.String, Required: false},
+		"insecure":                   &hcldec.AttrSpec{Name: "insecure", Type: cty.Bool, Required: false},
+		"max_retries":                &hcldec.AttrSpec{Name: "max_retries", Type: cty.Number, Required: false},
+		"cacert_file":                &hcldec.AttrSpec{Name: "cacert_file", Type: cty.String, Required: false},
+		"cert":                       &hcldec.AttrSpec{Name: "cert", Type: cty.String, Required: false},
+		"key":                        &hcldec.AttrSpec{Name: "key", Type: cty.String, Required: false},
+		"domain_id":                  &hcldec.AttrSpec{Name: "domain_id", Type: cty.String, Required: false},
+		"domain_name":                &hcldec.AttrSpec{Name: "domain_name", Type: cty.String, Required: false},
+		"shared_config_file":         &hcldec.AttrSpec{Name: "shared_config_file", Type: cty.String, Required: false},
+		"profile":                    &hcldec.AttrSpec{Name: "profile", Type: cty.String, Required: false},
+		"credential_process":         &hcldec.AttrSpec{Name: "credential_process", Type: cty.String, Required: false},
+		"assume_role":                &hcldec.BlockSpec{TypeName: "assume_role", Nested: hcldec.ObjectSpec((*ecs.FlatAssumeRoleConfig)(nil).HCL2Spec())},
+		"oidc":                       &hcldec.BlockSpec{TypeName: "oidc", Nested: hcldec.ObjectSpec((*ecs.FlatOIDCConfig)(nil).HCL2Spec())},
+		"cloud":                      &hcldec.AttrSpec{Name: "cloud", Type: cty.String, Required: false},
+		"endpoints":                  &hcldec.AttrSpec{Name: "endpoints", Type: cty.Map(cty.String), Required: false},
+		"channel":                    &hcldec.AttrSpec{Name: "channel", Type: cty.String, Required: false},
+		"channel_tag_key":            &hcldec.AttrSpec{Name: "channel_tag_key", Type: cty.String, Required: false},
+		"image_name_prefix":          &hcldec.AttrSpec{Name: "image_name_prefix", Type: cty.String, Required: false},
+	}
+	return s
+}
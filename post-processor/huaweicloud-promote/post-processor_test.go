@@ -0,0 +1,244 @@
+package huaweicloudpromote
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2/model"
+	ecsbuilder "github.com/huaweicloud/packer-builder-huaweicloud/builder/ecs"
+	"github.com/huaweicloud/packer-builder-huaweicloud/internal/fakecloud"
+)
+
+// fakeImageTagClient keeps the channel tags of the images, the images are listed in order of the IDs.
+type fakeImageTagClient struct {
+	names map[string]string
+	tags  map[string]string
+}
+
+func (f *fakeImageTagClient) ListImages(req *model.ListImagesRequest) (*model.ListImagesResponse, error) {
+	ids := make([]string, 0, len(f.names))
+	for id := range f.names {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	images := []model.ImageInfo{}
+	for _, id := range ids {
+		if req.Tag != nil && "channel."+f.tags[id] != *req.Tag {
+			continue
+		}
+		images = append(images, model.ImageInfo{Id: id, Name: f.names[id]})
+	}
+	return &model.ListImagesResponse{Images: &images}, nil
+}
+
+func (f *fakeImageTagClient) AddImageTag(req *model.AddImageTagRequest) (*model.AddImageTagResponse, error) {
+	f.tags[req.ImageId] = req.Body.Tag.Value
+	return &model.AddImageTagResponse{}, nil
+}
+
+func (f *fakeImageTagClient) DeleteImageTag(req *model.DeleteImageTagRequest) (*model.DeleteImageTagResponse, error) {
+	delete(f.tags, req.ImageId)
+	return &model.DeleteImageTagResponse{}, nil
+}
+
+// useImageTagClient replaces the client of the post-processor with the fake.
+func useImageTagClient(p *PostProcessor, client ecsbuilder.ImageTagClient) {
+	p.newImageTagClient = func(*ecsbuilder.AccessConfig, string) (ecsbuilder.ImageTagClient, error) {
+		return client, nil
+	}
+}
+
+func testPostProcessor(t *testing.T, cloud *fakecloud.Server, extra map[string]interface{}) *PostProcessor {
+	t.Helper()
+
+	raw := map[string]interface{}{
+		"access_key":  "FAKEACCESSKEY",
+		"secret_key":  "FakeSecretKey",
+		"region":      cloud.Region,
+		"domain_name": cloud.DomainName,
+		"endpoints":   cloud.Endpoints(),
+	}
+	for k, v := range extra {
+		raw[k] = v
+	}
+
+	p := &PostProcessor{}
+	if err := p.Configure(raw); err != nil {
+		t.Fatalf("configure failed: %s", err)
+	}
+	return p
+}
+
+func TestPostProcessor_PostProcessFakeCloud(t *testing.T) {
+	cloud := fakecloud.New(t)
+	stable := map[string]string{"channel": "stable"}
+	previous := cloud.AddImage(fakecloud.Image{Name: "golden-1", ImageType: "private", Tags: stable})
+	previousData := cloud.AddImage(fakecloud.Image{Name: "golden-1-vdb", ImageType: "private", Tags: stable})
+	otherFamily := cloud.AddImage(fakecloud.Image{Name: "other-1", ImageType: "private", Tags: stable})
+	image := cloud.AddImage(fakecloud.Image{
+		Name:      "golden-2",
+		ImageType: "private",
+		Tags:      map[string]string{"channel": "candidate"},
+	})
+	dataImage := cloud.AddImage(fakecloud.Image{Name: "golden-2-vdb", ImageType: "private"})
+
+	p := testPostProcessor(t, cloud, map[string]interface{}{
+		"channel":           "stable",
+		"image_name_prefix": "golden-",
+	})
+	artifact := &ecsbuilder.Artifact{
+		ImageId:        fmt.Sprintf("%s:%s;%s", cloud.Region, image, dataImage),
+		BuilderIdValue: ecsbuilder.BuilderId,
+	}
+	result, keep, forceOverride, err := p.PostProcess(context.Background(), packer.TestUi(t), artifact)
+	if err != nil {
+		t.Fatalf("post-process failed: %s", err)
+	}
+	if result != artifact || !keep || !forceOverride {
+		t.Fatalf("the artifact should be passed through and kept, got %v, %v, %v", result, keep, forceOverride)
+	}
+
+	for id, expected := range map[string]string{
+		image:        "stable",
+		dataImage:    "stable",
+		previous:     "",
+		previousData: "",
+		otherFamily:  "stable",
+	} {
+		got, _ := cloud.Image(id)
+		if got.Tags["channel"] != expected {
+			t.Errorf("expected the channel of %s to be %q, got %q", got.Name, expected, got.Tags["channel"])
+		}
+	}
+}
+
+func TestPostProcessor_PostProcessFakeCloudRollback(t *testing.T) {
+	cloud := fakecloud.New(t)
+	stable := map[string]string{"channel": "stable"}
+	previous := cloud.AddImage(fakecloud.Image{Name: "golden-1", ImageType: "private", Tags: stable})
+	image := cloud.AddImage(fakecloud.Image{Name: "golden-2", ImageType: "private"})
+	dataImage := cloud.AddImage(fakecloud.Image{Name: "golden-2-vdb", ImageType: "private"})
+	cloud.AddRule(fakecloud.Rule{
+		Service: "ims",
+		Method:  http.MethodPost,
+		Path:    "/images/" + dataImage + "/tags$",
+		Status:  http.StatusBadRequest,
+		Code:    "IMG.0001",
+		Message: "The tag can not be added.",
+	})
+
+	p := testPostProcessor(t, cloud, map[string]interface{}{"channel": "stable"})
+	artifact := &ecsbuilder.Artifact{ImageId: image + ";" + dataImage, BuilderIdValue: ecsbuilder.BuilderId}
+	_, _, _, err := p.PostProcess(context.Background(), packer.TestUi(t), artifact)
+	if err == nil || !strings.Contains(err.Error(), "error adding channel=stable to the image "+dataImage) {
+		t.Fatalf("expected the promotion to fail, got %v", err)
+	}
+
+	// the new image is rolled back and the previous image is still in the channel
+	if got, _ := cloud.Image(image); got.Tags["channel"] != "" {
+		t.Fatalf("the channel tag of the new image should be removed, got %v", got.Tags)
+	}
+	if got, _ := cloud.Image(previous); got.Tags["channel"] != "stable" {
+		t.Fatalf("the previous image should be kept in the channel, got %v", got.Tags)
+	}
+}
+
+func TestPostProcessor_PostProcessRejectsArtifact(t *testing.T) {
+	cloud := fakecloud.New(t)
+	p := testPostProcessor(t, cloud, map[string]interface{}{"channel": "stable"})
+
+	cases := map[string]packer.Artifact{
+		"unknown artifact type": &packer.MockArtifact{BuilderIdValue: "packer.file", IdValue: "image-1"},
+		"no image to promote":   &ecsbuilder.Artifact{BuilderIdValue: ecsbuilder.BuilderId},
+		// the custom endpoints of the fake cloud can not be used in another region
+		"can not be used in region cn-east-3": &ecsbuilder.Artifact{
+			ImageId:        "cn-east-3:image-1",
+			BuilderIdValue: ecsbuilder.BuilderId,
+		},
+	}
+	for expected, artifact := range cases {
+		_, _, _, err := p.PostProcess(context.Background(), packer.TestUi(t), artifact)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in the error, got %v", expected, err)
+		}
+	}
+}
+
+func TestPostProcessor_PostProcessFakeClient(t *testing.T) {
+	client := &fakeImageTagClient{
+		names: map[string]string{
+			"image-1": "golden-1",
+			"image-2": "golden-2",
+			"image-3": "golden-2-vdb",
+			"image-4": "other-1",
+		},
+		tags: map[string]string{"image-1": "stable", "image-4": "stable"},
+	}
+	p := testPostProcessor(t, fakecloud.New(t), map[string]interface{}{
+		"channel":           "stable",
+		"image_name_prefix": "golden-",
+	})
+	useImageTagClient(p, client)
+	artifact := &ecsbuilder.Artifact{ImageId: "image-2;image-3", BuilderIdValue: ecsbuilder.BuilderId}
+	if _, _, _, err := p.PostProcess(context.Background(), packer.TestUi(t), artifact); err != nil {
+		t.Fatalf("post-process failed: %s", err)
+	}
+
+	expected := map[string]string{"image-2": "stable", "image-3": "stable", "image-4": "stable"}
+	if !reflect.DeepEqual(client.tags, expected) {
+		t.Fatalf("expected the channel tags %v, got %v", expected, client.tags)
+	}
+}
+
+func TestPostProcessor_PostProcessRegionsOfArtifact(t *testing.T) {
+	cloud := fakecloud.New(t)
+	cloud.OtherRegions = []string{"cn-east-3", "cn-south-1"}
+	// the custom endpoints other than iam can not be used in the other regions
+	p := testPostProcessor(t, cloud, map[string]interface{}{
+		"channel":   "stable",
+		"endpoints": map[string]string{"iam": cloud.Endpoint("iam")},
+	})
+
+	clients := make(map[string]*fakeImageTagClient)
+	projects := make(map[string]string)
+	p.newImageTagClient = func(c *ecsbuilder.AccessConfig, region string) (ecsbuilder.ImageTagClient, error) {
+		projects[region] = c.ProjectID
+		clients[region] = &fakeImageTagClient{names: map[string]string{}, tags: map[string]string{}}
+		return clients[region], nil
+	}
+
+	// the artifact is built in another region than the post-processor's, with an image copied to
+	// a third region in "region:id" format
+	artifact := &ecsbuilder.Artifact{
+		ImageId:        "image-1;image-2,cn-south-1:image-3",
+		Region:         "cn-east-3",
+		BuilderIdValue: ecsbuilder.BuilderId,
+	}
+	if _, _, _, err := p.PostProcess(context.Background(), packer.TestUi(t), artifact); err != nil {
+		t.Fatalf("post-process failed: %s", err)
+	}
+
+	expected := map[string]map[string]string{
+		"cn-east-3":  {"image-1": "stable", "image-2": "stable"},
+		"cn-south-1": {"image-3": "stable"},
+	}
+	if len(clients) != len(expected) {
+		t.Fatalf("expected the images to be promoted in %d regions, got %v", len(expected), projects)
+	}
+	for region, tags := range expected {
+		if !reflect.DeepEqual(clients[region].tags, tags) {
+			t.Errorf("expected the channel tags %v in %s, got %v", tags, region, clients[region].tags)
+		}
+		if projects[region] != cloud.RegionProjectID(region) {
+			t.Errorf("expected the project of %s, got %s", region, projects[region])
+		}
+	}
+}
@@ -0,0 +1,321 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+//go:generate packer-sdc struct-markdown
+
+package huaweicloudretention
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+
+	ecsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2/model"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2/model"
+	ecsbuilder "github.com/huaweicloud/packer-builder-huaweicloud/builder/ecs"
+)
+
+const (
+	BuilderId = "packer.post-processor.huaweicloud-retention"
+
+	// the page sizes to list the images and the servers
+	imagePageSize  = 100
+	serverPageSize = 100
+)
+
+// Configuration of this post processor
+type Config struct {
+	common.PackerConfig     `mapstructure:",squash"`
+	ecsbuilder.AccessConfig `mapstructure:",squash"`
+
+	// Select the private images whose names start with the prefix, such as `my-golden-image-`.
+	// Either `image_name_prefix` or `image_tags` must be specified.
+	ImageNamePrefix string `mapstructure:"image_name_prefix" required:"false"`
+	// Select the private images which have all the tags in key/value format.
+	ImageTags map[string]string `mapstructure:"image_tags" required:"false"`
+	// The number of the newest selected images to keep.
+	KeepLatest int `mapstructure:"keep_latest" required:"false"`
+	// Keep the selected images created within the duration, such as "720h" for 30 days.
+	// An image is kept if it's one of the newest `keep_latest` images or created within `keep_within`,
+	// at least one of them must be specified.
+	KeepWithin string `mapstructure:"keep_within" required:"false"`
+	// Only report the images to be deleted without deleting them. (Default: `false`).
+	DryRun bool `mapstructure:"dry_run" required:"false"`
+
+	keepWithin time.Duration
+	ctx        interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+
+	// newImageClient and newServerListClient create the clients of the region, the clients are
+	// created with the access config if nil, the tests replace them with fakes
+	newImageClient      func(region string) (ecsbuilder.ImageClient, error)
+	newServerListClient func(region string) (ecsbuilder.ServerListClient, error)
+}
+
+func (p *PostProcessor) imageClient(region string) (ecsbuilder.ImageClient, error) {
+	if p.newImageClient != nil {
+		return p.newImageClient(region)
+	}
+	return p.config.HcImsClient(region)
+}
+
+func (p *PostProcessor) serverListClient(region string) (ecsbuilder.ServerListClient, error) {
+	if p.newServerListClient != nil {
+		return p.newServerListClient(region)
+	}
+	return p.config.HcEcsClient(region)
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec {
+	return p.config.FlatMapstructure().HCL2Spec()
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packersdk.MultiError)
+
+	// Check we have huaweicloud access variables defined somewhere
+	errs = packersdk.MultiErrorAppend(errs, p.config.AccessConfig.Prepare(&p.config.ctx)...)
+
+	if p.config.ImageNamePrefix == "" && len(p.config.ImageTags) == 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("either image_name_prefix or image_tags must be specified"))
+	}
+
+	if p.config.KeepLatest < 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("keep_latest must not be negative, got %d", p.config.KeepLatest))
+	}
+	if p.config.KeepWithin != "" {
+		p.config.keepWithin, err = time.ParseDuration(p.config.KeepWithin)
+		if err != nil || p.config.keepWithin <= 0 {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("keep_within must be a positive duration, got %s", p.config.KeepWithin))
+		}
+	}
+	if p.config.KeepLatest == 0 && p.config.KeepWithin == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("at least one of keep_latest and keep_within must be specified"))
+	}
+
+	// Anything which flagged return back up the stack
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(p.config.AccessKey, p.config.SecretKey)
+	return nil
+}
+
+// PostProcess deletes the expired images selected by the configuration. It only accepts the
+// artifacts built by the ECS builder, the images of the artifact are always kept.
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	if artifact.BuilderId() != ecsbuilder.BuilderId {
+		return nil, false, false, fmt.Errorf("unknown artifact type %s, only the artifacts of %s are supported",
+			artifact.BuilderId(), ecsbuilder.BuilderId)
+	}
+	if artifact.Id() == "" {
+		return nil, false, false, fmt.Errorf("the artifact contains no image")
+	}
+
+	region := p.config.Region
+	imsClient, err := p.imageClient(region)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("error initializing image service client: %s", err)
+	}
+	ecsClient, err := p.serverListClient(region)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("error initializing compute client: %s", err)
+	}
+
+	ui.Say("Looking for the images to clean up ...")
+	images, err := p.listImages(imsClient)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("error querying the images: %s", err)
+	}
+
+	// the images of the artifact are always kept
+	built := make(map[string]bool)
+	for _, item := range ecsbuilder.ArtifactRegionImages(artifact, region) {
+		for _, id := range item.Images {
+			built[id] = true
+		}
+	}
+	expired := expiredImages(images, built, p.config.KeepLatest, p.config.keepWithin, time.Now())
+	ui.Message(fmt.Sprintf("%d image(s) selected, %d of them expired", len(images), len(expired)))
+	if len(expired) == 0 {
+		return artifact, true, true, nil
+	}
+
+	inUse, err := imagesInUse(ecsClient)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("error querying the servers: %s", err)
+	}
+
+	for _, image := range expired {
+		if inUse[image.Id] {
+			ui.Message(fmt.Sprintf("Skipping the image %s (%s), it's in use by servers", image.Name, image.Id))
+			continue
+		}
+		members, err := imsClient.GlanceListImageMembers(&model.GlanceListImageMembersRequest{ImageId: image.Id})
+		if err != nil {
+			ui.Error(fmt.Sprintf("Skipping the image %s (%s), failed to query its members: %s", image.Name, image.Id, err))
+			continue
+		}
+		if members.Members != nil && len(*members.Members) > 0 {
+			ui.Message(fmt.Sprintf("Skipping the image %s (%s), it's shared with %d member(s)",
+				image.Name, image.Id, len(*members.Members)))
+			continue
+		}
+
+		if p.config.DryRun {
+			ui.Message(fmt.Sprintf("Would delete the image %s (%s) created at %s", image.Name, image.Id, image.CreatedAt))
+			continue
+		}
+		if _, err := imsClient.GlanceDeleteImage(&model.GlanceDeleteImageRequest{ImageId: image.Id}); err != nil {
+			ui.Error(fmt.Sprintf("Error deleting the image %s (%s): %s", image.Name, image.Id, err))
+			continue
+		}
+		ui.Message(fmt.Sprintf("Deleted the image %s (%s) created at %s", image.Name, image.Id, image.CreatedAt))
+	}
+
+	// the artifact is passed through, it must be kept since it's the same one
+	return artifact, true, true, nil
+}
+
+// listImages lists the active private images matching the name prefix and the tags, the newest
+// images come first.
+func (p *PostProcessor) listImages(client ecsbuilder.ImageClient) ([]model.ImageInfo, error) {
+	tags := make([]string, 0, len(p.config.ImageTags))
+	for k, v := range p.config.ImageTags {
+		tags = append(tags, k+"."+v)
+	}
+	sort.Strings(tags)
+
+	filters := ecsbuilder.ImageFilterOptions{Visibility: "private"}
+	// only one tag can be used as the filter, the others are checked in the response
+	if len(tags) > 0 {
+		filters.Tag = tags[0]
+	}
+	request, err := filters.Build()
+	if err != nil {
+		return nil, err
+	}
+	limit := int32(imagePageSize)
+	request.Limit = &limit
+
+	var images []model.ImageInfo
+	for {
+		log.Printf("[DEBUG] Listing the images: %s", request)
+		response, err := client.ListImages(request)
+		if err != nil {
+			return nil, err
+		}
+		if response.Images == nil || len(*response.Images) == 0 {
+			break
+		}
+
+		page := *response.Images
+		for _, image := range page {
+			if strings.HasPrefix(image.Name, p.config.ImageNamePrefix) && hasAllTags(image.Tags, tags) {
+				images = append(images, image)
+			}
+		}
+		if len(page) < imagePageSize {
+			break
+		}
+		request.Marker = &page[len(page)-1].Id
+	}
+	return images, nil
+}
+
+func hasAllTags(imageTags, tags []string) bool {
+	for _, tag := range tags {
+		if !isStringInSlice(tag, imageTags) {
+			return false
+		}
+	}
+	return true
+}
+
+// expiredImages returns the images which are neither built nor kept by keepLatest or keepWithin,
+// the images must be sorted from the newest to the oldest.
+func expiredImages(images []model.ImageInfo, built map[string]bool, keepLatest int, keepWithin time.Duration,
+	now time.Time) []model.ImageInfo {
+	var expired []model.ImageInfo
+	for i, image := range images {
+		if built[image.Id] || i < keepLatest {
+			continue
+		}
+		if keepWithin > 0 {
+			createdAt, err := time.Parse(time.RFC3339, image.CreatedAt)
+			if err != nil {
+				log.Printf("[WARN] keeping the image %s, failed to parse the creation time %q: %s",
+					image.Id, image.CreatedAt, err)
+				continue
+			}
+			if now.Sub(createdAt) < keepWithin {
+				continue
+			}
+		}
+		expired = append(expired, image)
+	}
+	return expired
+}
+
+// imagesInUse returns the IDs of the images used by the servers.
+func imagesInUse(client ecsbuilder.ServerListClient) (map[string]bool, error) {
+	inUse := make(map[string]bool)
+	limit := int32(serverPageSize)
+	for page := int32(1); ; page++ {
+		offset := page
+		response, err := client.ListServersDetails(&ecsmodel.ListServersDetailsRequest{
+			Limit:  &limit,
+			Offset: &offset,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if response.Servers == nil {
+			break
+		}
+
+		servers := *response.Servers
+		for _, server := range servers {
+			if server.Image != nil {
+				inUse[server.Image.Id] = true
+			}
+		}
+		if len(servers) < serverPageSize {
+			break
+		}
+	}
+	return inUse, nil
+}
+
+func isStringInSlice(key string, valid []string) bool {
+	for _, str := range valid {
+		if key == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,288 @@
+package huaweicloudretention
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/packer"
+
+	ecsmodel "github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ecs/v2/model"
+	"github.com/huaweicloud/huaweicloud-sdk-go-v3/services/ims/v2/model"
+	ecsbuilder "github.com/huaweicloud/packer-builder-huaweicloud/builder/ecs"
+	"github.com/huaweicloud/packer-builder-huaweicloud/internal/fakecloud"
+)
+
+// The fakes embed the interfaces they implement, so calling a method that a test does not expect
+// panics with a nil pointer dereference instead of silently succeeding.
+
+type fakeImageClient struct {
+	ecsbuilder.ImageClient
+
+	images     []model.ImageInfo
+	membersErr map[string]error
+	deleted    []string
+}
+
+func (f *fakeImageClient) ListImages(*model.ListImagesRequest) (*model.ListImagesResponse, error) {
+	return &model.ListImagesResponse{Images: &f.images}, nil
+}
+
+func (f *fakeImageClient) GlanceListImageMembers(req *model.GlanceListImageMembersRequest) (*model.GlanceListImageMembersResponse, error) {
+	if err := f.membersErr[req.ImageId]; err != nil {
+		return nil, err
+	}
+	return &model.GlanceListImageMembersResponse{Members: &[]model.GlanceImageMembers{}}, nil
+}
+
+func (f *fakeImageClient) GlanceDeleteImage(req *model.GlanceDeleteImageRequest) (*model.GlanceDeleteImageResponse, error) {
+	f.deleted = append(f.deleted, req.ImageId)
+	return &model.GlanceDeleteImageResponse{}, nil
+}
+
+type fakeServerListClient struct {
+	imageIDs []string
+}
+
+func (f *fakeServerListClient) ListServersDetails(*ecsmodel.ListServersDetailsRequest) (*ecsmodel.ListServersDetailsResponse, error) {
+	servers := make([]ecsmodel.ServerDetail, len(f.imageIDs))
+	for i, id := range f.imageIDs {
+		servers[i].Image = &ecsmodel.ServerImage{Id: id}
+	}
+	return &ecsmodel.ListServersDetailsResponse{Servers: &servers}, nil
+}
+
+// useClients replaces the clients of the post-processor with the fakes.
+func useClients(p *PostProcessor, images ecsbuilder.ImageClient, servers ecsbuilder.ServerListClient) {
+	p.newImageClient = func(string) (ecsbuilder.ImageClient, error) {
+		return images, nil
+	}
+	p.newServerListClient = func(string) (ecsbuilder.ServerListClient, error) {
+		return servers, nil
+	}
+}
+
+func testPostProcessor(t *testing.T, cloud *fakecloud.Server, extra map[string]interface{}) *PostProcessor {
+	t.Helper()
+
+	raw := map[string]interface{}{
+		"access_key":  "FAKEACCESSKEY",
+		"secret_key":  "FakeSecretKey",
+		"region":      cloud.Region,
+		"domain_name": cloud.DomainName,
+		"endpoints":   cloud.Endpoints(),
+	}
+	for k, v := range extra {
+		raw[k] = v
+	}
+
+	p := &PostProcessor{}
+	if err := p.Configure(raw); err != nil {
+		t.Fatalf("configure failed: %s", err)
+	}
+	return p
+}
+
+func TestPostProcessor_Configure(t *testing.T) {
+	cases := map[string]map[string]interface{}{
+		"no selector":         {"keep_latest": 3},
+		"no retention":        {"image_name_prefix": "golden-"},
+		"negative latest":     {"image_name_prefix": "golden-", "keep_latest": -1},
+		"invalid keep_within": {"image_name_prefix": "golden-", "keep_within": "30 days"},
+	}
+	for name, extra := range cases {
+		raw := map[string]interface{}{
+			"access_key": "FAKEACCESSKEY",
+			"secret_key": "FakeSecretKey",
+			"region":     "ap-southeast-1",
+			"project_id": "0970dd7a1300f5672ff2c003c60ae115",
+		}
+		for k, v := range extra {
+			raw[k] = v
+		}
+		if err := new(PostProcessor).Configure(raw); err == nil {
+			t.Errorf("%s: should have error", name)
+		}
+	}
+}
+
+func TestExpiredImages(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	images := []model.ImageInfo{
+		{Id: "image-4", CreatedAt: "2024-04-30T00:00:00Z"},
+		{Id: "image-3", CreatedAt: "2024-04-20T00:00:00Z"},
+		{Id: "image-2", CreatedAt: "2024-04-10T00:00:00Z"},
+		{Id: "image-1", CreatedAt: "2024-03-01T00:00:00Z"},
+	}
+	ids := func(images []model.ImageInfo) string {
+		result := make([]string, len(images))
+		for i, image := range images {
+			result[i] = image.Id
+		}
+		return strings.Join(result, ",")
+	}
+
+	cases := []struct {
+		keepLatest int
+		keepWithin time.Duration
+		built      map[string]bool
+		expected   string
+	}{
+		{keepLatest: 2, expected: "image-2,image-1"},
+		{keepWithin: 15 * 24 * time.Hour, expected: "image-2,image-1"},
+		{keepLatest: 1, keepWithin: 15 * 24 * time.Hour, expected: "image-2,image-1"},
+		{keepLatest: 3, keepWithin: 24 * time.Hour, expected: "image-1"},
+		{keepLatest: 1, built: map[string]bool{"image-3": true}, expected: "image-2,image-1"},
+	}
+	for _, tc := range cases {
+		result := ids(expiredImages(images, tc.built, tc.keepLatest, tc.keepWithin, now))
+		if result != tc.expected {
+			t.Errorf("keep_latest %d, keep_within %s: expected %s, got %s", tc.keepLatest, tc.keepWithin, tc.expected, result)
+		}
+	}
+}
+
+func TestPostProcessor_PostProcessFakeCloud(t *testing.T) {
+	cloud := fakecloud.New(t)
+	now := time.Now()
+	addImage := func(name string, age time.Duration, tags map[string]string, members ...string) string {
+		return cloud.AddImage(fakecloud.Image{
+			Name:      name,
+			ImageType: "private",
+			Tags:      tags,
+			Members:   members,
+			CreatedAt: now.Add(-age),
+		})
+	}
+	golden := map[string]string{"role": "golden"}
+	built := addImage("golden-5", 0, golden)
+	kept := addImage("golden-4", 24*time.Hour, golden)
+	expired := addImage("golden-3", 48*time.Hour, golden)
+	shared := addImage("golden-2", 72*time.Hour, golden, "0970dd7a1300f5672ff2c003c60ae116")
+	inUse := addImage("golden-1", 96*time.Hour, golden)
+	untagged := addImage("golden-0", 120*time.Hour, nil)
+	other := addImage("other-0", 120*time.Hour, golden)
+	cloud.AddServer(fakecloud.Instance{Name: "app", ImageRef: inUse})
+
+	p := testPostProcessor(t, cloud, map[string]interface{}{
+		"image_name_prefix": "golden-",
+		"image_tags":        golden,
+		"keep_latest":       2,
+	})
+	artifact := &ecsbuilder.Artifact{ImageId: built, BuilderIdValue: ecsbuilder.BuilderId}
+
+	output := new(bytes.Buffer)
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: output, ErrorWriter: output}
+	result, keep, forceOverride, err := p.PostProcess(context.Background(), ui, artifact)
+	if err != nil {
+		t.Fatalf("post-process failed: %s", err)
+	}
+	if result != artifact || !keep || !forceOverride {
+		t.Fatalf("the artifact should be passed through and kept, got %v, %v, %v", result, keep, forceOverride)
+	}
+
+	if _, ok := cloud.Image(expired); ok {
+		t.Fatalf("the expired image %s should be deleted:\n%s", expired, output)
+	}
+	for _, id := range []string{built, kept, shared, inUse, untagged, other} {
+		if _, ok := cloud.Image(id); !ok {
+			t.Fatalf("the image %s should be kept:\n%s", id, output)
+		}
+	}
+	for _, expected := range []string{
+		"5 image(s) selected, 3 of them expired",
+		"Skipping the image golden-2 (" + shared + "), it's shared with 1 member(s)",
+		"Skipping the image golden-1 (" + inUse + "), it's in use by servers",
+		"Deleted the image golden-3 (" + expired + ")",
+	} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("expected %q in the output, got:\n%s", expected, output.String())
+		}
+	}
+}
+
+func TestPostProcessor_PostProcessFakeCloudDryRun(t *testing.T) {
+	cloud := fakecloud.New(t)
+	old := cloud.AddImage(fakecloud.Image{
+		Name:      "golden-1",
+		ImageType: "private",
+		CreatedAt: time.Now().Add(-60 * 24 * time.Hour),
+	})
+	built := cloud.AddImage(fakecloud.Image{Name: "golden-2", ImageType: "private"})
+
+	p := testPostProcessor(t, cloud, map[string]interface{}{
+		"image_name_prefix": "golden-",
+		"keep_within":       "720h",
+		"dry_run":           true,
+	})
+	artifact := &ecsbuilder.Artifact{ImageId: built, BuilderIdValue: ecsbuilder.BuilderId}
+
+	output := new(bytes.Buffer)
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: output, ErrorWriter: output}
+	if _, _, _, err := p.PostProcess(context.Background(), ui, artifact); err != nil {
+		t.Fatalf("post-process failed: %s", err)
+	}
+
+	if !strings.Contains(output.String(), "Would delete the image golden-1 ("+old+")") {
+		t.Fatalf("expected the image to be reported, got:\n%s", output.String())
+	}
+	if n := cloud.CountRequests("ims", http.MethodDelete, ""); n > 0 {
+		t.Fatalf("expected no image to be deleted, got %d requests", n)
+	}
+}
+
+func TestPostProcessor_PostProcessRejectsArtifact(t *testing.T) {
+	p := testPostProcessor(t, fakecloud.New(t), map[string]interface{}{
+		"image_name_prefix": "golden-",
+		"keep_latest":       1,
+	})
+	useClients(p, nil, nil)
+
+	cases := map[string]packer.Artifact{
+		"unknown artifact type": &packer.MockArtifact{BuilderIdValue: "packer.file", IdValue: "image-1"},
+		"no image":              &ecsbuilder.Artifact{BuilderIdValue: ecsbuilder.BuilderId},
+	}
+	for expected, artifact := range cases {
+		_, _, _, err := p.PostProcess(context.Background(), packer.TestUi(t), artifact)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in the error, got %v", expected, err)
+		}
+	}
+}
+
+func TestPostProcessor_PostProcessFakeClients(t *testing.T) {
+	now := time.Now()
+	createdAt := func(age time.Duration) string {
+		return now.Add(-age).UTC().Format(time.RFC3339)
+	}
+	images := &fakeImageClient{
+		images: []model.ImageInfo{
+			{Id: "image-5", Name: "golden-5", CreatedAt: createdAt(0)},
+			{Id: "image-4", Name: "golden-4", CreatedAt: createdAt(24 * time.Hour)},
+			{Id: "image-3", Name: "golden-3", CreatedAt: createdAt(48 * time.Hour)},
+			{Id: "image-2", Name: "golden-2", CreatedAt: createdAt(72 * time.Hour)},
+			{Id: "image-1", Name: "golden-1", CreatedAt: createdAt(96 * time.Hour)},
+		},
+		membersErr: map[string]error{"image-2": errors.New("forbidden")},
+	}
+	p := testPostProcessor(t, fakecloud.New(t), map[string]interface{}{
+		"image_name_prefix": "golden-",
+		"keep_latest":       1,
+	})
+	useClients(p, images, &fakeServerListClient{imageIDs: []string{"image-1"}})
+	// both the system and data disk images of the artifact are kept
+	artifact := &ecsbuilder.Artifact{ImageId: "image-5;image-4", BuilderIdValue: ecsbuilder.BuilderId}
+	if _, _, _, err := p.PostProcess(context.Background(), packer.TestUi(t), artifact); err != nil {
+		t.Fatalf("post-process failed: %s", err)
+	}
+
+	// image-2 is skipped since its members can not be queried, image-1 is in use
+	if !reflect.DeepEqual(images.deleted, []string{"image-3"}) {
+		t.Fatalf("expected only image-3 to be deleted, got %v", images.deleted)
+	}
+}